@@ -0,0 +1,234 @@
+// Command justdone-admin manages a running justdone server through its admin API.
+//
+//	justdone-admin [-url URL] [-api-key KEY | -token TOKEN] dead-letters list [-order-id ID] [-limit N] [-offset N]
+//	justdone-admin dead-letters show|retry|discard EVENT_ID
+//	justdone-admin reconciliation run [-correct] [-format csv|json] FILE
+//	justdone-admin reconciliation corrections [-run-id ID] [-order-id ID] [-limit N] [-offset N]
+//
+// The URL and credentials default to JUSTDONE_URL, JUSTDONE_API_KEY and JUSTDONE_TOKEN.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("justdone-admin", flag.ExitOnError)
+	baseURL := flags.String("url", envOr("JUSTDONE_URL", "http://localhost:8080"), "server URL")
+	apiKey := flags.String("api-key", os.Getenv("JUSTDONE_API_KEY"), "backoffice API key")
+	token := flags.String("token", os.Getenv("JUSTDONE_TOKEN"), "backoffice bearer token")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: justdone-admin [flags] dead-letters list|show|retry|discard [args]")
+		fmt.Fprintln(flags.Output(), "       justdone-admin [flags] reconciliation run|corrections [args]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 || (flags.Arg(0) != "dead-letters" && flags.Arg(0) != "reconciliation") {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	client := adminClient{
+		baseURL: *baseURL,
+		apiKey:  *apiKey,
+		token:   *token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if flags.Arg(0) == "reconciliation" {
+		return reconciliation(client, flags.Arg(1), flags.Args()[2:])
+	}
+	return deadLetters(client, flags.Arg(1), flags.Args()[2:])
+}
+
+func deadLetters(client adminClient, command string, args []string) error {
+	switch command {
+	case "list":
+		flags := flag.NewFlagSet("list", flag.ExitOnError)
+		orderID := flags.String("order-id", "", "only events of the order")
+		limit := flags.Int("limit", 10, "number of events")
+		offset := flags.Int("offset", 0, "number of events to skip")
+		flags.Parse(args)
+
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(*limit))
+		query.Set("offset", strconv.Itoa(*offset))
+		if *orderID != "" {
+			query.Set("order_id", *orderID)
+		}
+		return client.do(http.MethodGet, "/admin/dead-letters?"+query.Encode())
+	case "show", "retry", "discard":
+		if len(args) != 1 {
+			return fmt.Errorf("%s expects an event ID", command)
+		}
+		path := "/admin/dead-letters/" + url.PathEscape(args[0])
+
+		switch command {
+		case "retry":
+			return client.do(http.MethodPost, path+"/retry")
+		case "discard":
+			return client.do(http.MethodDelete, path)
+		default:
+			return client.do(http.MethodGet, path)
+		}
+	default:
+		return fmt.Errorf("unknown dead-letters command %q", command)
+	}
+}
+
+func reconciliation(client adminClient, command string, args []string) error {
+	switch command {
+	case "run":
+		flags := flag.NewFlagSet("run", flag.ExitOnError)
+		correct := flags.Bool("correct", false, "create corrective events for discrepancies")
+		format := flags.String("format", "", "csv or json, defaults to the file extension")
+		periodFrom := flags.String("period-from", "", "start of the settlement period, defaults to the earliest settled order")
+		periodTo := flags.String("period-to", "", "end of the settlement period, defaults to the latest settled order")
+		flags.Parse(args)
+
+		if flags.NArg() != 1 {
+			return errors.New("run expects a settlement file")
+		}
+
+		query := url.Values{}
+		query.Set("correct", strconv.FormatBool(*correct))
+		if *format != "" {
+			query.Set("format", *format)
+		}
+		if *periodFrom != "" {
+			query.Set("period_from", *periodFrom)
+		}
+		if *periodTo != "" {
+			query.Set("period_to", *periodTo)
+		}
+		return client.upload("/admin/reconciliation?"+query.Encode(), flags.Arg(0))
+	case "corrections":
+		flags := flag.NewFlagSet("corrections", flag.ExitOnError)
+		runID := flags.String("run-id", "", "only corrections of the run")
+		orderID := flags.String("order-id", "", "only corrections of the order")
+		limit := flags.Int("limit", 10, "number of corrections")
+		offset := flags.Int("offset", 0, "number of corrections to skip")
+		flags.Parse(args)
+
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(*limit))
+		query.Set("offset", strconv.Itoa(*offset))
+		if *runID != "" {
+			query.Set("run_id", *runID)
+		}
+		if *orderID != "" {
+			query.Set("order_id", *orderID)
+		}
+		return client.do(http.MethodGet, "/admin/reconciliation/corrections?"+query.Encode())
+	default:
+		return fmt.Errorf("unknown reconciliation command %q", command)
+	}
+}
+
+type adminClient struct {
+	baseURL string
+	apiKey  string
+	token   string
+	http    *http.Client
+}
+
+// do sends the request and prints the indented JSON response.
+// Responses other than 2xx are returned as errors.
+func (c adminClient) do(method, path string) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	return c.send(req)
+}
+
+// upload posts the file as the "file" field of a multipart form.
+func (c adminClient) upload(path, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return c.send(req)
+}
+
+func (c adminClient) send(req *http.Request) error {
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if len(body) == 0 {
+		fmt.Println(resp.Status)
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		out.Write(body)
+	}
+	fmt.Println(out.String())
+
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
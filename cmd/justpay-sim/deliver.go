@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sender posts events to the webhook like JustPay! does: every response
+// other than 2xx is retried with exponential backoff, except the ones that
+// can't change on retry.
+type sender struct {
+	client     *http.Client
+	webhookURL string
+	limiter    *rate.Limiter
+	maxRetries int
+	backoff    time.Duration
+	stats      *stats
+}
+
+// attempt is a delivery of an event. backoff is the wait before
+// the next attempt if this one fails.
+type attempt struct {
+	body    []byte
+	retries int
+	backoff time.Duration
+}
+
+func (s *sender) newAttempt(e event) (attempt, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return attempt{}, err
+	}
+	return attempt{body: body, backoff: s.backoff}, nil
+}
+
+// send posts the event once. If JustPay! would deliver it again, it returns
+// the next attempt and how long to wait before it.
+func (s *sender) send(ctx context.Context, a attempt) (*attempt, time.Duration) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, 0
+	}
+
+	status, retryAfter := s.post(ctx, a.body)
+	if !isRetried(status) {
+		return nil, 0
+	}
+	if a.retries == s.maxRetries {
+		s.stats.exhausted()
+		return nil, 0
+	}
+
+	s.stats.retry()
+	wait := a.backoff
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	return &attempt{body: a.body, retries: a.retries + 1, backoff: a.backoff * 2}, wait
+}
+
+func (s *sender) post(ctx context.Context, body []byte) (int, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		// The URL is checked on start, so it fails like an unreachable server
+		s.stats.record(0, 0)
+		return 0, 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		s.stats.record(0, latency)
+		return 0, 0
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	s.stats.record(resp.StatusCode, latency)
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return resp.StatusCode, retryAfter
+}
+
+// isRetried reports whether JustPay! delivers the event again after the
+// response. Invalid payloads and final orders are never accepted.
+func isRetried(status int) bool {
+	switch {
+	case status >= 200 && status < 300:
+		return false
+	case status == http.StatusBadRequest, status == http.StatusGone, status == http.StatusUnprocessableEntity:
+		return false
+	default:
+		return true
+	}
+}
+
+// stats collects webhook responses and latencies of all deliveries.
+type stats struct {
+	mu        sync.Mutex
+	statuses  map[int]int
+	latencies []time.Duration
+	retries   int
+	failed    int
+}
+
+func newStats() *stats {
+	return &stats{statuses: make(map[int]int)}
+}
+
+func (s *stats) record(status int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses[status]++
+	s.latencies = append(s.latencies, latency)
+}
+
+func (s *stats) retry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+}
+
+func (s *stats) exhausted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed++
+}
+
+// deliver sends the deliveries of every plan. Deliveries of an order follow
+// each other after their delays, while up to concurrency requests are in
+// flight. Retries wait for their backoff outside of the workers, so slow
+// retries don't hold back other orders.
+func deliver(ctx context.Context, s *sender, plans []orderPlan, concurrency int) error {
+	// Events are encoded before the first delivery, so a failure stops the run
+	attempts := make([][]attempt, len(plans))
+	for i, plan := range plans {
+		for _, d := range plan.deliveries {
+			a, err := s.newAttempt(d.event)
+			if err != nil {
+				return fmt.Errorf("failed to encode event %s: %w", d.event.EventID, err)
+			}
+			attempts[i] = append(attempts[i], a)
+		}
+	}
+
+	queue := make(chan attempt)
+
+	// pending counts attempts until a worker has sent them.
+	var pending sync.WaitGroup
+	enqueue := func(a attempt, delay time.Duration) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			pending.Done()
+			return
+		}
+		select {
+		case queue <- a:
+		case <-ctx.Done():
+			pending.Done()
+		}
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for a := range queue {
+				if next, wait := s.send(ctx, a); next != nil {
+					pending.Add(1)
+					go enqueue(*next, wait)
+				}
+				pending.Done()
+			}
+		}()
+	}
+
+	var orders sync.WaitGroup
+	for i, plan := range plans {
+		orders.Add(1)
+		go func(plan orderPlan, attempts []attempt) {
+			defer orders.Done()
+			for j, d := range plan.deliveries {
+				pending.Add(1)
+				enqueue(attempts[j], d.delay)
+			}
+		}(plan, attempts[i])
+	}
+
+	orders.Wait()
+	pending.Wait()
+	close(queue)
+	workers.Wait()
+	return nil
+}
@@ -0,0 +1,131 @@
+// Command justpay-sim simulates JustPay! delivering order events to the webhook.
+//
+// It generates an event stream for every order following one of the scenarios
+// (happy path to chinazes, cancellation, refund), delivers it shuffled, with
+// duplicates and delays if asked to, retries non-2xx responses the way JustPay!
+// does, and then checks the final state of every order through GET /orders.
+//
+//	justpay-sim -orders 500 -rate 200 -concurrency 20 -shuffle -duplicates 0.2 -max-delay 2s
+//
+// It exits with status 1 when an order did not end in the expected state.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func main() {
+	var (
+		baseURL      = flag.String("url", "http://localhost:8080", "server URL")
+		orders       = flag.Int("orders", 100, "number of orders to simulate")
+		scenarioList = flag.String("scenarios", "happy,cancel,refund", "comma separated scenarios, picked at random per order")
+		ratePerSec   = flag.Float64("rate", 50, "webhook requests per second, 0 for unlimited")
+		concurrency  = flag.Int("concurrency", 10, "webhook requests in flight")
+		shuffle      = flag.Bool("shuffle", false, "deliver events of an order in random order")
+		duplicates   = flag.Float64("duplicates", 0, "probability of delivering an event twice")
+		maxDelay     = flag.Duration("max-delay", 0, "random delay before each delivery of an order, keep it below the finalization timeout")
+		maxRetries   = flag.Int("max-retries", 8, "retries of a non-2xx response")
+		backoff      = flag.Duration("backoff", 200*time.Millisecond, "first retry delay, doubled on every retry")
+		settle       = flag.Duration("settle", time.Second, "wait before verifying orders")
+		timeout      = flag.Duration("timeout", 10*time.Second, "HTTP request timeout")
+		seed         = flag.Int64("seed", 0, "seed of the generated scenarios, 0 for a random one")
+		apiKey       = flag.String("api-key", os.Getenv("JUSTDONE_API_KEY"), "backoffice API key for GET /orders")
+		token        = flag.String("token", os.Getenv("JUSTDONE_TOKEN"), "backoffice bearer token for GET /orders")
+		maxFailures  = flag.Int("max-failures", 20, "failed orders listed in the report")
+	)
+	flag.Parse()
+
+	selected, err := parseScenarios(*scenarioList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	if *concurrency < 1 || *orders < 1 {
+		fmt.Fprintln(os.Stderr, "error: -orders and -concurrency must be positive")
+		os.Exit(2)
+	}
+	webhookURL := strings.TrimSuffix(*baseURL, "/") + "/webhooks/payments/orders"
+	if _, err := http.NewRequest(http.MethodPost, webhookURL, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid -url: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(*seed))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	options := planOptions{shuffle: *shuffle, duplicates: *duplicates, maxDelay: *maxDelay}
+	plans := make([]orderPlan, *orders)
+	deliveries := 0
+	for i := range plans {
+		plans[i] = newOrderPlan(random, selected[random.Intn(len(selected))], options)
+		deliveries += len(plans[i].deliveries)
+	}
+
+	fmt.Printf("Simulating %d orders (%d deliveries) against %s, seed %d\n", len(plans), deliveries, *baseURL, *seed)
+
+	limit := rate.Inf
+	if *ratePerSec > 0 {
+		limit = rate.Limit(*ratePerSec)
+	}
+	client := &http.Client{Timeout: *timeout}
+	stats := newStats()
+	s := &sender{
+		client:     client,
+		webhookURL: webhookURL,
+		limiter:    rate.NewLimiter(limit, 1),
+		maxRetries: *maxRetries,
+		backoff:    *backoff,
+		stats:      stats,
+	}
+
+	start := time.Now()
+	if err := deliver(ctx, s, plans, *concurrency); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	elapsed := time.Since(start)
+
+	select {
+	case <-time.After(*settle):
+	case <-ctx.Done():
+	}
+
+	orderIDs := make([]string, len(plans))
+	for i, plan := range plans {
+		orderIDs[i] = plan.orderID
+	}
+
+	reader := ordersClient{client: client, baseURL: strings.TrimSuffix(*baseURL, "/"), apiKey: *apiKey, token: *token}
+	stored, err := reader.get(ctx, orderIDs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: failed to verify orders: %v\n", err)
+		os.Exit(1)
+	}
+
+	results := make([]result, len(plans))
+	for i, plan := range plans {
+		results[i] = result{plan: plan}
+		if order, ok := stored[plan.orderID]; ok {
+			results[i].order = &order
+		}
+	}
+
+	if failed := report(os.Stdout, results, stats, elapsed, *maxFailures); failed > 0 {
+		os.Exit(1)
+	}
+}
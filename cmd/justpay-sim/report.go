@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// verifyBatch is the number of orders requested from GET /orders at once.
+const verifyBatch = 50
+
+// ordersClient reads orders back from the API.
+type ordersClient struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	token   string
+}
+
+// get returns the stored orders with the given IDs by order ID.
+func (c ordersClient) get(ctx context.Context, orderIDs []string) (map[string]domain.Order, error) {
+	orders := make(map[string]domain.Order, len(orderIDs))
+
+	for start := 0; start < len(orderIDs); start += verifyBatch {
+		end := start + verifyBatch
+		if end > len(orderIDs) {
+			end = len(orderIDs)
+		}
+
+		query := url.Values{}
+		query.Set("limit", strconv.Itoa(end-start))
+		for _, orderID := range orderIDs[start:end] {
+			query.Add("order_id", orderID)
+		}
+
+		batch, err := c.getPage(ctx, c.baseURL+"/orders?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range batch {
+			orders[order.OrderID] = order
+		}
+	}
+
+	return orders, nil
+}
+
+// getPage requests one page, waiting when rate limited.
+func (c ordersClient) getPage(ctx context.Context, pageURL string) ([]domain.Order, error) {
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		} else if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait := time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET /orders: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+
+		var orders []domain.Order
+		if err := json.Unmarshal(body, &orders); err != nil {
+			return nil, fmt.Errorf("GET /orders: %w", err)
+		}
+		return orders, nil
+	}
+}
+
+// result is the verification outcome of an order.
+type result struct {
+	plan  orderPlan
+	order *domain.Order
+}
+
+func (r result) passed() bool {
+	if r.order == nil || r.order.Status != r.plan.expected {
+		return false
+	}
+	return !r.plan.finalized || r.order.IsFinal
+}
+
+func (r result) String() string {
+	want := string(r.plan.expected)
+	if r.plan.finalized {
+		want += " (final)"
+	}
+
+	got := "missing"
+	if r.order != nil {
+		got = string(r.order.Status)
+		if r.order.IsFinal {
+			got += " (final)"
+		}
+	}
+
+	return fmt.Sprintf("%-6s order %s: expected %s, got %s", r.plan.scenario, r.plan.orderID, want, got)
+}
+
+// report prints the pass/fail summary per scenario, the failed orders and
+// webhook latencies. It returns the number of failed orders.
+func report(w io.Writer, results []result, stats *stats, elapsed time.Duration, maxFailures int) int {
+	type counts struct{ passed, failed int }
+	byScenario := make(map[scenario]*counts)
+	var failures []result
+
+	for _, r := range results {
+		c, ok := byScenario[r.plan.scenario]
+		if !ok {
+			c = &counts{}
+			byScenario[r.plan.scenario] = c
+		}
+		if r.passed() {
+			c.passed++
+		} else {
+			c.failed++
+			failures = append(failures, r)
+		}
+	}
+
+	fmt.Fprintln(w, "Orders:")
+	for _, s := range scenarios {
+		if c, ok := byScenario[s]; ok {
+			fmt.Fprintf(w, "  %-6s %d passed, %d failed\n", s, c.passed, c.failed)
+		}
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintln(w, "Failures:")
+		for i, r := range failures {
+			if i == maxFailures {
+				fmt.Fprintf(w, "  ... and %d more\n", len(failures)-maxFailures)
+				break
+			}
+			fmt.Fprintf(w, "  %s\n", r)
+		}
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	fmt.Fprintf(w, "Webhook: %d requests in %s (%.1f/s), %d retries, %d events not accepted after all retries\n",
+		len(stats.latencies), elapsed.Round(time.Millisecond),
+		float64(len(stats.latencies))/elapsed.Seconds(), stats.retries, stats.failed)
+
+	codes := make([]int, 0, len(stats.statuses))
+	for code := range stats.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	var parts []string
+	for _, code := range codes {
+		name := strconv.Itoa(code)
+		if code == 0 {
+			name = "error"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d", name, stats.statuses[code]))
+	}
+	fmt.Fprintf(w, "  responses: %s\n", strings.Join(parts, ", "))
+
+	if len(stats.latencies) > 0 {
+		latencies := make([]time.Duration, len(stats.latencies))
+		copy(latencies, stats.latencies)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		var total time.Duration
+		for _, latency := range latencies {
+			total += latency
+		}
+
+		fmt.Fprintf(w, "  latency: min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+			round(latencies[0]),
+			round(total/time.Duration(len(latencies))),
+			round(percentile(latencies, 50)),
+			round(percentile(latencies, 90)),
+			round(percentile(latencies, 99)),
+			round(latencies[len(latencies)-1]),
+		)
+	}
+
+	if len(failures) == 0 {
+		fmt.Fprintln(w, "PASS")
+	} else {
+		fmt.Fprintln(w, "FAIL")
+	}
+
+	return len(failures)
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(10 * time.Microsecond)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/therealyo/justdone/domain"
+)
+
+// scenario is the lifecycle an order goes through at JustPay!.
+type scenario string
+
+const (
+	scenarioHappy  scenario = "happy"
+	scenarioCancel scenario = "cancel"
+	scenarioRefund scenario = "refund"
+)
+
+var scenarios = []scenario{scenarioHappy, scenarioCancel, scenarioRefund}
+
+func parseScenarios(value string) ([]scenario, error) {
+	var result []scenario
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, s := range scenarios {
+			if string(s) == name {
+				result = append(result, s)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown scenario %q", name)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no scenarios selected")
+	}
+	return result, nil
+}
+
+// event is the webhook payload of JustPay!.
+type event struct {
+	EventID     string             `json:"event_id"`
+	OrderID     string             `json:"order_id"`
+	UserID      string             `json:"user_id"`
+	OrderStatus domain.OrderStatus `json:"order_status"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// delivery is a webhook call of an event, sent after waiting delay
+// since the previous delivery of the same order.
+type delivery struct {
+	event event
+	delay time.Duration
+}
+
+// orderPlan is the generated event stream of an order with its expected outcome.
+type orderPlan struct {
+	scenario   scenario
+	orderID    string
+	events     []event
+	deliveries []delivery
+	// expected is the status the order must end in, finalized orders must also be final
+	expected  domain.OrderStatus
+	finalized bool
+}
+
+// planOptions control how the event stream of an order is delivered.
+type planOptions struct {
+	shuffle    bool
+	duplicates float64
+	maxDelay   time.Duration
+}
+
+func newOrderPlan(random *rand.Rand, s scenario, options planOptions) orderPlan {
+	plan := orderPlan{scenario: s, orderID: uuid.NewString(), expected: domain.Chinazes}
+	userID := uuid.NewString()
+
+	statuses := []domain.OrderStatus{domain.CoolOrderCreated, domain.SbuVerificationPending}
+	switch s {
+	case scenarioHappy:
+		statuses = append(statuses, domain.ConfirmedByMayor, domain.Chinazes)
+	case scenarioCancel:
+		// Orders are cancelled before or after confirmation by the mayor
+		if random.Intn(2) == 0 {
+			statuses = append(statuses, domain.ConfirmedByMayor)
+		}
+		cancel := domain.ChangedMyMind
+		if random.Intn(2) == 0 {
+			cancel = domain.Failed
+		}
+		statuses = append(statuses, cancel)
+		plan.expected, plan.finalized = cancel, true
+	case scenarioRefund:
+		statuses = append(statuses, domain.ConfirmedByMayor, domain.Chinazes, domain.GiveMyMoneyBack)
+		plan.expected, plan.finalized = domain.GiveMyMoneyBack, true
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	for i, status := range statuses {
+		at := createdAt.Add(time.Duration(i) * time.Second)
+		plan.events = append(plan.events, event{
+			EventID:     uuid.NewString(),
+			OrderID:     plan.orderID,
+			UserID:      userID,
+			OrderStatus: status,
+			CreatedAt:   at,
+			UpdatedAt:   at,
+		})
+	}
+
+	order := make([]event, len(plan.events))
+	copy(order, plan.events)
+	if options.shuffle {
+		random.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+
+	for _, e := range order {
+		plan.deliveries = append(plan.deliveries, delivery{event: e, delay: randomDelay(random, options.maxDelay)})
+		if random.Float64() < options.duplicates {
+			plan.deliveries = append(plan.deliveries, delivery{event: e, delay: randomDelay(random, options.maxDelay)})
+		}
+	}
+
+	return plan
+}
+
+func randomDelay(random *rand.Rand, max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(random.Int63n(int64(max)))
+}
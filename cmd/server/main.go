@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
 
 	_ "github.com/therealyo/justdone/docs"
 
@@ -11,18 +14,33 @@ import (
 	"github.com/therealyo/justdone/internal/app"
 )
 
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
 func main() {
 	config, err := config.New()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	level, _ := config.LogLevel()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	// log is only used for fatal startup errors below
+	slog.SetLogLoggerLevel(slog.LevelError)
+	slog.Debug("effective config", "config", config)
+
 	app, err := app.New(config)
 	if err != nil {
 		log.Fatalf("failed to create app: %v", err)
 	}
 
-	server, err := http.NewServer(app).Setup()
+	go app.StuckOrders.Run(context.Background(), config.Stuck.CheckInterval)
+
+	server, err := http.NewServer(app, config).Setup()
 	if err != nil {
 		log.Fatalf("failed to setup server: %v", err)
 	}
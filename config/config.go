@@ -1,21 +1,223 @@
 package config
 
-import "github.com/caarlos0/env/v9"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v9"
+	"github.com/therealyo/justdone/domain"
+)
 
 type Config struct {
 	App struct {
 		Port int `env:"PORT" envDefault:"8080"`
+		// LogLevel is debug, info, warn or error
+		LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	}
+
+	HTTP struct {
+		ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"10s"`
+		ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"30s"`
+		// WriteTimeout also cuts SSE streams and exports, zero disables it
+		WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"0s"`
+		IdleTimeout  time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"2m"`
+		// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For is used as the
+		// client address for rate limits, by default the connection address is used
+		TrustedProxies []string `env:"HTTP_TRUSTED_PROXIES" envDefault:""`
+	}
+
+	Swagger struct {
+		Host string `env:"SWAGGER_HOST" envDefault:"localhost"`
+		// Port defaults to PORT when zero
+		Port int `env:"SWAGGER_PORT" envDefault:"0"`
+	}
+
+	Orders struct {
+		CountExactLimit int `env:"ORDERS_COUNT_EXACT_LIMIT" envDefault:"10000"`
+		// FinalizingTimeout is how long an order in chinazes waits for further events
+		FinalizingTimeout time.Duration `env:"ORDER_FINALIZING_TIMEOUT" envDefault:"30s"`
+		// ProcessingStaleAfter is how long an event claimed by a crashed
+		// instance blocks other instances from processing it
+		ProcessingStaleAfter time.Duration `env:"ORDER_PROCESSING_STALE_AFTER" envDefault:"5m"`
+	}
+
+	Stuck struct {
+		// SLA is how long a non-final order may stay in a status, other statuses are not checked
+		SLA map[string]time.Duration `env:"STUCK_ORDER_SLA" envDefault:"cool_order_created:1h,sbu_verification_pending:1h,confirmed_by_mayor:1h,chinazes:10m" envKeyValSeparator:":"`
+		// CheckInterval is how often subscribers are notified of stuck orders, zero disables it
+		CheckInterval time.Duration `env:"STUCK_ORDER_CHECK_INTERVAL" envDefault:"1m"`
+	}
+
+	Storage struct {
+		// Driver is postgres, sqlite or memory
+		Driver string `env:"STORAGE" envDefault:"postgres"`
+	}
+
+	SQLite struct {
+		Path string `env:"SQLITE_PATH" envDefault:"justdone.db"`
 	}
 
 	Postgres struct {
-		ConnectionString string `env:"POSTGRES_URL"`
+		ConnectionString string `env:"POSTGRES_URL" envDefault:"" secret:"true"`
+		// ReplicaConnectionString optionally serves order listings, counts and stats
+		ReplicaConnectionString string `env:"POSTGRES_REPLICA_URL" envDefault:"" secret:"true"`
+	}
+
+	// Database configures the connection pool of Postgres and SQLite, zero means no limit
+	Database struct {
+		MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"0"`
+		MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"2"`
+		ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"0s"`
+		ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"0s"`
+		// MaxLockConns limits connections holding order locks, which have a pool of their own
+		MaxLockConns int `env:"DB_MAX_LOCK_CONNS" envDefault:"0"`
+		// ConnectAttempts is how many times Postgres is pinged on startup,
+		// waiting ConnectBackoff after the first failure and twice as long after every next one
+		ConnectAttempts   int           `env:"DB_CONNECT_ATTEMPTS" envDefault:"10"`
+		ConnectBackoff    time.Duration `env:"DB_CONNECT_BACKOFF" envDefault:"500ms"`
+		ConnectMaxBackoff time.Duration `env:"DB_CONNECT_MAX_BACKOFF" envDefault:"10s"`
+	}
+
+	SSE struct {
+		// ClientTimeout disconnects subscribers that were sent no event for the duration
+		ClientTimeout time.Duration `env:"SSE_CLIENT_TIMEOUT" envDefault:"1m"`
+		// ClientBuffer is the number of events queued for a subscriber, a subscriber
+		// with a full queue is disconnected and has to resync
+		ClientBuffer int `env:"SSE_CLIENT_BUFFER" envDefault:"256"`
+	}
+
+	Webhook struct {
+		// SequenceViolationStatus is returned for events rejected for an illegal transition
+		SequenceViolationStatus int `env:"WEBHOOK_SEQUENCE_VIOLATION_STATUS" envDefault:"202"`
+	}
+
+	Idempotency struct {
+		TTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+	}
+
+	Auth struct {
+		Enabled         bool     `env:"AUTH_ENABLED" envDefault:"false"`
+		APIKeys         []string `env:"AUTH_API_KEYS" envDefault:"" secret:"true"`
+		JWTSecret       string   `env:"AUTH_JWT_SECRET" envDefault:"" secret:"true"`
+		JWKSFile        string   `env:"AUTH_JWKS_FILE" envDefault:""`
+		BackofficeScope string   `env:"AUTH_BACKOFFICE_SCOPE" envDefault:"orders:read:all"`
+	}
+
+	RateLimit struct {
+		OrdersPerSecond     float64       `env:"ORDERS_RATE_LIMIT" envDefault:"10"`
+		OrdersBurst         int           `env:"ORDERS_RATE_BURST" envDefault:"20"`
+		SubscribersPerOrder int           `env:"SSE_MAX_SUBSCRIBERS_PER_ORDER" envDefault:"20"`
+		SubscribersPerUser  int           `env:"SSE_MAX_SUBSCRIBERS_PER_USER" envDefault:"10"`
+		SubscribersTotal    int           `env:"SSE_MAX_SUBSCRIBERS" envDefault:"1000"`
+		SubscribeRetryAfter time.Duration `env:"SSE_RETRY_AFTER" envDefault:"30s"`
 	}
 }
 
+// New loads the config from environment variables. When CONFIG_FILE is set,
+// the YAML file provides the variables that are not set in the environment.
 func New() (*Config, error) {
+	environment := make(map[string]string)
+	for _, variable := range os.Environ() {
+		if key, value, ok := strings.Cut(variable, "="); ok {
+			environment[key] = value
+		}
+	}
+
+	if path := environment["CONFIG_FILE"]; path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			if _, ok := environment[key]; !ok {
+				environment[key] = value
+			}
+		}
+	}
+
 	cfg := Config{}
-	if err := env.ParseWithOptions(&cfg, env.Options{RequiredIfNoDef: true}); err != nil {
+	if err := env.ParseWithOptions(&cfg, env.Options{RequiredIfNoDef: true, Environment: environment}); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 	return &cfg, nil
 }
+
+// Validate reports every invalid value at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.App.Port > 0 && c.App.Port <= 65535, "PORT must be a TCP port, got %d", c.App.Port)
+	_, err := c.LogLevel()
+	check(err == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", c.App.LogLevel)
+
+	check(c.HTTP.ReadHeaderTimeout >= 0, "HTTP_READ_HEADER_TIMEOUT must not be negative, got %s", c.HTTP.ReadHeaderTimeout)
+	check(c.HTTP.ReadTimeout >= 0, "HTTP_READ_TIMEOUT must not be negative, got %s", c.HTTP.ReadTimeout)
+	check(c.HTTP.WriteTimeout >= 0, "HTTP_WRITE_TIMEOUT must not be negative, got %s", c.HTTP.WriteTimeout)
+	check(c.HTTP.IdleTimeout >= 0, "HTTP_IDLE_TIMEOUT must not be negative, got %s", c.HTTP.IdleTimeout)
+	for _, proxy := range c.HTTP.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "HTTP_TRUSTED_PROXIES must have IPs or CIDRs, got %q", proxy)
+	}
+
+	check(c.Swagger.Port >= 0 && c.Swagger.Port <= 65535, "SWAGGER_PORT must be a TCP port or 0, got %d", c.Swagger.Port)
+
+	check(c.Orders.FinalizingTimeout > 0, "ORDER_FINALIZING_TIMEOUT must be positive, got %s", c.Orders.FinalizingTimeout)
+	check(c.Orders.ProcessingStaleAfter > 0, "ORDER_PROCESSING_STALE_AFTER must be positive, got %s", c.Orders.ProcessingStaleAfter)
+
+	for name, sla := range c.Stuck.SLA {
+		status, err := domain.ParseOrderStatus(name)
+		check(err == nil && !status.IsFinal(), "STUCK_ORDER_SLA must have non-final statuses, got %q", name)
+		check(sla > 0, "STUCK_ORDER_SLA of %s must be positive, got %s", name, sla)
+	}
+	check(c.Stuck.CheckInterval >= 0, "STUCK_ORDER_CHECK_INTERVAL must not be negative, got %s", c.Stuck.CheckInterval)
+
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative, got %d", c.Database.MaxOpenConns)
+	check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative, got %d", c.Database.MaxIdleConns)
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative, got %s", c.Database.ConnMaxLifetime)
+	check(c.Database.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative, got %s", c.Database.ConnMaxIdleTime)
+	check(c.Database.MaxLockConns >= 0, "DB_MAX_LOCK_CONNS must not be negative, got %d", c.Database.MaxLockConns)
+	check(c.Database.ConnectAttempts > 0, "DB_CONNECT_ATTEMPTS must be positive, got %d", c.Database.ConnectAttempts)
+	check(c.Database.ConnectBackoff >= 0, "DB_CONNECT_BACKOFF must not be negative, got %s", c.Database.ConnectBackoff)
+	check(c.Database.ConnectMaxBackoff >= c.Database.ConnectBackoff,
+		"DB_CONNECT_MAX_BACKOFF must not be less than DB_CONNECT_BACKOFF, got %s", c.Database.ConnectMaxBackoff)
+
+	check(c.SSE.ClientTimeout > 0, "SSE_CLIENT_TIMEOUT must be positive, got %s", c.SSE.ClientTimeout)
+	check(c.SSE.ClientBuffer > 0, "SSE_CLIENT_BUFFER must be positive, got %d", c.SSE.ClientBuffer)
+
+	status := c.Webhook.SequenceViolationStatus
+	check(status >= 200 && status <= 599, "WEBHOOK_SEQUENCE_VIOLATION_STATUS must be an HTTP status, got %d", status)
+
+	check(c.Idempotency.TTL > 0, "IDEMPOTENCY_TTL must be positive, got %s", c.Idempotency.TTL)
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) LogLevel() (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(c.App.LogLevel))
+	return level, err
+}
+
+// StuckOrderSLA returns the validated STUCK_ORDER_SLA by status.
+func (c *Config) StuckOrderSLA() map[domain.OrderStatus]time.Duration {
+	slas := make(map[domain.OrderStatus]time.Duration, len(c.Stuck.SLA))
+	for name, sla := range c.Stuck.SLA {
+		if status, err := domain.ParseOrderStatus(name); err == nil {
+			slas[status] = sla
+		}
+	}
+	return slas
+}
@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/config"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestConfigFile(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "ORDER_FINALIZING_TIMEOUT: 45s\nDB_MAX_OPEN_CONNS: 20\nAUTH_API_KEYS: [first, second]\nSTUCK_ORDER_SLA: {chinazes: 5m}\n"))
+	t.Setenv("DB_MAX_OPEN_CONNS", "30")
+
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Orders.FinalizingTimeout != 45*time.Second {
+		t.Errorf("Expected finalizing timeout from the file, got %s", cfg.Orders.FinalizingTimeout)
+	}
+	if len(cfg.Auth.APIKeys) != 2 || cfg.Auth.APIKeys[1] != "second" {
+		t.Errorf("Expected API keys from the file, got %v", cfg.Auth.APIKeys)
+	}
+	if sla := cfg.Stuck.SLA; len(sla) != 1 || sla["chinazes"] != 5*time.Minute {
+		t.Errorf("Expected stuck order SLA from the file, got %v", sla)
+	}
+	// Environment variables take precedence over the file
+	if cfg.Database.MaxOpenConns != 30 {
+		t.Errorf("Expected max open connections from the environment, got %d", cfg.Database.MaxOpenConns)
+	}
+	// Variables missing from both keep their defaults
+	if cfg.SSE.ClientTimeout != time.Minute {
+		t.Errorf("Expected default SSE client timeout, got %s", cfg.SSE.ClientTimeout)
+	}
+}
+
+func TestConfigFileUnknownVariable(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "ORDER_FINALIZNG_TIMEOUT: 45s\n"))
+
+	if _, err := config.New(); err == nil || !strings.Contains(err.Error(), "ORDER_FINALIZNG_TIMEOUT") {
+		t.Errorf("Expected the unknown variable to be reported, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Setenv("ORDER_FINALIZING_TIMEOUT", "0s")
+	t.Setenv("WEBHOOK_SEQUENCE_VIOLATION_STATUS", "42")
+	t.Setenv("STUCK_ORDER_SLA", "failed:1h")
+	t.Setenv("HTTP_TRUSTED_PROXIES", "10.0.0.0/8,proxy.local")
+
+	_, err := config.New()
+	if err == nil {
+		t.Fatalf("Expected invalid config to be rejected")
+	}
+	for _, variable := range []string{"ORDER_FINALIZING_TIMEOUT", "WEBHOOK_SEQUENCE_VIOLATION_STATUS", "STUCK_ORDER_SLA", "HTTP_TRUSTED_PROXIES"} {
+		if !strings.Contains(err.Error(), variable) {
+			t.Errorf("Expected %s to be reported, got %v", variable, err)
+		}
+	}
+}
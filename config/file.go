@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a YAML file that maps environment variables to values:
+//
+//	ORDER_FINALIZING_TIMEOUT: 45s
+//	DB_MAX_OPEN_CONNS: 20
+//	AUTH_API_KEYS: [first, second]
+//	STUCK_ORDER_SLA: {chinazes: 10m}
+//
+// Unknown variables are rejected, so typos don't go unnoticed.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]reflect.StructField)
+	walk(reflect.ValueOf(Config{}), func(field reflect.StructField, _ reflect.Value) {
+		known[field.Tag.Get("env")] = field
+	})
+
+	values := make(map[string]string, len(document))
+	for key, value := range document {
+		field, ok := known[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s in config file %s", key, path)
+		}
+
+		switch value := value.(type) {
+		case nil:
+			values[key] = ""
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			separator := field.Tag.Get("envKeyValSeparator")
+			if separator == "" {
+				separator = ":"
+			}
+			items := make([]string, 0, len(value))
+			for name, item := range value {
+				items = append(items, name+separator+fmt.Sprint(item))
+			}
+			sort.Strings(items)
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+
+	return values, nil
+}
+
+// walk calls fn for every field of the config read from an environment variable.
+func walk(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if _, ok := field.Tag.Lookup("env"); ok {
+			fn(field, v.Field(i))
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), fn)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// LogValue lists the effective value of every environment variable.
+// Values of secrets are redacted.
+func (c Config) LogValue() slog.Value {
+	var attrs []slog.Attr
+	walk(reflect.ValueOf(c), func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("env")
+		empty := value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0)
+		if field.Tag.Get("secret") == "true" && !empty {
+			attrs = append(attrs, slog.String(name, "[redacted]"))
+			return
+		}
+		attrs = append(attrs, slog.Any(name, value.Interface()))
+	})
+	return slog.GroupValue(attrs...)
+}
@@ -15,9 +15,906 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Events that were rejected, failed or ignored by the processor, most recently failed first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dead letter events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the order to list events of.",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of events to return. Default is 10.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination. Default is 0.",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.DeadLetterEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{event_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "The delivered event with the reason it was not applied and the number of attempts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Inspect a dead letter event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "event_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DeadLetterEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the event from the dead letters without processing it.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Discard a dead letter event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "event_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{event_id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Feeds the event to the processor again. An applied event is removed from the dead letters,\notherwise the reason is updated and another attempt counted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retry a dead letter event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event ID",
+                        "name": "event_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Matches rows of a JustPay! settlement file to orders by order_id and reports missing orders,\nstatus mismatches and orders final on one side only. Stored orders created in the period that are\nmissing from the file are reported too, the period defaults to the creation times of the stored\norders in the file. The file is a CSV with a header or a JSON array with order_id, status and\noptional user_id and is_final. With correct=true the missing events of every discrepancy are\nprocessed as synthetic events and recorded in the audit trail.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reconcile a settlement file",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Settlement file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File format (csv/json). Default is the file extension.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Create corrective events. Default is false.",
+                        "name": "correct",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the settlement period (RFC3339 or YYYY-MM-DD), inclusive",
+                        "name": "period_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the settlement period (RFC3339 or YYYY-MM-DD), exclusive",
+                        "name": "period_to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ReconciliationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation/corrections": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Audit trail of synthetic events created by reconciliation runs, most recent first.\nCorrections that were not applied have an error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List reconciliation corrections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the reconciliation run.",
+                        "name": "run_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the order.",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of corrections to return. Default is 10.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination. Default is 0.",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ReconciliationCorrection"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
         "/orders": {
             "get": {
-                "description": "Retrieve a list of orders with optional filtering and sorting.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve a list of orders with optional filtering and sorting.\nPagination links are returned in the Link header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Retrieve a list of orders",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "List of order statuses to filter by.",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "IDs of the users to filter orders by.",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "IDs of the orders to return.",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Final status of the order.",
+                        "name": "is_final",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created at or after this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created before this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders updated at or after this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders updated before this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Orders that have ever been in any of the statuses.",
+                        "name": "ever_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Non-final orders not updated for this many minutes.",
+                        "name": "stuck_for",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders with an amount of at least this many minor units.",
+                        "name": "amount_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders with an amount of at most this many minor units.",
+                        "name": "amount_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "ISO 4217 currencies of the order amount to filter by.",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of orders to return. Default is 10.",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination. Default is 0.",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort by (created_at/updated_at/order_id/user_id/status), prefix with - for descending order. Default is -created_at.",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deprecated, use sort. Field to sort by.",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Deprecated, use sort. Sort order (asc/desc).",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap orders in a page envelope with the total count. Default is false.",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bare array, or {items, total, total_estimated, limit, offset, next} when envelope is true",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Order"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Pagination links (first, prev, next, last)"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream orders matching the filters as CSV or NDJSON.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Export orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format (csv/ndjson). Default is csv.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns (order_id,user_id,status,is_final,created_at,updated_at,amount,currency,metadata). Default is all.",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "List of order statuses to filter by.",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "IDs of the users to filter orders by.",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "IDs of the orders to export.",
+                        "name": "order_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Final status of the order.",
+                        "name": "is_final",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created at or after this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders created before this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders updated at or after this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Orders updated before this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Orders that have ever been in any of the statuses.",
+                        "name": "ever_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Non-final orders not updated for this many minutes.",
+                        "name": "stuck_for",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders with an amount of at least this many minor units.",
+                        "name": "amount_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Orders with an amount of at most this many minor units.",
+                        "name": "amount_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "ISO 4217 currencies of the order amount to filter by.",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort by (created_at/updated_at/order_id/user_id/status), prefix with - for descending order. Default is -created_at.",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Order counts grouped by status, day or hour, conversion to chinazes, refund and cancel rates\nand median time between statuses.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,52 +924,108 @@ const docTemplate = `{
                 "tags": [
                     "orders"
                 ],
-                "summary": "Retrieve a list of orders",
+                "summary": "Retrieve order statistics",
                 "parameters": [
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "List of order statuses to filter by. Required if ` + "`" + `is_final` + "`" + ` is not provided.",
-                        "name": "status",
+                        "type": "string",
+                        "description": "Include orders created at or after this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "from",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "ID of the user to filter orders by.",
-                        "name": "user_id",
+                        "description": "Include orders created before this time (RFC3339 or YYYY-MM-DD).",
+                        "name": "to",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Number of orders to return. Default is 10.",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "ID of the user to calculate statistics for.",
+                        "name": "user_id",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Offset for pagination. Default is 0.",
-                        "name": "offset",
+                        "type": "string",
+                        "description": "Grouping of order counts (status/day/hour). Default is status.",
+                        "name": "group_by",
                         "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.OrderStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/stuck": {
+            "get": {
+                "security": [
                     {
-                        "type": "boolean",
-                        "description": "Final status of the order. Required if ` + "`" + `status` + "`" + ` is not provided.",
-                        "name": "is_final",
-                        "in": "query"
+                        "ApiKeyAuth": []
                     },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Non-final orders that stayed in their status longer than the SLA of the status (STUCK_ORDER_SLA),\nthe longest stuck first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "List stuck orders",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Field to sort by (created_at/updated_at). Default is created_at.",
-                        "name": "sort_by",
+                        "description": "ID of the user to list stuck orders for.",
+                        "name": "user_id",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Sort order (asc/desc). Default is desc.",
-                        "name": "sort_order",
+                        "type": "integer",
+                        "description": "Maximum number of orders (1-1000). Default is 100.",
+                        "name": "limit",
                         "in": "query"
                     }
                 ],
@@ -82,7 +1035,43 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Order"
+                                "$ref": "#/definitions/domain.OrderStuck"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     }
@@ -91,7 +1080,15 @@ const docTemplate = `{
         },
         "/orders/{order_id}/events": {
             "get": {
-                "description": "Stream events for an order using Server-Side Events (SSE).",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream events for an order using Server-Side Events (SSE).\nEvents rejected for an illegal transition are sent as \"anomaly\" events with a rejected_reason.\nOrders staying in a status longer than its SLA are reported with \"stuck\" events.\nA client that can't keep up with the events (SSE_CLIENT_BUFFER) gets a \"resync\" event and is\ndisconnected, it has to subscribe again to get the stored events of the order.",
                 "consumes": [
                     "application/json"
                 ],
@@ -117,6 +1114,192 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/domain.OrderEvent"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{order_id}/events/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stream all stored events of an order as CSV or NDJSON.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Export order events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the order",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format (csv/ndjson). Default is csv.",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns (event_id,order_id,user_id,order_status,created_at,updated_at,is_final,rejected_reason,amount,currency,metadata). Default is all.",
+                        "name": "columns",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{order_id}/timeline": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Transitions of an order built from its stored events, with the time spent in every status,\nevents ignored as out of sequence, and when and by what (timer or terminal event) the order was finalized.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orders"
+                ],
+                "summary": "Order timeline",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the order",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.OrderTimeline"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 }
             }
@@ -141,15 +1324,84 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/http.postEventRequest"
+                            "$ref": "#/definitions/http.postEventRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.OrderEvent"
+                        }
+                    },
+                    "202": {
+                        "description": "Stored but not applied, the status is configurable",
+                        "schema": {
+                            "$ref": "#/definitions/http.sequenceViolationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Duplicate event, with Retry-After while the original is processed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reused for another payload",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
-                            "$ref": "#/definitions/domain.OrderEvent"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
@@ -157,15 +1409,119 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "domain.CurrencyTotal": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Amount is in minor units of the currency",
+                    "type": "integer"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DeadLetterEvent": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts counts how many times processing of the event failed",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the event as it was delivered",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.OrderEvent"
+                        }
+                    ]
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Discrepancy": {
+            "type": "object",
+            "properties": {
+                "corrections": {
+                    "description": "Corrections are the statuses of events that would bring the order to the\nsettlement status, empty when it can't be corrected with events",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.OrderStatus"
+                    }
+                },
+                "kind": {
+                    "$ref": "#/definitions/domain.DiscrepancyKind"
+                },
+                "order_final": {
+                    "type": "boolean"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "order_status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "settlement_final": {
+                    "type": "boolean"
+                },
+                "settlement_status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                }
+            }
+        },
+        "domain.DiscrepancyKind": {
+            "type": "string",
+            "enum": [
+                "missing_order",
+                "status_mismatch",
+                "final_mismatch",
+                "missing_settlement"
+            ],
+            "x-enum-varnames": [
+                "DiscrepancyMissingOrder",
+                "DiscrepancyStatusMismatch",
+                "DiscrepancyFinalMismatch",
+                "DiscrepancyMissingSettlement"
+            ]
+        },
         "domain.Order": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "description": "Amount is in minor units of the currency, e.g. cents",
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "currency": {
+                    "description": "Currency is an ISO 4217 code, set together with the amount",
+                    "type": "string"
+                },
                 "is_final": {
                     "type": "boolean"
                 },
+                "metadata": {
+                    "description": "Metadata is a free-form JSON object",
+                    "type": "object"
+                },
                 "order_id": {
                     "type": "string"
                 },
@@ -183,21 +1539,37 @@ const docTemplate = `{
         "domain.OrderEvent": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "description": "Amount is in minor units of the currency, e.g. cents",
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "currency": {
+                    "description": "Currency is an ISO 4217 code, set together with the amount",
+                    "type": "string"
+                },
                 "event_id": {
                     "type": "string"
                 },
                 "is_final": {
                     "type": "boolean"
                 },
+                "metadata": {
+                    "description": "Metadata is a free-form JSON object",
+                    "type": "object"
+                },
                 "order_id": {
                     "type": "string"
                 },
                 "order_status": {
                     "$ref": "#/definitions/domain.OrderStatus"
                 },
+                "rejected_reason": {
+                    "description": "RejectedReason is set while the event is stored but not applied to its order",
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 },
@@ -206,6 +1578,57 @@ const docTemplate = `{
                 }
             }
         },
+        "domain.OrderStats": {
+            "type": "object",
+            "properties": {
+                "amounts": {
+                    "description": "Amounts are the totals of orders with an amount, by currency",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CurrencyTotal"
+                    }
+                },
+                "cancel_rate": {
+                    "description": "CancelRate is the share of orders that were canceled or failed",
+                    "type": "number"
+                },
+                "canceled": {
+                    "type": "integer"
+                },
+                "chinazes": {
+                    "type": "integer"
+                },
+                "conversion": {
+                    "description": "Conversion is the share of orders that reached chinazes",
+                    "type": "number"
+                },
+                "group_by": {
+                    "$ref": "#/definitions/domain.StatsGroupBy"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.StatsGroup"
+                    }
+                },
+                "refund_rate": {
+                    "description": "RefundRate is the share of chinazes orders that were refunded",
+                    "type": "number"
+                },
+                "refunded": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TransitionStats"
+                    }
+                }
+            }
+        },
         "domain.OrderStatus": {
             "type": "string",
             "enum": [
@@ -227,6 +1650,221 @@ const docTemplate = `{
                 "GiveMyMoneyBack"
             ]
         },
+        "domain.OrderStuck": {
+            "type": "object",
+            "properties": {
+                "order_id": {
+                    "type": "string"
+                },
+                "sla_seconds": {
+                    "type": "number"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "stuck_seconds": {
+                    "description": "StuckSeconds is the time since the last update of the order",
+                    "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.OrderTimeline": {
+            "type": "object",
+            "properties": {
+                "finalization": {
+                    "$ref": "#/definitions/domain.TimelineFinalization"
+                },
+                "is_final": {
+                    "type": "boolean"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TimelineTransition"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ReconciliationCorrection": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error is why the event was not applied, empty when it was",
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "run_id": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "Source is the name of the settlement file",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                }
+            }
+        },
+        "domain.ReconciliationReport": {
+            "type": "object",
+            "properties": {
+                "corrections": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ReconciliationCorrection"
+                    }
+                },
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Discrepancy"
+                    }
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "period": {
+                    "description": "Period is the range of stored orders expected in the file, it is not\nset when no stored order was found to derive it from",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.SettlementPeriod"
+                        }
+                    ]
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "run_id": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.SettlementPeriod": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.StatsGroup": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.StatsGroupBy": {
+            "type": "string",
+            "enum": [
+                "status",
+                "day",
+                "hour"
+            ],
+            "x-enum-varnames": [
+                "GroupByStatus",
+                "GroupByDay",
+                "GroupByHour"
+            ]
+        },
+        "domain.TimelineFinalization": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "by": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                }
+            }
+        },
+        "domain.TimelineTransition": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_seconds": {
+                    "description": "DurationSeconds is the time spent in the To status until the next\napplied event, or until now for the current non-final status",
+                    "type": "number"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "rejected_reason": {
+                    "description": "RejectedReason is the illegal transition of an ignored event",
+                    "type": "string"
+                },
+                "to": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TransitionStats": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "from": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "median_seconds": {
+                    "type": "number"
+                },
+                "to": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                }
+            }
+        },
         "http.postEventRequest": {
             "type": "object",
             "required": [
@@ -238,12 +1876,26 @@ const docTemplate = `{
                 "user_id"
             ],
             "properties": {
+                "amount": {
+                    "description": "Amount is in minor units of the currency, e.g. cents",
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "currency": {
+                    "description": "Currency is an ISO 4217 code, required with the amount",
+                    "type": "string",
+                    "example": "EUR"
+                },
                 "event_id": {
                     "type": "string"
                 },
+                "metadata": {
+                    "description": "Metadata is a free-form JSON object of up to 16KB",
+                    "type": "object"
+                },
                 "order_id": {
                     "type": "string"
                 },
@@ -266,6 +1918,32 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "http.sequenceViolationResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "from": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                },
+                "to": {
+                    "$ref": "#/definitions/domain.OrderStatus"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
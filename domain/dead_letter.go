@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// DeadLetterEvent is an event the processor rejected, failed on or ignored,
+// kept with the reason so it can be inspected and retried.
+type DeadLetterEvent struct {
+	EventID string `json:"event_id"`
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+	// Payload is the event as it was delivered
+	Payload OrderEvent `json:"payload"`
+	// Attempts counts how many times processing of the event failed
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeadLetterFilter struct {
+	OrderID string
+	Limit   int
+	Offset  int
+}
+
+type DeadLetterRepository interface {
+	// Add stores the event with the reason. For an event that is already
+	// stored it replaces the reason and payload and counts another attempt.
+	Add(event OrderEvent, reason string) error
+	// Get returns nil if the event is not stored
+	Get(eventID string) (*DeadLetterEvent, error)
+	// List returns events from the most recently failed one
+	List(filter DeadLetterFilter) ([]DeadLetterEvent, error)
+	// Delete removes the events, ignoring ones that are not stored
+	Delete(eventIDs ...string) error
+}
@@ -6,8 +6,25 @@ var (
 	ErrEventConflict     = errors.New("event already exists")
 	ErrOrderAlreadyFinal = errors.New("order already in final state")
 	ErrOrderNotFound     = errors.New("order not found")
+	// ErrEventInProgress is returned for a duplicate of an event that is
+	// still being processed
+	ErrEventInProgress = errors.New("event is being processed")
+	// ErrConcurrentModification is returned when saving an order that was
+	// changed since it was read
+	ErrConcurrentModification = errors.New("order was modified concurrently")
+	ErrDeadLetterNotFound     = errors.New("dead letter event not found")
 )
 
 func IsDomainError(err error) bool {
-	return errors.Is(err, ErrEventConflict) || errors.Is(err, ErrOrderAlreadyFinal) || errors.Is(err, ErrOrderNotFound)
+	var violation *SequenceViolation
+	return errors.Is(err, ErrEventConflict) || errors.Is(err, ErrEventInProgress) || errors.Is(err, ErrOrderAlreadyFinal) ||
+		errors.Is(err, ErrOrderNotFound) || errors.As(err, &violation)
+}
+
+// IsRejection reports whether the event was rejected by the rules of its
+// order, so delivering it again fails the same way until the order changes.
+// Other errors are duplicates or transient failures of the storage.
+func IsRejection(err error) bool {
+	var violation *SequenceViolation
+	return errors.Is(err, ErrOrderAlreadyFinal) || errors.Is(err, ErrOrderNotFound) || errors.As(err, &violation)
 }
@@ -1,6 +1,19 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// PaymentDetails are the optional amount and metadata JustPay! sends with events.
+type PaymentDetails struct {
+	// Amount is in minor units of the currency, e.g. cents
+	Amount *int64 `json:"amount,omitempty"`
+	// Currency is an ISO 4217 code, set together with the amount
+	Currency string `json:"currency,omitempty"`
+	// Metadata is a free-form JSON object
+	Metadata json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+}
 
 type OrderEvent struct {
 	EventID     string      `json:"event_id"`
@@ -10,11 +23,14 @@ type OrderEvent struct {
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 	IsFinal     bool        `json:"is_final"`
+	// RejectedReason is set while the event is stored but not applied to its order
+	RejectedReason string `json:"rejected_reason,omitempty"`
+	PaymentDetails
 }
 
-func (e *OrderEvent) Finalize() *OrderEvent {
+func (e *OrderEvent) Finalize(now time.Time) *OrderEvent {
 	e.IsFinal = true
-	e.UpdatedAt = time.Now()
+	e.UpdatedAt = now
 	return e
 }
 
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// IdempotentResponse is the response to a request, replayed when the
+// request is retried with the same idempotency key.
+type IdempotentResponse struct {
+	Key string
+	// RequestHash identifies the request body the response was produced for
+	RequestHash string
+	Status      int
+	// Headers are the response headers replayed with the body
+	Headers   map[string][]string
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+type IdempotencyStore interface {
+	// Get returns the stored response, or nil if the key is unknown or expired
+	Get(key string) (*IdempotentResponse, error)
+	// Save stores the response unless an unexpired response exists for the key
+	Save(response IdempotentResponse) error
+}
@@ -15,17 +15,44 @@ type Order struct {
 	LastEvent *OrderEvent  `json:"-"`
 	CreatedAt time.Time    `json:"created_at"`
 	UpdatedAt time.Time    `json:"updated_at"`
+	// Version is incremented on every save, zero means the order is not stored
+	// yet. It is internal to optimistic locking and not part of the API.
+	Version int64 `json:"-"`
+	// PaymentDetails are carried forward from the applied events
+	PaymentDetails
 }
 
-func (o *Order) isValidSequence() bool {
-	requiredSequence := []OrderStatus{
-		CoolOrderCreated,
-		SbuVerificationPending,
-		ConfirmedByMayor,
-		Chinazes,
-		GiveMyMoneyBack,
+// applyPaymentDetails carries the amount and metadata of the event forward to
+// the order. Events without them keep the values of earlier events.
+func (o *Order) applyPaymentDetails(event OrderEvent) {
+	if event.Amount != nil {
+		o.Amount = event.Amount
+		o.Currency = event.Currency
+	}
+	if len(event.Metadata) > 0 {
+		o.Metadata = event.Metadata
+	}
+}
+
+func (o *Order) hasEvent(eventID string) bool {
+	for _, event := range o.Events {
+		if event.EventID == eventID {
+			return true
+		}
 	}
+	return false
+}
 
+// requiredSequence is the lifecycle of an order that is not cancelled.
+var requiredSequence = []OrderStatus{
+	CoolOrderCreated,
+	SbuVerificationPending,
+	ConfirmedByMayor,
+	Chinazes,
+	GiveMyMoneyBack,
+}
+
+func (o *Order) isValidSequence() bool {
 	currentSequence := []OrderStatus{}
 
 	for _, event := range o.Events {
@@ -35,14 +62,64 @@ func (o *Order) isValidSequence() bool {
 	return array.IsSubArray(currentSequence, requiredSequence)
 }
 
+// sequenceViolation returns the first transition of the events that breaks
+// the required sequence, for an order whose sequence is not valid.
+func (o *Order) sequenceViolation(event OrderEvent) *SequenceViolation {
+	violation := &SequenceViolation{OrderID: o.OrderID, EventID: event.EventID, To: event.OrderStatus}
+
+	for i, current := range o.Events {
+		if i < len(requiredSequence) && current.OrderStatus == requiredSequence[i] {
+			continue
+		}
+
+		violation.To = current.OrderStatus
+		if i > 0 {
+			violation.From = o.Events[i-1].OrderStatus
+		}
+		break
+	}
+
+	return violation
+}
+
+// OrderCount is the number of orders matching a filter. Estimated is set
+// when counting exactly was too expensive and an approximation is returned.
+type OrderCount struct {
+	Total     int  `json:"total"`
+	Estimated bool `json:"estimated"`
+}
+
 type OrderFilter struct {
-	Status    []OrderStatus
-	UserID    string
-	IsFinal   *bool
-	Limit     int
-	Offset    int
-	SortBy    string
-	SortOrder string
+	Status      []OrderStatus
+	UserIDs     []string
+	OrderIDs    []string
+	IsFinal     *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// EverInStatus matches orders that have an event with any of the statuses
+	EverInStatus []OrderStatus
+	// StuckFor matches non-final orders that were not updated for the duration
+	// before Now
+	StuckFor time.Duration
+	// Now is the reference time of relative conditions, set from the clock
+	// of the use case
+	Now time.Time
+	// AmountFrom and AmountTo are inclusive bounds of the amount in minor units,
+	// orders without an amount don't match them
+	AmountFrom *int64
+	AmountTo   *int64
+	Currencies []string
+	Limit      int
+	Offset     int
+	Sort       OrderSort
+}
+
+// StuckBefore returns the time non-final orders were last updated before to
+// match StuckFor.
+func (f *OrderFilter) StuckBefore() time.Time {
+	return f.Now.Add(-f.StuckFor)
 }
 
 type FilterOption func(*OrderFilter)
@@ -55,7 +132,66 @@ func WithStatus(statuses ...OrderStatus) FilterOption {
 
 func WithUserID(userID string) FilterOption {
 	return func(f *OrderFilter) {
-		f.UserID = userID
+		if userID != "" {
+			f.UserIDs = append(f.UserIDs, userID)
+		}
+	}
+}
+
+func WithUserIDs(userIDs ...string) FilterOption {
+	return func(f *OrderFilter) {
+		f.UserIDs = append(f.UserIDs, userIDs...)
+	}
+}
+
+func WithOrderIDs(orderIDs ...string) FilterOption {
+	return func(f *OrderFilter) {
+		f.OrderIDs = append(f.OrderIDs, orderIDs...)
+	}
+}
+
+func WithCreatedBetween(from, to *time.Time) FilterOption {
+	return func(f *OrderFilter) {
+		f.CreatedFrom = from
+		f.CreatedTo = to
+	}
+}
+
+func WithUpdatedBetween(from, to *time.Time) FilterOption {
+	return func(f *OrderFilter) {
+		f.UpdatedFrom = from
+		f.UpdatedTo = to
+	}
+}
+
+func WithEverInStatus(statuses ...OrderStatus) FilterOption {
+	return func(f *OrderFilter) {
+		f.EverInStatus = append(f.EverInStatus, statuses...)
+	}
+}
+
+func WithStuckFor(duration time.Duration) FilterOption {
+	return func(f *OrderFilter) {
+		f.StuckFor = duration
+	}
+}
+
+func WithNow(now time.Time) FilterOption {
+	return func(f *OrderFilter) {
+		f.Now = now
+	}
+}
+
+func WithAmountBetween(from, to *int64) FilterOption {
+	return func(f *OrderFilter) {
+		f.AmountFrom = from
+		f.AmountTo = to
+	}
+}
+
+func WithCurrencies(currencies ...string) FilterOption {
+	return func(f *OrderFilter) {
+		f.Currencies = append(f.Currencies, currencies...)
 	}
 }
 
@@ -77,24 +213,19 @@ func WithIsFinal(isFinal *bool) FilterOption {
 	}
 }
 
-func WithSortBy(sortBy string) FilterOption {
-	return func(f *OrderFilter) {
-		f.SortBy = sortBy
-	}
-}
-
-func WithSortOrder(sortOrder string) FilterOption {
+func WithSort(sort OrderSort) FilterOption {
 	return func(f *OrderFilter) {
-		f.SortOrder = sortOrder
+		if len(sort) > 0 {
+			f.Sort = sort
+		}
 	}
 }
 
 func NewOrderFilter(options ...FilterOption) *OrderFilter {
 	filter := &OrderFilter{
-		Limit:     10,
-		Offset:    0,
-		SortBy:    "created_at",
-		SortOrder: "desc",
+		Limit:  10,
+		Offset: 0,
+		Sort:   DefaultOrderSort,
 	}
 
 	for _, option := range options {
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"sync"
+	"time"
+)
+
+// DisconnectReason tells why a subscriber was disconnected.
+type DisconnectReason string
+
+const (
+	// DisconnectClosed is a subscriber that was unregistered
+	DisconnectClosed DisconnectReason = "closed"
+	// DisconnectIdle is a subscriber that was sent no event for its idle timeout
+	DisconnectIdle DisconnectReason = "idle"
+	// DisconnectResync is a subscriber whose queue overflowed. Events were not
+	// delivered to it, so it has to reload the order to get them.
+	DisconnectResync DisconnectReason = "resync"
+)
+
+// OrderNotification is queued for the subscribers of an order. It is an
+// event of the order, or a notice that the order is stuck when Stuck is set.
+type OrderNotification struct {
+	Event OrderEvent
+	Stuck *OrderStuck
+}
+
+// OrderEventsSubscriber receives the events of an order through a bounded
+// queue in the order they were sent. Events are never dropped silently: when
+// the queue is full the subscriber is disconnected with DisconnectResync.
+type OrderEventsSubscriber struct {
+	events chan OrderNotification
+	done   chan struct{}
+	once   sync.Once
+	reason DisconnectReason
+	// IdleTimeout disconnects the subscriber when it was sent no event for the duration
+	IdleTimeout time.Duration
+}
+
+func NewOrderEventsSubscriber(queueSize int, idleTimeout time.Duration) *OrderEventsSubscriber {
+	return &OrderEventsSubscriber{
+		events:      make(chan OrderNotification, queueSize),
+		done:        make(chan struct{}),
+		IdleTimeout: idleTimeout,
+	}
+}
+
+// Events returns the queue of the subscriber. It is not closed on disconnect,
+// readers stop on Done.
+func (s *OrderEventsSubscriber) Events() <-chan OrderNotification {
+	return s.events
+}
+
+// Done is closed when the subscriber is disconnected.
+func (s *OrderEventsSubscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Reason tells why the subscriber was disconnected, it is empty until Done is closed.
+func (s *OrderEventsSubscriber) Reason() DisconnectReason {
+	select {
+	case <-s.done:
+		return s.reason
+	default:
+		return ""
+	}
+}
+
+// Send queues the notification without blocking. It returns false if the queue
+// is full or the subscriber is disconnected. Notifications sent from multiple
+// goroutines must be serialized by the caller to keep their order.
+func (s *OrderEventsSubscriber) Send(notification OrderNotification) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+
+	select {
+	case s.events <- notification:
+		return true
+	default:
+		return false
+	}
+}
+
+// Disconnect closes Done with the reason, later calls are ignored.
+func (s *OrderEventsSubscriber) Disconnect(reason DisconnectReason) {
+	s.once.Do(func() {
+		s.reason = reason
+		close(s.done)
+	})
+}
@@ -3,18 +3,37 @@ package domain
 import (
 	"fmt"
 	"sort"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/therealyo/justdone/pkg/clock"
 )
 
 type OrderRepository interface {
 	Get(orderID string) (*Order, error)
 	GetMany(filter *OrderFilter) ([]Order, error)
+	// Count returns the number of orders matching the filter, ignoring limit and offset.
+	// Above exactLimit matches the count may be estimated, a non-positive exactLimit always counts exactly.
+	Count(filter *OrderFilter, exactLimit int) (*OrderCount, error)
+	// Save creates the order when its Version is zero and otherwise updates it
+	// only if the stored version still matches. It returns ErrConcurrentModification
+	// when the order was created or changed by someone else, and increments Version
+	// on success.
 	Save(order *Order) error
 }
 
+type OrderStatsRepository interface {
+	Stats(filter *OrderStatsFilter) (*OrderStats, error)
+}
+
+// OrderExportRepository streams orders and events one by one,
+// so large result sets are never held in memory.
+type OrderExportRepository interface {
+	StreamOrders(filter *OrderFilter, fn func(Order) error) error
+	StreamEvents(orderID string, fn func(OrderEvent) error) error
+}
+
 type EventRepository interface {
 	Get(eventID string) (*OrderEvent, error)
 	Create(event OrderEvent) error
@@ -22,33 +41,34 @@ type EventRepository interface {
 	Delete(eventID string) error
 }
 
-type OrderEventsSubscriber struct {
-	EventChan  chan OrderEvent
-	Disconnect chan bool
-	Timeout    time.Duration
-}
-
-func NewOrderEventsSubscriber(timeout time.Duration) OrderEventsSubscriber {
-	return OrderEventsSubscriber{
-		EventChan:  make(chan OrderEvent, 1),
-		Disconnect: make(chan bool),
-		Timeout:    timeout,
-	}
-}
-
 type OrderObserver interface {
-	RegisterClient(orderID string, client OrderEventsSubscriber)
-	UnregisterClient(orderID string, client OrderEventsSubscriber)
+	RegisterClient(orderID string, client *OrderEventsSubscriber)
+	UnregisterClient(orderID string, client *OrderEventsSubscriber)
 	AddProcessedEvent(orderID string, event OrderEvent)
 	Notify(order *Order, event OrderEvent)
+	// NotifyAnomaly reports an event that was rejected for an illegal transition
+	NotifyAnomaly(order *Order, violation SequenceViolation)
+	// NotifyStuck reports an order that stayed in its status longer than its SLA
+	NotifyStuck(stuck OrderStuck)
 }
 
+// ProcessedEvents is the set of events being processed, shared by all instances.
 type ProcessedEvents interface {
-	Add(eventID string)
-	Contains(eventID string) bool
-	Remove(eventID string)
+	// Add claims the event and returns false if it is already being processed
+	Add(eventID string) (bool, error)
+	Remove(eventID string) error
 }
 
+// Locker serializes processing of an order across all instances.
+type Locker interface {
+	// Lock blocks until the order is locked and returns a function releasing the lock
+	Lock(orderID string) (func(), error)
+}
+
+// maxSaveAttempts limits how many times an order is reloaded and saved again
+// after a concurrent modification.
+const maxSaveAttempts = 5
+
 // OrderProcessor handles incoming order events, ensures correct event sequencing,
 // and manages the order lifecycle.
 type OrderProcessor struct {
@@ -56,23 +76,45 @@ type OrderProcessor struct {
 	eventRepo       EventRepository
 	observer        OrderObserver
 	processing      ProcessedEvents
+	locker          Locker
+	deadLetters     DeadLetterRepository
 	finalizeTimeout time.Duration
-
-	mu sync.Mutex
+	clock           clock.Clock
 }
 
 // HandleEvent processes an incoming OrderEvent, handling deduplication,
 // order creation, event sequencing, and client notifications.
+// Rejected events are kept as dead letters. Transient failures, such as a
+// lost database connection, are returned without one, so JustPay! delivers
+// the event again.
 func (op *OrderProcessor) HandleEvent(event OrderEvent) error {
+	err := op.handleEvent(event)
+	if IsRejection(err) {
+		op.deadLetter(event, err.Error())
+	}
+	return err
+}
+
+func (op *OrderProcessor) handleEvent(event OrderEvent) error {
+	// Mark the event as being processed, unless another request already does
+	claimed, err := op.processing.Add(event.EventID)
+	if err != nil {
+		return errors.Wrap(err, "claim event")
+	}
+	if !claimed {
+		return ErrEventInProgress
+	}
+	defer func() {
+		if err := op.processing.Remove(event.EventID); err != nil {
+			fmt.Printf("error releasing event %s: %v\n", event.EventID, err)
+		}
+	}()
+
 	// Check if the event has already been processed
 	if op.isEventAlreadyProcessed(event.EventID) {
 		return ErrEventConflict
 	}
 
-	// Mark the event as being processed
-	op.processing.Add(event.EventID)
-	defer op.processing.Remove(event.EventID)
-
 	// Process the event
 	if err := op.processEvent(event); err != nil {
 		// Handle domain errors
@@ -92,32 +134,15 @@ func (op *OrderProcessor) HandleEvent(event OrderEvent) error {
 // processEvent handles the core logic of event processing, including
 // order updates, event sequencing, and finalization.
 func (op *OrderProcessor) processEvent(event OrderEvent) error {
-	op.mu.Lock()
-	defer op.mu.Unlock()
-
-	// Retrieve the order
-	order, err := op.orderRepo.Get(event.OrderID)
+	unlock, err := op.locker.Lock(event.OrderID)
 	if err != nil {
-		return errors.Wrap(err, "retrieve order")
+		return errors.Wrap(err, "lock order")
 	}
+	defer unlock()
 
-	// Create a new order if it doesn't exist
-	if order == nil {
-		if event.OrderStatus == CoolOrderCreated {
-			order = &Order{
-				OrderID:   event.OrderID,
-				UserID:    event.UserID,
-				Status:    CoolOrderCreated,
-				CreatedAt: event.CreatedAt,
-				UpdatedAt: event.UpdatedAt,
-			}
-
-			if err := op.orderRepo.Save(order); err != nil {
-				return errors.Wrap(err, "save new order")
-			}
-		} else {
-			return ErrOrderNotFound
-		}
+	order, err := op.getOrCreateOrder(event)
+	if err != nil {
+		return err
 	}
 
 	// Check if the order is already final
@@ -130,8 +155,195 @@ func (op *OrderProcessor) processEvent(event OrderEvent) error {
 		return errors.Wrap(err, "save event")
 	}
 
-	// Append and sort events
-	order.Events = append(order.Events, event)
+	return op.applyWithReload(order, event)
+}
+
+// applyWithReload applies the stored event to the order. Another instance may
+// update the order between reading and saving it, in which case the order is
+// reloaded and the event applied again. The order must be locked.
+func (op *OrderProcessor) applyWithReload(order *Order, event OrderEvent) error {
+	for attempt := 1; ; attempt++ {
+		err := op.applyEvent(order, event)
+		if !errors.Is(err, ErrConcurrentModification) || attempt == maxSaveAttempts {
+			return err
+		}
+
+		order, err = op.orderRepo.Get(event.OrderID)
+		if err != nil {
+			return errors.Wrap(err, "reload order")
+		}
+		if order == nil {
+			return ErrOrderNotFound
+		}
+
+		// The order was finalized concurrently, so the event is rejected
+		if order.IsFinal {
+			if err := op.eventRepo.Delete(event.EventID); err != nil {
+				return errors.Wrap(err, "delete event")
+			}
+			return ErrOrderAlreadyFinal
+		}
+	}
+}
+
+// RetryEvent processes a dead letter again. An event that is not stored is
+// handled like a new one. A stored event rejected for an invalid sequence is
+// applied to the reloaded order instead, which succeeds once the missing
+// events arrived. The dead letter is kept when it fails again, and counts
+// another attempt when the event is rejected again.
+func (op *OrderProcessor) RetryEvent(event OrderEvent) error {
+	stored, err := op.eventRepo.Get(event.EventID)
+	if err != nil {
+		return errors.Wrap(err, "retrieve event")
+	}
+	if stored == nil {
+		return op.HandleEvent(event)
+	}
+	if stored.RejectedReason == "" {
+		return ErrEventConflict
+	}
+
+	err = op.retryStoredEvent(*stored)
+	if IsRejection(err) {
+		op.deadLetter(*stored, err.Error())
+	}
+	return err
+}
+
+func (op *OrderProcessor) retryStoredEvent(event OrderEvent) error {
+	claimed, err := op.processing.Add(event.EventID)
+	if err != nil {
+		return errors.Wrap(err, "claim event")
+	}
+	if !claimed {
+		return ErrEventInProgress
+	}
+	defer func() {
+		if err := op.processing.Remove(event.EventID); err != nil {
+			fmt.Printf("error releasing event %s: %v\n", event.EventID, err)
+		}
+	}()
+
+	unlock, err := op.locker.Lock(event.OrderID)
+	if err != nil {
+		return errors.Wrap(err, "lock order")
+	}
+	defer unlock()
+
+	order, err := op.orderRepo.Get(event.OrderID)
+	if err != nil {
+		return errors.Wrap(err, "retrieve order")
+	}
+	if order == nil {
+		return ErrOrderNotFound
+	}
+	if order.IsFinal {
+		return ErrOrderAlreadyFinal
+	}
+
+	return op.applyWithReload(order, event)
+}
+
+// DiscardEvent removes an event that was not applied, so it can be created
+// again, together with its dead letter. Applied events are kept and
+// ErrEventConflict is returned.
+func (op *OrderProcessor) DiscardEvent(eventID string) error {
+	claimed, err := op.processing.Add(eventID)
+	if err != nil {
+		return errors.Wrap(err, "claim event")
+	}
+	if !claimed {
+		return ErrEventInProgress
+	}
+	defer func() {
+		if err := op.processing.Remove(eventID); err != nil {
+			fmt.Printf("error releasing event %s: %v\n", eventID, err)
+		}
+	}()
+
+	stored, err := op.eventRepo.Get(eventID)
+	if err != nil {
+		return errors.Wrap(err, "retrieve event")
+	}
+	if stored != nil {
+		unlock, err := op.locker.Lock(stored.OrderID)
+		if err != nil {
+			return errors.Wrap(err, "lock order")
+		}
+		defer unlock()
+
+		// The event may have been applied since it was read
+		if stored, err = op.eventRepo.Get(eventID); err != nil {
+			return errors.Wrap(err, "retrieve event")
+		}
+	}
+	if stored != nil {
+		if stored.RejectedReason == "" {
+			return ErrEventConflict
+		}
+		if err := op.eventRepo.Delete(eventID); err != nil {
+			return errors.Wrap(err, "delete event")
+		}
+	}
+
+	if err := op.deadLetters.Delete(eventID); err != nil {
+		return errors.Wrap(err, "delete dead letter")
+	}
+	return nil
+}
+
+// getOrCreateOrder retrieves the order of the event, creating it for
+// the initial event. If another instance creates the order at the same time,
+// the stored order is returned.
+func (op *OrderProcessor) getOrCreateOrder(event OrderEvent) (*Order, error) {
+	order, err := op.orderRepo.Get(event.OrderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieve order")
+	}
+
+	if order != nil {
+		return order, nil
+	}
+
+	if event.OrderStatus != CoolOrderCreated {
+		return nil, ErrOrderNotFound
+	}
+
+	order = &Order{
+		OrderID:   event.OrderID,
+		UserID:    event.UserID,
+		Status:    CoolOrderCreated,
+		CreatedAt: event.CreatedAt,
+		UpdatedAt: event.UpdatedAt,
+	}
+	order.applyPaymentDetails(event)
+
+	err = op.orderRepo.Save(order)
+	if errors.Is(err, ErrConcurrentModification) {
+		order, err = op.orderRepo.Get(event.OrderID)
+		if err != nil {
+			return nil, errors.Wrap(err, "reload order")
+		}
+		if order == nil {
+			return nil, ErrOrderNotFound
+		}
+		return order, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "save new order")
+	}
+
+	return order, nil
+}
+
+// applyEvent updates the order with the stored event and saves it.
+// Observers are notified only when the order was saved. If the events do not
+// form a valid sequence, the order is left as is and a SequenceViolation is returned.
+func (op *OrderProcessor) applyEvent(order *Order, event OrderEvent) error {
+	// Append and sort events, a reloaded order already contains the event
+	if !order.hasEvent(event.EventID) {
+		order.Events = append(order.Events, event)
+	}
 	sort.SliceStable(order.Events, func(i, j int) bool {
 		return order.Events[i].CreatedAt.Before(order.Events[j].CreatedAt)
 	})
@@ -142,67 +354,142 @@ func (op *OrderProcessor) processEvent(event OrderEvent) error {
 		order.Status = event.OrderStatus
 		order.LastEvent = &event
 		order.UpdatedAt = event.UpdatedAt
+		order.applyPaymentDetails(event)
 
 		if err := op.orderRepo.Save(order); err != nil {
 			return errors.Wrap(err, "save order")
 		}
+		op.resolveDeadLetters(event.EventID)
 		op.observer.Notify(order, event)
 		return nil
 	}
 
+	if !order.isValidSequence() {
+		return op.rejectEvent(order, event)
+	}
+
+	// Events rejected while the sequence had a gap are applied now
+	var accepted []OrderEvent
+	eventIDs := make([]string, len(order.Events))
+	for i := range order.Events {
+		eventIDs[i] = order.Events[i].EventID
+		order.applyPaymentDetails(order.Events[i])
+		if order.Events[i].RejectedReason != "" {
+			order.Events[i].RejectedReason = ""
+			accepted = append(accepted, order.Events[i])
+		}
+	}
+
 	// Process the last event
 	lastEvent := order.Events[len(order.Events)-1]
-	if order.isValidSequence() {
-		order.Status = lastEvent.OrderStatus
-		order.LastEvent = &lastEvent
-		order.UpdatedAt = lastEvent.UpdatedAt
+	order.Status = lastEvent.OrderStatus
+	order.LastEvent = &lastEvent
+	order.UpdatedAt = lastEvent.UpdatedAt
 
-		// Start finalization timer for Chinazes status
-		if lastEvent.OrderStatus == Chinazes {
-			go op.waitAndFinalize(order, lastEvent)
-		}
+	// Mark order as final for refund status
+	if lastEvent.OrderStatus.isRefund() {
+		order.IsFinal = true
+	}
 
-		// Mark order as final for refund status
-		if lastEvent.OrderStatus.isRefund() {
-			order.IsFinal = true
-		}
+	// Save the updated order
+	if err := op.orderRepo.Save(order); err != nil {
+		return errors.Wrap(err, "save order")
+	}
 
-		// Save the updated order
-		if err := op.orderRepo.Save(order); err != nil {
-			return errors.Wrap(err, "save order")
+	for _, acceptedEvent := range accepted {
+		if err := op.eventRepo.Update(acceptedEvent); err != nil {
+			fmt.Printf("error clearing rejected reason of event %s: %v\n", acceptedEvent.EventID, err)
 		}
+	}
+	op.resolveDeadLetters(eventIDs...)
 
-		// Notify observers
-		op.observer.Notify(order, lastEvent)
+	// Start finalization timer for Chinazes status
+	if lastEvent.OrderStatus == Chinazes {
+		op.scheduleFinalization(order.OrderID, lastEvent)
 	}
+
+	// Notify observers
+	op.observer.Notify(order, lastEvent)
 	return nil
 }
 
-// waitAndFinalize starts a timer to finalize an order after receiving
-// the Chinazes status, marking it as complete if no further events are received.
-func (op *OrderProcessor) waitAndFinalize(order *Order, lastEvent OrderEvent) {
-	time.Sleep(op.finalizeTimeout)
+// rejectEvent records why the stored event was not applied and reports the
+// anomaly to observers. The order is not updated until the sequence is valid.
+func (op *OrderProcessor) rejectEvent(order *Order, event OrderEvent) error {
+	violation := order.sequenceViolation(event)
+
+	event.RejectedReason = violation.Error()
+	if err := op.eventRepo.Update(event); err != nil {
+		return errors.Wrap(err, "reject event")
+	}
+	for i := range order.Events {
+		if order.Events[i].EventID == event.EventID {
+			order.Events[i].RejectedReason = event.RejectedReason
+		}
+	}
+
+	op.observer.NotifyAnomaly(order, *violation)
+	return violation
+}
+
+// deadLetter keeps the event that could not be applied. Failing to store it
+// does not change the outcome of processing.
+func (op *OrderProcessor) deadLetter(event OrderEvent, reason string) {
+	if err := op.deadLetters.Add(event, reason); err != nil {
+		fmt.Printf("error storing dead letter event %s: %v\n", event.EventID, err)
+	}
+}
+
+// resolveDeadLetters removes dead letters of events that were applied.
+func (op *OrderProcessor) resolveDeadLetters(eventIDs ...string) {
+	if err := op.deadLetters.Delete(eventIDs...); err != nil {
+		fmt.Printf("error resolving dead letter events: %v\n", err)
+	}
+}
 
-	op.mu.Lock()
-	defer op.mu.Unlock()
+// scheduleFinalization starts a timer to finalize an order after receiving
+// the Chinazes status.
+func (op *OrderProcessor) scheduleFinalization(orderID string, lastEvent OrderEvent) {
+	op.clock.AfterFunc(op.finalizeTimeout, func() {
+		op.finalize(orderID, lastEvent)
+	})
+}
 
-	// Retrieve the latest order state
-	finalOrder, err := op.orderRepo.Get(order.OrderID)
-	if err != nil || finalOrder == nil {
-		fmt.Println("order not found")
+// finalize marks the order as complete if no further events were received
+// since the Chinazes status.
+func (op *OrderProcessor) finalize(orderID string, lastEvent OrderEvent) {
+	unlock, err := op.locker.Lock(orderID)
+	if err != nil {
+		fmt.Println("error locking order")
 		return
 	}
+	defer unlock()
+
+	for attempt := 1; attempt <= maxSaveAttempts; attempt++ {
+		// Retrieve the latest order state
+		finalOrder, err := op.orderRepo.Get(orderID)
+		if err != nil || finalOrder == nil {
+			fmt.Println("order not found")
+			return
+		}
+
+		// Finalize the order only if still in Chinazes status
+		if finalOrder.Status != Chinazes || finalOrder.IsFinal {
+			return
+		}
 
-	// Finalize the order if still in Chinazes status
-	if finalOrder.Status == Chinazes && !finalOrder.IsFinal {
 		finalOrder.IsFinal = true
-		if err := op.orderRepo.Save(finalOrder); err != nil {
+		err = op.orderRepo.Save(finalOrder)
+		if errors.Is(err, ErrConcurrentModification) {
+			continue
+		}
+		if err != nil {
 			fmt.Println("error saving order")
 			return
 		}
 
 		// Update the event to finalized state
-		updatedEvent := lastEvent.Finalize()
+		updatedEvent := lastEvent.Finalize(op.clock.Now())
 		if err := op.eventRepo.Update(*updatedEvent); err != nil {
 			fmt.Println("error updating event")
 			return
@@ -210,16 +497,15 @@ func (op *OrderProcessor) waitAndFinalize(order *Order, lastEvent OrderEvent) {
 
 		// Notify observers of the finalized order
 		op.observer.Notify(finalOrder, *updatedEvent)
+		return
 	}
+
+	fmt.Println("error finalizing order: too many concurrent modifications")
 }
 
-// isEventAlreadyProcessed checks if an event has already been processed
-// or is currently being processed to avoid duplicates.
+// isEventAlreadyProcessed checks if an event has already been stored
+// to avoid duplicates.
 func (op *OrderProcessor) isEventAlreadyProcessed(eventID string) bool {
-	if op.processing.Contains(eventID) {
-		return true
-	}
-
 	existingEvent, err := op.eventRepo.Get(eventID)
 	if err == nil && existingEvent != nil {
 		return true
@@ -233,13 +519,19 @@ func NewOrderProcessor(
 	eventRepo EventRepository,
 	observer OrderObserver,
 	processing ProcessedEvents,
+	locker Locker,
+	deadLetters DeadLetterRepository,
 	finalizeTimeout time.Duration,
+	clock clock.Clock,
 ) *OrderProcessor {
 	return &OrderProcessor{
 		orderRepo:       orderRepo,
 		eventRepo:       eventRepo,
 		observer:        observer,
 		processing:      processing,
+		locker:          locker,
+		deadLetters:     deadLetters,
 		finalizeTimeout: finalizeTimeout,
+		clock:           clock,
 	}
 }
@@ -0,0 +1,327 @@
+package domain_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+// processorSeed replays a single seed of TestProcessorInvariants, e.g.
+//
+//	go test ./domain -run TestProcessorInvariants -processor.seed=1718000000000
+//
+// The seed fixes the histories, the delivery order, duplicates and delays.
+// Goroutine scheduling is not reproducible, so a race may need a few runs.
+var processorSeed = flag.Int64("processor.seed", 0, "run TestProcessorInvariants with this seed only")
+
+const (
+	propertySeeds          = 10
+	propertyOrders         = 20
+	propertyMaxDelay       = 10 * time.Millisecond
+	propertyFinalizeAfter  = 5 * time.Second
+	propertyMaxDeliveries  = 500
+	propertyDuplicateRatio = 0.3
+)
+
+// notification is a snapshot of an order taken when observers were notified.
+type notification struct {
+	status     domain.OrderStatus
+	isFinal    bool
+	eventID    string
+	eventFinal bool
+}
+
+// recordingObserver keeps every notification per order.
+type recordingObserver struct {
+	mu            sync.Mutex
+	notifications map[string][]notification
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{notifications: make(map[string][]notification)}
+}
+
+func (r *recordingObserver) RegisterClient(orderID string, client *domain.OrderEventsSubscriber) {}
+
+func (r *recordingObserver) UnregisterClient(orderID string, client *domain.OrderEventsSubscriber) {}
+
+func (r *recordingObserver) AddProcessedEvent(orderID string, event domain.OrderEvent) {}
+
+func (r *recordingObserver) Notify(order *domain.Order, event domain.OrderEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notifications[order.OrderID] = append(r.notifications[order.OrderID], notification{
+		status:     order.Status,
+		isFinal:    order.IsFinal,
+		eventID:    event.EventID,
+		eventFinal: event.IsFinal,
+	})
+}
+
+func (r *recordingObserver) NotifyAnomaly(order *domain.Order, violation domain.SequenceViolation) {}
+
+func (r *recordingObserver) NotifyStuck(stuck domain.OrderStuck) {}
+
+func (r *recordingObserver) get(orderID string) []notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]notification(nil), r.notifications[orderID]...)
+}
+
+var _ domain.OrderObserver = new(recordingObserver)
+
+// history is a legal event history of an order and the way it is delivered.
+type history struct {
+	orderID    string
+	events     []domain.OrderEvent
+	deliveries []propertyDelivery
+	// late is set when the last event is delivered after the order was finalized
+	late bool
+}
+
+// propertyDelivery is a webhook call of an event. Retried deliveries are
+// repeated like JustPay! does until the event is accepted, others are sent once.
+type propertyDelivery struct {
+	event   domain.OrderEvent
+	delay   time.Duration
+	retried bool
+	// late deliveries are sent after the finalization timeout
+	late bool
+}
+
+// final is the status the order ends in after all events are delivered.
+// A refund delivered after finalization is rejected.
+func (h history) final() domain.OrderStatus {
+	if h.late {
+		return domain.Chinazes
+	}
+	return h.events[len(h.events)-1].OrderStatus
+}
+
+func (h history) String() string {
+	statuses := make([]string, len(h.events))
+	for i, event := range h.events {
+		statuses[i] = string(event.OrderStatus)
+	}
+	delivered := make([]string, len(h.deliveries))
+	for i, d := range h.deliveries {
+		delivered[i] = fmt.Sprintf("%s+%s", d.event.OrderStatus, d.delay)
+		if d.late {
+			delivered[i] = fmt.Sprintf("%s+finalization", d.event.OrderStatus)
+		}
+	}
+	return fmt.Sprintf("%s history [%s] delivered [%s]", h.orderID, strings.Join(statuses, " "), strings.Join(delivered, " "))
+}
+
+// newHistory returns the happy path to chinazes, optionally refunded before
+// or after finalization, or a cancellation before chinazes, delivered in
+// random order with duplicates and delays.
+func newHistory(random *rand.Rand, n int) history {
+	statuses := []domain.OrderStatus{
+		domain.CoolOrderCreated, domain.SbuVerificationPending, domain.ConfirmedByMayor, domain.Chinazes,
+	}
+	h := history{orderID: fmt.Sprintf("order%d", n)}
+	switch random.Intn(4) {
+	case 1:
+		statuses = append(statuses, domain.GiveMyMoneyBack)
+	case 2:
+		statuses = append(statuses, domain.GiveMyMoneyBack)
+		h.late = true
+	case 3:
+		cancel := domain.ChangedMyMind
+		if random.Intn(2) == 0 {
+			cancel = domain.Failed
+		}
+		statuses = append(statuses[:1+random.Intn(3)], cancel)
+	}
+
+	createdAt := time.Now().Add(-time.Hour)
+	for i, status := range statuses {
+		at := createdAt.Add(time.Duration(i) * time.Minute)
+		h.events = append(h.events, domain.OrderEvent{
+			EventID:     fmt.Sprintf("order%d-event%d", n, i),
+			OrderID:     h.orderID,
+			UserID:      fmt.Sprintf("user%d", n),
+			OrderStatus: status,
+			CreatedAt:   at,
+			UpdatedAt:   at,
+		})
+	}
+
+	delivered := len(h.events)
+	if h.late {
+		delivered--
+	}
+	for _, i := range random.Perm(delivered) {
+		delay := time.Duration(random.Int63n(int64(propertyMaxDelay)))
+		h.deliveries = append(h.deliveries, propertyDelivery{event: h.events[i], delay: delay, retried: true})
+		if random.Float64() < propertyDuplicateRatio {
+			delay := time.Duration(random.Int63n(int64(propertyMaxDelay)))
+			h.deliveries = append(h.deliveries, propertyDelivery{event: h.events[i], delay: delay})
+		}
+	}
+	if h.late {
+		refund := h.events[len(h.events)-1]
+		h.deliveries = append(h.deliveries, propertyDelivery{event: refund, retried: true, late: true})
+	}
+
+	return h
+}
+
+func TestProcessorInvariants(t *testing.T) {
+	seeds := make([]int64, 0, propertySeeds)
+	if *processorSeed != 0 {
+		seeds = append(seeds, *processorSeed)
+	} else {
+		start := time.Now().UnixNano()
+		for i := int64(0); i < propertySeeds; i++ {
+			seeds = append(seeds, start+i)
+		}
+	}
+
+	for _, seed := range seeds {
+		seed := seed
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			t.Parallel()
+			checkProcessorInvariants(t, seed)
+		})
+	}
+}
+
+// checkProcessorInvariants delivers random histories of several orders
+// concurrently and checks that
+//   - an order never leaves a final state,
+//   - every order ends in the final status of its history,
+//   - no event is notified twice,
+//   - an order reaching chinazes is finalized exactly once.
+func checkProcessorInvariants(t *testing.T, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+	histories := make([]history, propertyOrders)
+	for i := range histories {
+		histories[i] = newHistory(random, i)
+	}
+
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
+	observer := newRecordingObserver()
+	fakeClock := clocktest.NewFakeClock(time.Now())
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, observer, inmemory.NewProcessedEvents(), inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), propertyFinalizeAfter, fakeClock)
+
+	deliver := func(late bool) {
+		var wg sync.WaitGroup
+		for _, h := range histories {
+			for _, d := range h.deliveries {
+				if d.late != late {
+					continue
+				}
+				wg.Add(1)
+				go func(h history, d propertyDelivery) {
+					defer wg.Done()
+					time.Sleep(d.delay)
+					if err := deliverEvent(processor, storageEvents, d); err != nil {
+						t.Errorf("Seed %d: %v\n%s", seed, err, h)
+					}
+				}(h, d)
+			}
+		}
+		wg.Wait()
+	}
+
+	// Orders reaching chinazes are finalized before the late events arrive,
+	// and the clock is advanced again so a second finalization would run too
+	deliver(false)
+	fakeClock.Advance(propertyFinalizeAfter)
+	deliver(true)
+	fakeClock.Advance(propertyFinalizeAfter)
+
+	for _, h := range histories {
+		order, err := storageOrders.Get(h.orderID)
+		if err != nil || order == nil {
+			t.Errorf("Seed %d: failed to retrieve order %s: %v\n%s", seed, h.orderID, err, h)
+			continue
+		}
+		if order.Status != h.final() || !order.IsFinal {
+			t.Errorf("Seed %d: expected order %s to end final in %s, got %s (final %t)\n%s",
+				seed, h.orderID, h.final(), order.Status, order.IsFinal, h)
+		}
+
+		checkNotifications(t, seed, h, observer.get(h.orderID))
+	}
+}
+
+// deliverEvent hands the event to the processor and, for retried deliveries,
+// repeats it while JustPay! would get a retryable response.
+func deliverEvent(processor *domain.OrderProcessor, events domain.EventRepository, d propertyDelivery) error {
+	for attempt := 0; attempt < propertyMaxDeliveries; attempt++ {
+		err := processor.HandleEvent(d.event)
+
+		var violation *domain.SequenceViolation
+		switch {
+		case err == nil, errors.As(err, &violation), errors.Is(err, domain.ErrOrderAlreadyFinal):
+			return nil
+		case errors.Is(err, domain.ErrEventConflict):
+			// Accepted by an earlier delivery
+			if stored, _ := events.Get(d.event.EventID); stored != nil {
+				return nil
+			}
+		case errors.Is(err, domain.ErrEventInProgress), errors.Is(err, domain.ErrOrderNotFound),
+			errors.Is(err, domain.ErrConcurrentModification):
+		default:
+			return fmt.Errorf("unexpected error delivering %s: %v", d.event.OrderStatus, err)
+		}
+
+		if !d.retried {
+			return nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return fmt.Errorf("event %s not accepted after %d deliveries", d.event.OrderStatus, propertyMaxDeliveries)
+}
+
+func checkNotifications(t *testing.T, seed int64, h history, notifications []notification) {
+	t.Helper()
+
+	notified := make(map[string]bool)
+	finalizations := 0
+	var final *notification
+	for i, n := range notifications {
+		if final != nil && (n.status != final.status || !n.isFinal) {
+			t.Errorf("Seed %d: order %s left final state %s for %s (final %t)\n%s",
+				seed, h.orderID, final.status, n.status, n.isFinal, h)
+		}
+		if n.isFinal && final == nil {
+			final = &notifications[i]
+		}
+
+		key := fmt.Sprintf("%s/%t", n.eventID, n.eventFinal)
+		if notified[key] {
+			t.Errorf("Seed %d: order %s notified event %s twice\n%s", seed, h.orderID, n.eventID, h)
+		}
+		notified[key] = true
+
+		if n.eventFinal {
+			finalizations++
+		}
+	}
+
+	wantFinalizations := 0
+	if h.final() == domain.Chinazes {
+		wantFinalizations = 1
+	}
+	if finalizations != wantFinalizations {
+		t.Errorf("Seed %d: expected order %s to be finalized %d times, got %d\n%s",
+			seed, h.orderID, wantFinalizations, finalizations, h)
+	}
+}
@@ -1,6 +1,7 @@
 package domain_test
 
 import (
+	"encoding/json"
 	"errors"
 	"sync"
 	"testing"
@@ -9,98 +10,17 @@ import (
 	"github.com/therealyo/justdone/domain"
 	"github.com/therealyo/justdone/internal/console"
 	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/pkg/clock"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
 )
 
-type InMemoryStorageOrders struct {
-	orders map[string]*domain.Order
-	mu     sync.Mutex
-}
-
-type InMemoryStorageEvents struct {
-	events map[string]domain.OrderEvent
-	mu     sync.Mutex
-}
-
-func NewInMemoryOrders() *InMemoryStorageOrders {
-	return &InMemoryStorageOrders{
-		orders: make(map[string]*domain.Order),
-	}
-}
-
-func NewInMemoryEvents() *InMemoryStorageEvents {
-	return &InMemoryStorageEvents{
-		events: make(map[string]domain.OrderEvent),
-	}
-}
-
-func (s *InMemoryStorageOrders) Get(orderID string) (*domain.Order, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if order, exists := s.orders[orderID]; exists {
-		return order, nil
-	}
-	return nil, nil
-}
-
-func (s *InMemoryStorageOrders) GetMany(filter *domain.OrderFilter) ([]domain.Order, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return nil, nil
-}
-
-func (s *InMemoryStorageOrders) Save(order *domain.Order) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.orders[order.OrderID] = order
-	return nil
-}
-
-func (s *InMemoryStorageEvents) Create(event domain.OrderEvent) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, exists := s.events[event.EventID]; exists {
-		return domain.ErrEventConflict
-	}
-	s.events[event.EventID] = event
-	return nil
-}
-
-func (s *InMemoryStorageEvents) Update(event domain.OrderEvent) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if _, exists := s.events[event.EventID]; !exists {
-		return errors.New("event not found")
-	}
-	s.events[event.EventID] = event
-	return nil
-}
-func (s *InMemoryStorageEvents) Get(eventID string) (*domain.OrderEvent, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if event, exists := s.events[eventID]; exists {
-		return &event, nil
-	}
-	return nil, nil
-}
-
-func (s *InMemoryStorageEvents) Delete(eventID string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.events, eventID)
-	return nil
-}
-
-func sendEvent(event domain.OrderEvent) domain.OrderEvent {
-	time.Sleep(500 * time.Millisecond)
-	return event
-}
-
 func TestEnforcesCorrectSequence(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	event1 := domain.OrderEvent{
 		EventID:     "event1",
@@ -130,7 +50,7 @@ func TestEnforcesCorrectSequence(t *testing.T) {
 	}
 
 	// Test processing the first event
-	if err := processor.HandleEvent(sendEvent(event1)); err != nil {
+	if err := processor.HandleEvent(event1); err != nil {
 		t.Fatalf("Failed to process event1: %v", err)
 	}
 
@@ -145,8 +65,21 @@ func TestEnforcesCorrectSequence(t *testing.T) {
 	}
 
 	// Test processing the second event (ConfirmedByMayor) out of sequence
-	if err := processor.HandleEvent(sendEvent(event2)); err != nil {
-		t.Fatalf("Failed to process event2: %v", err)
+	var violation *domain.SequenceViolation
+	if err := processor.HandleEvent(event2); !errors.As(err, &violation) {
+		t.Fatalf("Expected SequenceViolation for event2, got %v", err)
+	}
+	if violation.From != domain.CoolOrderCreated || violation.To != domain.ConfirmedByMayor {
+		t.Errorf("Expected illegal transition from CoolOrderCreated to ConfirmedByMayor, got %v", violation)
+	}
+
+	// The event is stored with the reason it was rejected
+	rejected, err := storageEvents.Get(event2.EventID)
+	if err != nil || rejected == nil {
+		t.Fatalf("Failed to retrieve rejected event2: %v", err)
+	}
+	if rejected.RejectedReason != violation.Error() {
+		t.Errorf("Expected rejected reason %q, got %q", violation.Error(), rejected.RejectedReason)
 	}
 
 	// Verify that the order status was not updated
@@ -161,7 +94,7 @@ func TestEnforcesCorrectSequence(t *testing.T) {
 	}
 
 	// Now process the correct event (SbuVerificationPending)
-	if err := processor.HandleEvent(sendEvent(event3)); err != nil {
+	if err := processor.HandleEvent(event3); err != nil {
 		t.Fatalf("Failed to process event3 (SbuVerificationPending): %v", err)
 	}
 
@@ -175,19 +108,25 @@ func TestEnforcesCorrectSequence(t *testing.T) {
 		t.Errorf("Expected order status to be ConfirmedByMayor, got %v", order.Status)
 	}
 
+	// The rejected event is applied now
+	if accepted, _ := storageEvents.Get(event2.EventID); accepted.RejectedReason != "" {
+		t.Errorf("Expected rejected reason to be cleared, got %q", accepted.RejectedReason)
+	}
+
 	// Error when processing ConfirmedByMayor once more
-	if err := processor.HandleEvent(sendEvent(event2)); err == nil {
+	if err := processor.HandleEvent(event2); err == nil {
 		t.Fatalf("Expected error when processing ConfirmedByMayor once more, but got none")
 	}
 
 }
 
 func TestInvalidInitialEvent(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	event := domain.OrderEvent{
 		EventID:     "event1",
@@ -198,7 +137,7 @@ func TestInvalidInitialEvent(t *testing.T) {
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := processor.HandleEvent(sendEvent(event)); err == nil {
+	if err := processor.HandleEvent(event); err == nil {
 		if err != domain.ErrOrderNotFound {
 			t.Fatalf("Expected error when processing an out-of-sequence initial event to be ErrOrderNotFound, but got %v", err)
 		}
@@ -217,11 +156,12 @@ func TestInvalidInitialEvent(t *testing.T) {
 }
 
 func TestConcurrentEventProcessing(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	initialEvent := domain.OrderEvent{
 		EventID:     "initialEvent",
@@ -303,11 +243,12 @@ func TestConcurrentEventProcessing(t *testing.T) {
 }
 
 func TestCancelingEventInBetween(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	event1 := domain.OrderEvent{
 		EventID:     "event1",
@@ -337,15 +278,15 @@ func TestCancelingEventInBetween(t *testing.T) {
 	}
 
 	// Process events
-	if err := processor.HandleEvent(sendEvent(event1)); err != nil {
+	if err := processor.HandleEvent(event1); err != nil {
 		t.Fatalf("Failed to process event1: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event2)); err != nil {
+	if err := processor.HandleEvent(event2); err != nil {
 		t.Fatalf("Failed to process event2: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event3)); err != nil {
+	if err := processor.HandleEvent(event3); err != nil {
 		t.Fatalf("Failed to process event3: %v", err)
 	}
 
@@ -364,12 +305,69 @@ func TestCancelingEventInBetween(t *testing.T) {
 	}
 }
 
+func TestCarriesPaymentDetails(t *testing.T) {
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	processor := domain.NewOrderProcessor(storageOrders, inmemory.NewEventRepository(storage), console.NewConsoleNotifier(), inmemory.NewProcessedEvents(), inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
+
+	now := time.Now()
+	event := func(id string, status domain.OrderStatus, step int, amount int64, metadata string) domain.OrderEvent {
+		event := domain.OrderEvent{
+			EventID:     id,
+			OrderID:     "order1",
+			UserID:      "user1",
+			OrderStatus: status,
+			CreatedAt:   now.Add(time.Duration(step) * time.Minute),
+			UpdatedAt:   now.Add(time.Duration(step) * time.Minute),
+		}
+		if amount > 0 {
+			event.Amount = &amount
+			event.Currency = "EUR"
+		}
+		if metadata != "" {
+			event.Metadata = json.RawMessage(metadata)
+		}
+		return event
+	}
+
+	// The mayor event arrives before the verification event, the amount of
+	// the latest event wins once the sequence is complete
+	events := []domain.OrderEvent{
+		event("event1", domain.CoolOrderCreated, 0, 1000, `{"invoice":"A-1"}`),
+		event("event3", domain.ConfirmedByMayor, 2, 1200, ""),
+		event("event2", domain.SbuVerificationPending, 1, 1100, ""),
+	}
+	for _, e := range events {
+		err := processor.HandleEvent(e)
+		var violation *domain.SequenceViolation
+		if err != nil && !errors.As(err, &violation) {
+			t.Fatalf("Failed to process %s: %v", e.EventID, err)
+		}
+	}
+
+	order, err := storageOrders.Get("order1")
+	if err != nil || order == nil {
+		t.Fatalf("Failed to retrieve order: %v", err)
+	}
+
+	if order.Status != domain.ConfirmedByMayor {
+		t.Errorf("Expected order status to be ConfirmedByMayor, got %v", order.Status)
+	}
+	if order.Amount == nil || *order.Amount != 1200 || order.Currency != "EUR" {
+		t.Errorf("Expected amount 1200 EUR, got %v %s", order.Amount, order.Currency)
+	}
+	if string(order.Metadata) != `{"invoice":"A-1"}` {
+		t.Errorf("Expected metadata of the first event, got %s", order.Metadata)
+	}
+}
+
 func TestGiveMyMoneyBack(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	event1 := domain.OrderEvent{
 		EventID:     "event1",
@@ -417,23 +415,23 @@ func TestGiveMyMoneyBack(t *testing.T) {
 	}
 
 	// Process events
-	if err := processor.HandleEvent(sendEvent(event1)); err != nil {
+	if err := processor.HandleEvent(event1); err != nil {
 		t.Fatalf("Failed to process event1: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event2)); err != nil {
+	if err := processor.HandleEvent(event2); err != nil {
 		t.Fatalf("Failed to process event2: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event3)); err != nil {
+	if err := processor.HandleEvent(event3); err != nil {
 		t.Fatalf("Failed to process event3: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event4)); err != nil {
+	if err := processor.HandleEvent(event4); err != nil {
 		t.Fatalf("Failed to process event4: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event5)); err != nil {
+	if err := processor.HandleEvent(event5); err != nil {
 		t.Fatalf("Failed to process event5: %v", err)
 	}
 
@@ -453,11 +451,13 @@ func TestGiveMyMoneyBack(t *testing.T) {
 }
 
 func TestChinazesFinalization(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	fakeClock := clocktest.NewFakeClock(time.Now())
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, fakeClock)
 
 	event1 := domain.OrderEvent{
 		EventID:     "event1",
@@ -496,24 +496,29 @@ func TestChinazesFinalization(t *testing.T) {
 	}
 
 	// Process events
-	if err := processor.HandleEvent(sendEvent(event1)); err != nil {
+	if err := processor.HandleEvent(event1); err != nil {
 		t.Fatalf("Failed to process event1: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event2)); err != nil {
+	if err := processor.HandleEvent(event2); err != nil {
 		t.Fatalf("Failed to process event2: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event3)); err != nil {
+	if err := processor.HandleEvent(event3); err != nil {
 		t.Fatalf("Failed to process event3: %v", err)
 	}
 
-	if err := processor.HandleEvent(sendEvent(event4)); err != nil {
+	if err := processor.HandleEvent(event4); err != nil {
 		t.Fatalf("Failed to process event4: %v", err)
 	}
 
-	// Wait duration of 10 seconds and check if the order is finalized
-	time.Sleep(10 * time.Second)
+	// The order is not final until the timeout elapses
+	fakeClock.Advance(5*time.Second - time.Nanosecond)
+	if order, _ := storageOrders.Get(event4.OrderID); order == nil || order.IsFinal {
+		t.Fatalf("Expected order not to be final before the timeout, got %+v", order)
+	}
+
+	fakeClock.Advance(time.Nanosecond)
 
 	order, err := storageOrders.Get(event4.OrderID)
 	if err != nil || order == nil {
@@ -521,20 +526,30 @@ func TestChinazesFinalization(t *testing.T) {
 	}
 
 	if !order.IsFinal {
-		t.Errorf("Expected order to be marked as final after 30 seconds, but it wasn't")
+		t.Errorf("Expected order to be marked as final after 5 seconds, but it wasn't")
 	}
 
 	if order.Status != domain.Chinazes {
 		t.Errorf("Expected order status to remain Chinazes, got %v", order.Status)
 	}
+
+	// The Chinazes event is finalized at the time of the clock
+	finalized, err := storageEvents.Get(event4.EventID)
+	if err != nil || finalized == nil {
+		t.Fatalf("Failed to retrieve event4: %v", err)
+	}
+	if !finalized.IsFinal || !finalized.UpdatedAt.Equal(fakeClock.Now()) {
+		t.Errorf("Expected event4 to be finalized at %v, got %+v", fakeClock.Now(), *finalized)
+	}
 }
 
 func TestConcurrentProcessing(t *testing.T) {
-	storageOrders := NewInMemoryOrders()
-	storageEvents := NewInMemoryEvents()
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
 	notifier := console.NewConsoleNotifier()
 	processedEvents := inmemory.NewProcessedEvents()
-	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, 5*time.Second)
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
 
 	event := domain.OrderEvent{
 		EventID:     "event1",
@@ -583,3 +598,244 @@ func TestConcurrentProcessing(t *testing.T) {
 		t.Errorf("Expected 1 event to be processed, but got %d", len(order.Events))
 	}
 }
+
+// racingOrderRepository simulates another instance saving the order right
+// before the processor updates it, for the given number of saves.
+type racingOrderRepository struct {
+	*inmemory.OrderRepository
+	races int
+}
+
+func (r *racingOrderRepository) Save(order *domain.Order) error {
+	if r.races > 0 && order.Version > 0 {
+		r.races--
+		stored, err := r.OrderRepository.Get(order.OrderID)
+		if err != nil {
+			return err
+		}
+		if err := r.OrderRepository.Save(stored); err != nil {
+			return err
+		}
+	}
+	return r.OrderRepository.Save(order)
+}
+
+func TestRetriesConcurrentModification(t *testing.T) {
+	storage := inmemory.NewStorage()
+	storageOrders := &racingOrderRepository{OrderRepository: inmemory.NewOrderRepository(storage)}
+	storageEvents := inmemory.NewEventRepository(storage)
+	notifier := console.NewConsoleNotifier()
+	processedEvents := inmemory.NewProcessedEvents()
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, notifier, processedEvents, inmemory.NewLocker(), inmemory.NewDeadLetterRepository(), 5*time.Second, clock.New())
+
+	now := time.Now()
+	newEvent := func(eventID string, status domain.OrderStatus, offset time.Duration) domain.OrderEvent {
+		return domain.OrderEvent{
+			EventID:     eventID,
+			OrderID:     "order1",
+			UserID:      "user1",
+			OrderStatus: status,
+			CreatedAt:   now.Add(offset),
+			UpdatedAt:   now.Add(offset),
+		}
+	}
+
+	if err := processor.HandleEvent(newEvent("event1", domain.CoolOrderCreated, 0)); err != nil {
+		t.Fatalf("Failed to process event1: %v", err)
+	}
+
+	// Two conflicting saves are retried with the reloaded order
+	storageOrders.races = 2
+	if err := processor.HandleEvent(newEvent("event2", domain.SbuVerificationPending, time.Minute)); err != nil {
+		t.Fatalf("Failed to process event2: %v", err)
+	}
+
+	order, err := storageOrders.Get("order1")
+	if err != nil || order == nil {
+		t.Fatalf("Failed to retrieve order: %v", err)
+	}
+	if order.Status != domain.SbuVerificationPending {
+		t.Errorf("Expected order status to be SbuVerificationPending, got %v", order.Status)
+	}
+	if order.Version != 5 {
+		t.Errorf("Expected version 5 after two concurrent saves, got %d", order.Version)
+	}
+
+	// The processor gives up when the order keeps changing and removes the event
+	storageOrders.races = 100
+	err = processor.HandleEvent(newEvent("event3", domain.ConfirmedByMayor, 2*time.Minute))
+	if !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("Expected %v, got %v", domain.ErrConcurrentModification, err)
+	}
+
+	if event, _ := storageEvents.Get("event3"); event != nil {
+		t.Errorf("Expected event3 to be deleted after failed processing, got %+v", event)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
+	deadLetters := inmemory.NewDeadLetterRepository()
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, console.NewConsoleNotifier(), inmemory.NewProcessedEvents(), inmemory.NewLocker(), deadLetters, 5*time.Second, clock.New())
+
+	now := time.Now()
+	newEvent := func(id string, status domain.OrderStatus, step int) domain.OrderEvent {
+		createdAt := now.Add(time.Duration(step) * time.Minute)
+		return domain.OrderEvent{
+			EventID:     id,
+			OrderID:     "order1",
+			UserID:      "user1",
+			OrderStatus: status,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		}
+	}
+	created := newEvent("event1", domain.CoolOrderCreated, 0)
+	pending := newEvent("event2", domain.SbuVerificationPending, 1)
+	confirmed := newEvent("event3", domain.ConfirmedByMayor, 2)
+
+	assertDeadLetter := func(eventID, reason string, attempts int) {
+		t.Helper()
+		deadLetter, err := deadLetters.Get(eventID)
+		if err != nil || deadLetter == nil {
+			t.Fatalf("Expected dead letter for %s, got %v, %v", eventID, deadLetter, err)
+		}
+		if deadLetter.Reason != reason || deadLetter.Attempts != attempts {
+			t.Errorf("Expected reason %q after %d attempts, got %q after %d", reason, attempts, deadLetter.Reason, deadLetter.Attempts)
+		}
+	}
+
+	// Rejected events are kept and count every attempt
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := processor.HandleEvent(confirmed); err != domain.ErrOrderNotFound {
+			t.Fatalf("Expected ErrOrderNotFound, got %v", err)
+		}
+	}
+	assertDeadLetter(confirmed.EventID, domain.ErrOrderNotFound.Error(), 2)
+
+	// Duplicates are not dead letters
+	if err := processor.HandleEvent(created); err != nil {
+		t.Fatalf("Failed to process event1: %v", err)
+	}
+	if err := processor.HandleEvent(created); err != domain.ErrEventConflict {
+		t.Fatalf("Expected ErrEventConflict, got %v", err)
+	}
+	if deadLetter, _ := deadLetters.Get(created.EventID); deadLetter != nil {
+		t.Errorf("Expected no dead letter for duplicate event, got %+v", deadLetter)
+	}
+
+	// The retried event is stored but rejected while the sequence has a gap
+	var violation *domain.SequenceViolation
+	if err := processor.HandleEvent(confirmed); !errors.As(err, &violation) {
+		t.Fatalf("Expected SequenceViolation for event3, got %v", err)
+	}
+	assertDeadLetter(confirmed.EventID, "illegal transition from cool_order_created to confirmed_by_mayor", 3)
+
+	// Retrying the stored event applies it again instead of rejecting a duplicate
+	if err := processor.RetryEvent(confirmed); !errors.As(err, &violation) {
+		t.Fatalf("Expected SequenceViolation for retried event3, got %v", err)
+	}
+	assertDeadLetter(confirmed.EventID, "illegal transition from cool_order_created to confirmed_by_mayor", 4)
+
+	// Filling the gap applies the rejected event and resolves its dead letter
+	if err := processor.HandleEvent(pending); err != nil {
+		t.Fatalf("Failed to process event2: %v", err)
+	}
+	if deadLetter, _ := deadLetters.Get(confirmed.EventID); deadLetter != nil {
+		t.Errorf("Expected dead letter to be resolved, got %+v", deadLetter)
+	}
+
+	order, err := storageOrders.Get("order1")
+	if err != nil || order == nil {
+		t.Fatalf("Failed to retrieve order: %v", err)
+	}
+	if order.Status != domain.ConfirmedByMayor {
+		t.Errorf("Expected order status to be ConfirmedByMayor, got %v", order.Status)
+	}
+	// Applied events are duplicates
+	if err := processor.RetryEvent(confirmed); err != domain.ErrEventConflict {
+		t.Errorf("Expected ErrEventConflict for applied event3, got %v", err)
+	}
+}
+
+func TestDiscardEvent(t *testing.T) {
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
+	deadLetters := inmemory.NewDeadLetterRepository()
+	processor := domain.NewOrderProcessor(storageOrders, storageEvents, console.NewConsoleNotifier(), inmemory.NewProcessedEvents(), inmemory.NewLocker(), deadLetters, 5*time.Second, clock.New())
+
+	now := time.Now()
+	created := domain.OrderEvent{EventID: "event1", OrderID: "order1", UserID: "user1", OrderStatus: domain.CoolOrderCreated, CreatedAt: now, UpdatedAt: now}
+	confirmed := domain.OrderEvent{EventID: "event3", OrderID: "order1", UserID: "user1", OrderStatus: domain.ConfirmedByMayor, CreatedAt: now.Add(time.Minute), UpdatedAt: now.Add(time.Minute)}
+
+	if err := processor.HandleEvent(created); err != nil {
+		t.Fatalf("Failed to process event1: %v", err)
+	}
+	var violation *domain.SequenceViolation
+	if err := processor.HandleEvent(confirmed); !errors.As(err, &violation) {
+		t.Fatalf("Expected SequenceViolation for event3, got %v", err)
+	}
+
+	// Applied events are kept
+	if err := processor.DiscardEvent(created.EventID); err != domain.ErrEventConflict {
+		t.Errorf("Expected ErrEventConflict for applied event1, got %v", err)
+	}
+
+	// Rejected events are removed with their dead letter and can be created again
+	if err := processor.DiscardEvent(confirmed.EventID); err != nil {
+		t.Fatalf("Failed to discard event3: %v", err)
+	}
+	if event, _ := storageEvents.Get(confirmed.EventID); event != nil {
+		t.Errorf("Expected event3 to be deleted, got %+v", event)
+	}
+	if deadLetter, _ := deadLetters.Get(confirmed.EventID); deadLetter != nil {
+		t.Errorf("Expected dead letter of event3 to be deleted, got %+v", deadLetter)
+	}
+	if order, _ := storageOrders.Get("order1"); order == nil || len(order.Events) != 1 {
+		t.Errorf("Expected only event1 on the order, got %+v", order)
+	}
+	if err := processor.HandleEvent(confirmed); !errors.As(err, &violation) {
+		t.Errorf("Expected event3 to be processed again, got %v", err)
+	}
+}
+
+// unavailableEventRepository fails to store events, like a database that lost its connection.
+type unavailableEventRepository struct {
+	domain.EventRepository
+}
+
+func (unavailableEventRepository) Create(domain.OrderEvent) error {
+	return errors.New("connection refused")
+}
+
+func TestTransientFailuresAreNotDeadLettered(t *testing.T) {
+	storage := inmemory.NewStorage()
+	storageOrders := inmemory.NewOrderRepository(storage)
+	storageEvents := inmemory.NewEventRepository(storage)
+	deadLetters := inmemory.NewDeadLetterRepository()
+	newProcessor := func(events domain.EventRepository) *domain.OrderProcessor {
+		return domain.NewOrderProcessor(storageOrders, events, console.NewConsoleNotifier(), inmemory.NewProcessedEvents(), inmemory.NewLocker(), deadLetters, 5*time.Second, clock.New())
+	}
+
+	now := time.Now()
+	created := domain.OrderEvent{EventID: "event1", OrderID: "order1", UserID: "user1", OrderStatus: domain.CoolOrderCreated, CreatedAt: now, UpdatedAt: now}
+
+	err := newProcessor(unavailableEventRepository{storageEvents}).HandleEvent(created)
+	if err == nil || domain.IsRejection(err) {
+		t.Fatalf("Expected a transient error, got %v", err)
+	}
+	if deadLetter, _ := deadLetters.Get(created.EventID); deadLetter != nil {
+		t.Errorf("Expected no dead letter for a transient failure, got %+v", deadLetter)
+	}
+
+	// The redelivered event is processed once the storage is back
+	if err := newProcessor(storageEvents).HandleEvent(created); err != nil {
+		t.Fatalf("Failed to process the redelivered event: %v", err)
+	}
+	if order, _ := storageOrders.Get("order1"); order == nil || len(order.Events) != 1 {
+		t.Errorf("Expected the order with its event, got %+v", order)
+	}
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortField is a field orders can be sorted by.
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+	SortByOrderID   SortField = "order_id"
+	SortByUserID    SortField = "user_id"
+	SortByStatus    SortField = "status"
+)
+
+var sortFields = map[string]SortField{
+	"created_at": SortByCreatedAt,
+	"updated_at": SortByUpdatedAt,
+	"order_id":   SortByOrderID,
+	"user_id":    SortByUserID,
+	"status":     SortByStatus,
+}
+
+func ParseSortField(field string) (SortField, error) {
+	if val, ok := sortFields[field]; ok {
+		return val, nil
+	}
+	return "", fmt.Errorf("%w: unknown field %q", ErrInvalidSort, field)
+}
+
+type SortKey struct {
+	Field      SortField
+	Descending bool
+}
+
+// OrderSort is an ordered list of sort keys, the first key has the highest priority.
+type OrderSort []SortKey
+
+// DefaultOrderSort sorts the newest orders first.
+var DefaultOrderSort = OrderSort{{Field: SortByCreatedAt, Descending: true}}
+
+// ParseOrderSort parses a comma separated list of fields, where a leading "-"
+// means descending order, e.g. "-updated_at,order_id".
+func ParseOrderSort(sort string) (OrderSort, error) {
+	if strings.TrimSpace(sort) == "" {
+		return nil, fmt.Errorf("%w: empty sort", ErrInvalidSort)
+	}
+
+	var result OrderSort
+	seen := make(map[SortField]bool)
+
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Descending = true
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+
+		field, err := ParseSortField(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[field] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidSort, field)
+		}
+		seen[field] = true
+
+		key.Field = field
+		result = append(result, key)
+	}
+
+	return result, nil
+}
+
+// WithTieBreaker appends order_id as the last key, so orders with equal
+// values in the requested fields are always returned in the same order.
+func (s OrderSort) WithTieBreaker() OrderSort {
+	for _, key := range s {
+		if key.Field == SortByOrderID {
+			return s
+		}
+	}
+
+	result := make(OrderSort, len(s), len(s)+1)
+	copy(result, s)
+	return append(result, SortKey{Field: SortByOrderID})
+}
+
+func (s OrderSort) String() string {
+	parts := make([]string, len(s))
+	for i, key := range s {
+		if key.Descending {
+			parts[i] = "-" + string(key.Field)
+		} else {
+			parts[i] = string(key.Field)
+		}
+	}
+	return strings.Join(parts, ",")
+}
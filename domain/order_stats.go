@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+type StatsGroupBy string
+
+const (
+	GroupByStatus StatsGroupBy = "status"
+	GroupByDay    StatsGroupBy = "day"
+	GroupByHour   StatsGroupBy = "hour"
+)
+
+func ParseStatsGroupBy(groupBy string) (StatsGroupBy, error) {
+	switch StatsGroupBy(groupBy) {
+	case GroupByStatus, GroupByDay, GroupByHour:
+		return StatsGroupBy(groupBy), nil
+	}
+	return "", errors.New("invalid group_by value")
+}
+
+// StatsGroup is the number of orders falling into a single bucket:
+// a status or the start of a day/hour in UTC.
+type StatsGroup struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// TransitionStats describes how long orders take to move between two statuses.
+type TransitionStats struct {
+	From          OrderStatus `json:"from"`
+	To            OrderStatus `json:"to"`
+	Count         int         `json:"count"`
+	MedianSeconds float64     `json:"median_seconds"`
+}
+
+// CurrencyTotal is the sum of the amounts of orders in a single currency.
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Count    int    `json:"count"`
+	// Amount is in minor units of the currency
+	Amount int64 `json:"amount"`
+}
+
+type OrderStats struct {
+	Total    int          `json:"total"`
+	GroupBy  StatsGroupBy `json:"group_by"`
+	Groups   []StatsGroup `json:"groups"`
+	Chinazes int          `json:"chinazes"`
+	Refunded int          `json:"refunded"`
+	Canceled int          `json:"canceled"`
+	// Conversion is the share of orders that reached chinazes
+	Conversion float64 `json:"conversion"`
+	// RefundRate is the share of chinazes orders that were refunded
+	RefundRate float64 `json:"refund_rate"`
+	// CancelRate is the share of orders that were canceled or failed
+	CancelRate  float64           `json:"cancel_rate"`
+	Transitions []TransitionStats `json:"transitions"`
+	// Amounts are the totals of orders with an amount, by currency
+	Amounts []CurrencyTotal `json:"amounts"`
+}
+
+// CalculateRates fills conversion, refund and cancel rates from the counters.
+func (s *OrderStats) CalculateRates() {
+	s.Conversion = ratio(s.Chinazes, s.Total)
+	s.RefundRate = ratio(s.Refunded, s.Chinazes)
+	s.CancelRate = ratio(s.Canceled, s.Total)
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+type OrderStatsFilter struct {
+	From    *time.Time
+	To      *time.Time
+	UserID  string
+	GroupBy StatsGroupBy
+}
+
+type StatsFilterOption func(*OrderStatsFilter)
+
+func WithStatsPeriod(from, to *time.Time) StatsFilterOption {
+	return func(f *OrderStatsFilter) {
+		f.From = from
+		f.To = to
+	}
+}
+
+func WithStatsUserID(userID string) StatsFilterOption {
+	return func(f *OrderStatsFilter) {
+		f.UserID = userID
+	}
+}
+
+func WithStatsGroupBy(groupBy StatsGroupBy) StatsFilterOption {
+	return func(f *OrderStatsFilter) {
+		f.GroupBy = groupBy
+	}
+}
+
+func NewOrderStatsFilter(options ...StatsFilterOption) *OrderStatsFilter {
+	filter := &OrderStatsFilter{
+		GroupBy: GroupByStatus,
+	}
+
+	for _, option := range options {
+		option(filter)
+	}
+
+	return filter
+}
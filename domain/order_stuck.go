@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// OrderStuck is a non-final order that stayed in its status longer than the SLA of the status.
+type OrderStuck struct {
+	OrderID   string      `json:"order_id"`
+	UserID    string      `json:"user_id"`
+	Status    OrderStatus `json:"status"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// StuckSeconds is the time since the last update of the order
+	StuckSeconds float64 `json:"stuck_seconds"`
+	SLASeconds   float64 `json:"sla_seconds"`
+}
+
+func NewOrderStuck(order Order, sla time.Duration, now time.Time) OrderStuck {
+	return OrderStuck{
+		OrderID:      order.OrderID,
+		UserID:       order.UserID,
+		Status:       order.Status,
+		UpdatedAt:    order.UpdatedAt,
+		StuckSeconds: now.Sub(order.UpdatedAt).Seconds(),
+		SLASeconds:   sla.Seconds(),
+	}
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// Finalization causes.
+const (
+	FinalizedByTimer = "timer"
+	FinalizedByEvent = "event"
+)
+
+// TimelineTransition is a stored event of an order. Applied events move the
+// order from one status to another, events ignored as out of sequence don't.
+type TimelineTransition struct {
+	EventID   string      `json:"event_id"`
+	From      OrderStatus `json:"from,omitempty"`
+	To        OrderStatus `json:"to"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Applied   bool        `json:"applied"`
+	// RejectedReason is the illegal transition of an ignored event
+	RejectedReason string `json:"rejected_reason,omitempty"`
+	// DurationSeconds is the time spent in the To status until the next
+	// applied event, or until now for the current non-final status
+	DurationSeconds *float64 `json:"duration_seconds,omitempty"`
+}
+
+// TimelineFinalization tells when and by what an order became final: the
+// finalization timer after chinazes or a terminal event.
+type TimelineFinalization struct {
+	At      time.Time   `json:"at"`
+	By      string      `json:"by"`
+	EventID string      `json:"event_id"`
+	Status  OrderStatus `json:"status"`
+}
+
+type OrderTimeline struct {
+	OrderID      string                `json:"order_id"`
+	UserID       string                `json:"user_id"`
+	Status       OrderStatus           `json:"status"`
+	IsFinal      bool                  `json:"is_final"`
+	Transitions  []TimelineTransition  `json:"transitions"`
+	Finalization *TimelineFinalization `json:"finalization,omitempty"`
+}
+
+// NewOrderTimeline builds the timeline from the stored events of the order.
+// now ends the time spent in the current status of a non-final order.
+func NewOrderTimeline(order *Order, now time.Time) *OrderTimeline {
+	events := append([]OrderEvent(nil), order.Events...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+
+	timeline := &OrderTimeline{
+		OrderID:     order.OrderID,
+		UserID:      order.UserID,
+		Status:      order.Status,
+		IsFinal:     order.IsFinal,
+		Transitions: make([]TimelineTransition, 0, len(events)),
+	}
+
+	var current OrderStatus
+	last := -1
+	for _, event := range events {
+		transition := TimelineTransition{
+			EventID:        event.EventID,
+			From:           current,
+			To:             event.OrderStatus,
+			CreatedAt:      event.CreatedAt,
+			UpdatedAt:      event.UpdatedAt,
+			Applied:        event.RejectedReason == "",
+			RejectedReason: event.RejectedReason,
+		}
+
+		if transition.Applied {
+			if last >= 0 {
+				timeline.Transitions[last].DurationSeconds = seconds(event.CreatedAt.Sub(timeline.Transitions[last].CreatedAt))
+			}
+			current = event.OrderStatus
+			last = len(timeline.Transitions)
+		}
+
+		timeline.Transitions = append(timeline.Transitions, transition)
+	}
+
+	if last < 0 {
+		return timeline
+	}
+
+	lastApplied := &timeline.Transitions[last]
+	switch {
+	case !order.IsFinal:
+		lastApplied.DurationSeconds = seconds(now.Sub(lastApplied.CreatedAt))
+	case lastApplied.To == Chinazes:
+		// The timer finalizes the chinazes event, updating it at that time
+		lastApplied.DurationSeconds = seconds(lastApplied.UpdatedAt.Sub(lastApplied.CreatedAt))
+		timeline.Finalization = &TimelineFinalization{
+			At:      lastApplied.UpdatedAt,
+			By:      FinalizedByTimer,
+			EventID: lastApplied.EventID,
+			Status:  lastApplied.To,
+		}
+	default:
+		timeline.Finalization = &TimelineFinalization{
+			At:      lastApplied.CreatedAt,
+			By:      FinalizedByEvent,
+			EventID: lastApplied.EventID,
+			Status:  lastApplied.To,
+		}
+	}
+
+	return timeline
+}
+
+func seconds(d time.Duration) *float64 {
+	s := d.Seconds()
+	return &s
+}
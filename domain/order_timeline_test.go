@@ -0,0 +1,67 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func TestOrderTimeline(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	newEvent := func(id string, status domain.OrderStatus, minutes int) domain.OrderEvent {
+		createdAt := start.Add(time.Duration(minutes) * time.Minute)
+		return domain.OrderEvent{EventID: id, OrderID: "order1", OrderStatus: status, CreatedAt: createdAt, UpdatedAt: createdAt}
+	}
+
+	// The mayor event arrived before sbu and is still ignored
+	rejected := newEvent("event3", domain.ConfirmedByMayor, 30)
+	rejected.RejectedReason = "illegal transition from cool_order_created to confirmed_by_mayor"
+	order := &domain.Order{
+		OrderID: "order1",
+		Status:  domain.CoolOrderCreated,
+		Events:  []domain.OrderEvent{rejected, newEvent("event1", domain.CoolOrderCreated, 0)},
+	}
+
+	timeline := domain.NewOrderTimeline(order, start.Add(time.Hour))
+	if len(timeline.Transitions) != 2 {
+		t.Fatalf("Expected 2 transitions, got %+v", timeline.Transitions)
+	}
+	created, ignored := timeline.Transitions[0], timeline.Transitions[1]
+	if !created.Applied || created.DurationSeconds == nil || *created.DurationSeconds != 3600 {
+		t.Errorf("Expected cool_order_created to be applied and last until now, got %+v", created)
+	}
+	if ignored.Applied || ignored.From != domain.CoolOrderCreated || ignored.RejectedReason == "" || ignored.DurationSeconds != nil {
+		t.Errorf("Expected confirmed_by_mayor to be ignored, got %+v", ignored)
+	}
+	if timeline.Finalization != nil {
+		t.Errorf("Expected no finalization, got %+v", timeline.Finalization)
+	}
+
+	// The sbu event fills the gap, chinazes is finalized by the timer
+	chinazes := newEvent("event4", domain.Chinazes, 45)
+	chinazes.IsFinal = true
+	chinazes.UpdatedAt = chinazes.CreatedAt.Add(30 * time.Second)
+	rejected.RejectedReason = ""
+	order.Status = domain.Chinazes
+	order.IsFinal = true
+	order.Events = []domain.OrderEvent{
+		newEvent("event1", domain.CoolOrderCreated, 0), newEvent("event2", domain.SbuVerificationPending, 10), rejected, chinazes,
+	}
+
+	timeline = domain.NewOrderTimeline(order, start.Add(time.Hour))
+	durations := []float64{600, 1200, 900, 30}
+	for i, transition := range timeline.Transitions {
+		if !transition.Applied || transition.DurationSeconds == nil || *transition.DurationSeconds != durations[i] {
+			t.Errorf("Expected transition %d to be applied for %vs, got %+v", i, durations[i], transition)
+		}
+	}
+	if timeline.Transitions[1].From != domain.CoolOrderCreated || timeline.Transitions[1].To != domain.SbuVerificationPending {
+		t.Errorf("Expected transition from cool_order_created to sbu_verification_pending, got %+v", timeline.Transitions[1])
+	}
+
+	finalization := timeline.Finalization
+	if finalization == nil || finalization.By != domain.FinalizedByTimer || !finalization.At.Equal(chinazes.UpdatedAt) {
+		t.Errorf("Expected finalization by timer at %v, got %+v", chinazes.UpdatedAt, finalization)
+	}
+}
@@ -0,0 +1,155 @@
+package domain
+
+import "time"
+
+// SettlementRecord is a row of a JustPay! settlement file.
+type SettlementRecord struct {
+	OrderID string      `json:"order_id"`
+	UserID  string      `json:"user_id"`
+	Status  OrderStatus `json:"status"`
+	IsFinal bool        `json:"is_final"`
+}
+
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyMissingOrder is a settled order that is not stored
+	DiscrepancyMissingOrder DiscrepancyKind = "missing_order"
+	// DiscrepancyStatusMismatch is an order with a different status
+	DiscrepancyStatusMismatch DiscrepancyKind = "status_mismatch"
+	// DiscrepancyFinalMismatch is an order with the same status that is final on one side only
+	DiscrepancyFinalMismatch DiscrepancyKind = "final_mismatch"
+	// DiscrepancyMissingSettlement is a stored order of the settlement period that is not settled
+	DiscrepancyMissingSettlement DiscrepancyKind = "missing_settlement"
+)
+
+// SettlementPeriod is the range of creation times of the orders a settlement
+// file covers, From inclusive and To exclusive. A nil bound is open.
+type SettlementPeriod struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// Discrepancy is a difference between a settlement record and the stored order.
+type Discrepancy struct {
+	OrderID          string          `json:"order_id"`
+	Kind             DiscrepancyKind `json:"kind"`
+	SettlementStatus OrderStatus     `json:"settlement_status,omitempty"`
+	SettlementFinal  bool            `json:"settlement_final"`
+	OrderStatus      OrderStatus     `json:"order_status,omitempty"`
+	OrderFinal       bool            `json:"order_final"`
+	// Corrections are the statuses of events that would bring the order to the
+	// settlement status, empty when it can't be corrected with events
+	Corrections []OrderStatus `json:"corrections,omitempty"`
+}
+
+// ReconciliationCorrection is the audit record of a synthetic event created
+// to correct a discrepancy.
+type ReconciliationCorrection struct {
+	RunID   string      `json:"run_id"`
+	EventID string      `json:"event_id"`
+	OrderID string      `json:"order_id"`
+	Status  OrderStatus `json:"status"`
+	// Source is the name of the settlement file
+	Source string `json:"source"`
+	// Error is why the event was not applied, empty when it was
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReconciliationReport struct {
+	RunID   string `json:"run_id"`
+	Source  string `json:"source"`
+	Rows    int    `json:"rows"`
+	Matched int    `json:"matched"`
+	// Period is the range of stored orders expected in the file, it is not
+	// set when no stored order was found to derive it from
+	Period        *SettlementPeriod          `json:"period,omitempty"`
+	Discrepancies []Discrepancy              `json:"discrepancies"`
+	Corrections   []ReconciliationCorrection `json:"corrections,omitempty"`
+}
+
+type ReconciliationCorrectionFilter struct {
+	RunID   string
+	OrderID string
+	Limit   int
+	Offset  int
+}
+
+type ReconciliationAuditRepository interface {
+	Add(correction ReconciliationCorrection) error
+	// List returns corrections from the most recent one
+	List(filter ReconciliationCorrectionFilter) ([]ReconciliationCorrection, error)
+}
+
+// Reconcile compares the settlement record with the stored order, which is
+// nil when it is missing. It returns nil when both agree.
+func Reconcile(record SettlementRecord, order *Order) *Discrepancy {
+	discrepancy := &Discrepancy{
+		OrderID:          record.OrderID,
+		SettlementStatus: record.Status,
+		SettlementFinal:  record.IsFinal,
+	}
+
+	switch {
+	case order == nil:
+		discrepancy.Kind = DiscrepancyMissingOrder
+	case order.Status != record.Status:
+		discrepancy.Kind = DiscrepancyStatusMismatch
+	case order.IsFinal != record.IsFinal:
+		discrepancy.Kind = DiscrepancyFinalMismatch
+	default:
+		return nil
+	}
+
+	if order != nil {
+		discrepancy.OrderStatus = order.Status
+		discrepancy.OrderFinal = order.IsFinal
+	}
+	// An order can only be created for a known user
+	if order != nil || record.UserID != "" {
+		discrepancy.Corrections = correctiveStatuses(order, record.Status)
+	}
+
+	return discrepancy
+}
+
+// correctiveStatuses returns the statuses of events missing from the order to
+// reach the target status, or nil if events can't get it there: final orders
+// don't accept events and the processor never moves an order backwards.
+func correctiveStatuses(order *Order, target OrderStatus) []OrderStatus {
+	if order != nil && order.IsFinal {
+		return nil
+	}
+
+	if target.isCancel() {
+		if order == nil {
+			return []OrderStatus{CoolOrderCreated, target}
+		}
+		return []OrderStatus{target}
+	}
+
+	from := 0
+	if order != nil {
+		from = sequenceIndex(order.Status) + 1
+		if from == 0 {
+			return nil
+		}
+	}
+
+	to := sequenceIndex(target)
+	if to < from {
+		return nil
+	}
+
+	return append([]OrderStatus(nil), requiredSequence[from:to+1]...)
+}
+
+func sequenceIndex(status OrderStatus) int {
+	for i, current := range requiredSequence {
+		if current == status {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package domain_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func TestReconcile(t *testing.T) {
+	stored := func(status domain.OrderStatus, isFinal bool) *domain.Order {
+		return &domain.Order{OrderID: "order1", UserID: "user1", Status: status, IsFinal: isFinal}
+	}
+	record := func(userID string, status domain.OrderStatus, isFinal bool) domain.SettlementRecord {
+		return domain.SettlementRecord{OrderID: "order1", UserID: userID, Status: status, IsFinal: isFinal}
+	}
+
+	tests := []struct {
+		name        string
+		record      domain.SettlementRecord
+		order       *domain.Order
+		kind        domain.DiscrepancyKind
+		corrections []domain.OrderStatus
+	}{
+		{"matched", record("", domain.Chinazes, false), stored(domain.Chinazes, false), "", nil},
+		{
+			"missing order", record("user1", domain.SbuVerificationPending, false), nil,
+			domain.DiscrepancyMissingOrder, []domain.OrderStatus{domain.CoolOrderCreated, domain.SbuVerificationPending},
+		},
+		{"missing order of unknown user", record("", domain.CoolOrderCreated, false), nil, domain.DiscrepancyMissingOrder, nil},
+		{
+			"missing cancelled order", record("user1", domain.Failed, true), nil,
+			domain.DiscrepancyMissingOrder, []domain.OrderStatus{domain.CoolOrderCreated, domain.Failed},
+		},
+		{
+			"order behind", record("", domain.GiveMyMoneyBack, true), stored(domain.ConfirmedByMayor, false),
+			domain.DiscrepancyStatusMismatch, []domain.OrderStatus{domain.Chinazes, domain.GiveMyMoneyBack},
+		},
+		{
+			"order cancelled by settlement", record("", domain.ChangedMyMind, true), stored(domain.SbuVerificationPending, false),
+			domain.DiscrepancyStatusMismatch, []domain.OrderStatus{domain.ChangedMyMind},
+		},
+		{"order ahead", record("", domain.SbuVerificationPending, false), stored(domain.Chinazes, false), domain.DiscrepancyStatusMismatch, nil},
+		{"final order", record("", domain.GiveMyMoneyBack, true), stored(domain.Chinazes, true), domain.DiscrepancyStatusMismatch, nil},
+		{"final in settlement only", record("", domain.Chinazes, true), stored(domain.Chinazes, false), domain.DiscrepancyFinalMismatch, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discrepancy := domain.Reconcile(test.record, test.order)
+			if test.kind == "" {
+				if discrepancy != nil {
+					t.Fatalf("Expected no discrepancy, got %+v", discrepancy)
+				}
+				return
+			}
+
+			if discrepancy == nil || discrepancy.Kind != test.kind {
+				t.Fatalf("Expected %s discrepancy, got %+v", test.kind, discrepancy)
+			}
+			if !reflect.DeepEqual(discrepancy.Corrections, test.corrections) {
+				t.Errorf("Expected corrections %v, got %v", test.corrections, discrepancy.Corrections)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "fmt"
+
+// SequenceViolation is the result of an event that was stored but not applied,
+// because the order's events no longer form a valid lifecycle. From and To are
+// the first illegal transition, From is empty when the order's first event is
+// not cool_order_created.
+type SequenceViolation struct {
+	OrderID string      `json:"order_id"`
+	EventID string      `json:"event_id"`
+	From    OrderStatus `json:"from"`
+	To      OrderStatus `json:"to"`
+}
+
+func (v *SequenceViolation) Error() string {
+	if v.From == "" {
+		return fmt.Sprintf("illegal transition to %s", v.To)
+	}
+	return fmt.Sprintf("illegal transition from %s to %s", v.From, v.To)
+}
@@ -2,12 +2,46 @@ package postgres
 
 import (
 	"database/sql"
+	"fmt"
+	"time"
 )
 
-func New(connection string) (*sql.DB, error) {
+// Retry controls how New waits for a database that does not accept
+// connections yet, e.g. when it starts together with the application.
+type Retry struct {
+	Attempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// New opens the database and pings it until it is reachable or the
+// attempts are used up.
+func New(connection string, retry Retry) (*sql.DB, error) {
 	db, err := sql.Open("postgres", connection)
 	if err != nil {
 		return nil, err
 	}
-	return db, nil
+
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err = db.Ping()
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= retry.Attempts {
+			break
+		}
+
+		fmt.Printf("error connecting to database (attempt %d of %d), retrying in %s: %v\n", attempt, retry.Attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+
+	db.Close()
+	return nil, fmt.Errorf("failed to connect to database: %w", err)
 }
@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/infrastructure/database/postgres"
+)
+
+// Nothing listens on these ports, so connecting fails right away.
+const (
+	unreachablePrimary = "postgres://justdone@127.0.0.1:1/justdone?sslmode=disable&connect_timeout=1"
+	unreachableReplica = "postgres://justdone@127.0.0.1:2/justdone?sslmode=disable&connect_timeout=1"
+)
+
+func TestNewRetriesUnreachableDatabase(t *testing.T) {
+	started := time.Now()
+	db, err := postgres.New(unreachablePrimary, postgres.Retry{Attempts: 2, Backoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	elapsed := time.Since(started)
+
+	if err == nil {
+		db.Close()
+		t.Fatal("Expected an error for an unreachable database")
+	}
+	if !strings.HasPrefix(err.Error(), "failed to connect to database: ") || !strings.Contains(err.Error(), "127.0.0.1:1") {
+		t.Errorf("Expected the connection error to be wrapped, got %v", err)
+	}
+
+	// One backoff between the two attempts
+	if elapsed < 100*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Expected a single backoff of 100ms, took %s", elapsed)
+	}
+}
+
+func TestNewDoesNotRetrySingleAttempt(t *testing.T) {
+	started := time.Now()
+	if _, err := postgres.New(unreachablePrimary, postgres.Retry{Attempts: 1, Backoff: time.Minute}); err == nil {
+		t.Fatal("Expected an error for an unreachable database")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected no backoff for a single attempt, took %s", elapsed)
+	}
+}
+
+func TestOrderRepositoryWithReplica(t *testing.T) {
+	// Opening does not connect, so every query reports the pool it was sent to
+	primary, replica := openLazy(t, unreachablePrimary), openLazy(t, unreachableReplica)
+
+	repository := postgres.NewOrderRepository(primary)
+	withReplica := repository.WithReplica(replica)
+	filter := domain.NewOrderFilter()
+
+	tests := []struct {
+		name    string
+		query   func() error
+		address string
+	}{
+		{"get without replica", func() error { _, err := repository.Get("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"); return err }, "127.0.0.1:1"},
+		{"get many without replica", func() error { _, err := repository.GetMany(filter); return err }, "127.0.0.1:1"},
+		{"get from primary", func() error { _, err := withReplica.Get("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"); return err }, "127.0.0.1:1"},
+		{"get many from replica", func() error { _, err := withReplica.GetMany(filter); return err }, "127.0.0.1:2"},
+		{"count from replica", func() error { _, err := withReplica.Count(filter, 0); return err }, "127.0.0.1:2"},
+		{"stats from replica", func() error { _, err := withReplica.Stats(domain.NewOrderStatsFilter()); return err }, "127.0.0.1:2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.query()
+			if err == nil || !strings.Contains(err.Error(), tt.address) {
+				t.Errorf("Expected the query to be sent to %s, got %v", tt.address, err)
+			}
+		})
+	}
+}
+
+func openLazy(t *testing.T, connection string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.DeadLetterRepository = new(DeadLetterRepository)
+
+type DeadLetterRepository struct {
+	db *sql.DB
+}
+
+func NewDeadLetterRepository(db *sql.DB) DeadLetterRepository {
+	return DeadLetterRepository{db: db}
+}
+
+func (r DeadLetterRepository) Add(event domain.OrderEvent, reason string) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter event: %w", err)
+	}
+
+	query := `
+		INSERT INTO dead_letter_events (event_id, order_id, reason, payload, attempts, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 1, NOW(), NOW())
+		ON CONFLICT (event_id) DO UPDATE
+		SET reason = EXCLUDED.reason,
+			payload = EXCLUDED.payload,
+			attempts = dead_letter_events.attempts + 1,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.Exec(query, event.EventID, event.OrderID, reason, payload); err != nil {
+		return fmt.Errorf("failed to add dead letter event: %w", err)
+	}
+
+	return nil
+}
+
+func (r DeadLetterRepository) Get(eventID string) (*domain.DeadLetterEvent, error) {
+	query := `SELECT event_id, order_id, reason, payload, attempts, created_at, updated_at
+			  FROM dead_letter_events WHERE event_id = $1`
+
+	event, err := scanDeadLetter(r.db.QueryRow(query, eventID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get dead letter event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (r DeadLetterRepository) List(filter domain.DeadLetterFilter) ([]domain.DeadLetterEvent, error) {
+	query := `SELECT event_id, order_id, reason, payload, attempts, created_at, updated_at
+			  FROM dead_letter_events`
+	var args []interface{}
+
+	if filter.OrderID != "" {
+		args = append(args, filter.OrderID)
+		query += fmt.Sprintf(" WHERE order_id = $%d", len(args))
+	}
+
+	query += " ORDER BY updated_at DESC, event_id"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letter events: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	var events []domain.DeadLetterEvent
+	for rows.Next() {
+		event, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r DeadLetterRepository) Delete(eventIDs ...string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	if _, err := r.db.Exec(`DELETE FROM dead_letter_events WHERE event_id = ANY($1::uuid[])`, pq.Array(eventIDs)); err != nil {
+		return fmt.Errorf("failed to delete dead letter events: %w", err)
+	}
+
+	return nil
+}
+
+func scanDeadLetter(row interface{ Scan(...interface{}) error }) (*domain.DeadLetterEvent, error) {
+	var event domain.DeadLetterEvent
+	var payload []byte
+
+	err := row.Scan(
+		&event.EventID,
+		&event.OrderID,
+		&event.Reason,
+		&payload,
+		&event.Attempts,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(payload, &event.Payload); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter event: %w", err)
+	}
+
+	return &event, nil
+}
@@ -2,12 +2,16 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/therealyo/justdone/domain"
 )
 
+// uniqueViolation is the Postgres error code of a duplicate key.
+const uniqueViolation = "23505"
+
 type EventRepository struct {
 	db *sql.DB
 }
@@ -17,10 +21,12 @@ func NewEventRepository(db *sql.DB) EventRepository {
 }
 
 func (r EventRepository) Get(eventID string) (*domain.OrderEvent, error) {
-	query := `SELECT event_id, order_id, user_id, order_status, created_at, updated_at, is_final 
+	query := `SELECT event_id, order_id, user_id, order_status, created_at, updated_at, is_final, rejected_reason,
+			  amount, COALESCE(currency, ''), metadata
 			  FROM order_events WHERE event_id = $1`
 
 	var event domain.OrderEvent
+	var rejectedReason sql.NullString
 
 	err := r.db.QueryRow(query, eventID).Scan(
 		&event.EventID,
@@ -30,6 +36,10 @@ func (r EventRepository) Get(eventID string) (*domain.OrderEvent, error) {
 		&event.CreatedAt,
 		&event.UpdatedAt,
 		&event.IsFinal,
+		&rejectedReason,
+		&event.Amount,
+		&event.Currency,
+		scanJSON(&event.Metadata),
 	)
 
 	if err != nil {
@@ -38,13 +48,15 @@ func (r EventRepository) Get(eventID string) (*domain.OrderEvent, error) {
 		}
 		return nil, err
 	}
+	event.RejectedReason = rejectedReason.String
 
 	return &event, nil
 }
 
 func (r EventRepository) Create(event domain.OrderEvent) error {
-	query := `INSERT INTO order_events (event_id, order_id, user_id, order_status, created_at, updated_at, is_final)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	query := `INSERT INTO order_events (event_id, order_id, user_id, order_status, created_at, updated_at, is_final, rejected_reason,
+			  amount, currency, metadata)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), $11)`
 
 	_, err := r.db.Exec(query,
 		event.EventID,
@@ -54,9 +66,17 @@ func (r EventRepository) Create(event domain.OrderEvent) error {
 		event.CreatedAt,
 		event.UpdatedAt,
 		event.IsFinal,
+		event.RejectedReason,
+		event.Amount,
+		event.Currency,
+		jsonValue(event.Metadata),
 	)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return domain.ErrEventConflict
+		}
 		return fmt.Errorf("failed to create event: %w", err)
 	}
 
@@ -65,12 +85,15 @@ func (r EventRepository) Create(event domain.OrderEvent) error {
 
 func (r EventRepository) Update(event domain.OrderEvent) error {
 	fmt.Println("updating event", event)
-	query := `UPDATE order_events SET order_status = $1, is_final = $2, updated_at = $3 WHERE event_id = $4`
+	query := `UPDATE order_events
+			  SET order_status = $1, is_final = $2, updated_at = $3, rejected_reason = NULLIF($4, '')
+			  WHERE event_id = $5`
 
 	_, err := r.db.Exec(query,
 		event.OrderStatus,
 		event.IsFinal,
 		event.UpdatedAt,
+		event.RejectedReason,
 		event.EventID,
 	)
 
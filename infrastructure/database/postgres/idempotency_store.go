@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.IdempotencyStore = new(IdempotencyStore)
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+func NewIdempotencyStore(db *sql.DB) IdempotencyStore {
+	return IdempotencyStore{db: db}
+}
+
+func (s IdempotencyStore) Get(key string) (*domain.IdempotentResponse, error) {
+	query := `SELECT key, request_hash, status, headers, body, expires_at
+			  FROM idempotency_keys WHERE key = $1 AND expires_at > NOW()`
+
+	var response domain.IdempotentResponse
+	var headers json.RawMessage
+	err := s.db.QueryRow(query, key).Scan(
+		&response.Key,
+		&response.RequestHash,
+		&response.Status,
+		scanJSON(&headers),
+		&response.Body,
+		&response.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotent response: %w", err)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &response.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode idempotent response headers: %w", err)
+		}
+	}
+
+	return &response, nil
+}
+
+// Save stores the response, replacing an expired one for the same key.
+// Other expired responses are purged in the same statement.
+func (s IdempotencyStore) Save(response domain.IdempotentResponse) error {
+	var headers json.RawMessage
+	if len(response.Headers) > 0 {
+		encoded, err := json.Marshal(response.Headers)
+		if err != nil {
+			return fmt.Errorf("failed to encode idempotent response headers: %w", err)
+		}
+		headers = encoded
+	}
+
+	query := `
+		WITH purged AS (
+			DELETE FROM idempotency_keys WHERE expires_at <= NOW() AND key <> $1
+		)
+		INSERT INTO idempotency_keys (key, request_hash, status, headers, body, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+		ON CONFLICT (key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status = EXCLUDED.status,
+			headers = EXCLUDED.headers,
+			body = EXCLUDED.body,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+	`
+
+	_, err := s.db.Exec(query,
+		response.Key,
+		response.RequestHash,
+		response.Status,
+		jsonValue(headers),
+		response.Body,
+		response.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+
+	return nil
+}
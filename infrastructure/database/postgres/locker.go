@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.Locker = new(Locker)
+
+// Locker locks orders across all instances sharing the database with
+// transaction scoped advisory locks. Each held lock keeps a connection
+// with an open transaction until it is released.
+type Locker struct {
+	db *sql.DB
+}
+
+func NewLocker(db *sql.DB) Locker {
+	return Locker{db: db}
+}
+
+func (l Locker) Lock(orderID string) (func(), error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin lock transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, orderID); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			fmt.Printf("error rolling back lock transaction: %v\n", rbErr)
+		}
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	// Ending the transaction releases the lock
+	return func() {
+		if err := tx.Commit(); err != nil {
+			fmt.Printf("error releasing order lock: %v\n", err)
+		}
+	}, nil
+}
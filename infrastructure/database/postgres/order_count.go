@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func (r OrderRepository) Count(filter *domain.OrderFilter, exactLimit int) (*domain.OrderCount, error) {
+	where, args := r.buildWhere(filter)
+
+	if exactLimit <= 0 {
+		return r.countExact(where, args)
+	}
+
+	// Count at most exactLimit+1 rows to find out whether an exact count is cheap
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM (SELECT 1 FROM orders%s LIMIT $%d) limited`, where, len(args)+1)
+
+	var total int
+	if err := r.replica.QueryRow(query, append(args, exactLimit+1)...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	if total <= exactLimit {
+		return &domain.OrderCount{Total: total}, nil
+	}
+
+	estimate, err := r.estimateCount(where, args)
+	if err != nil {
+		// The planner estimate is an optimization, fall back to the exact count
+		fmt.Printf("error estimating orders count: %v\n", err)
+		return r.countExact(where, args)
+	}
+
+	if estimate < total {
+		estimate = total
+	}
+
+	return &domain.OrderCount{Total: estimate, Estimated: true}, nil
+}
+
+func (r OrderRepository) countExact(where string, args []interface{}) (*domain.OrderCount, error) {
+	var total int
+	if err := r.replica.QueryRow(`SELECT COUNT(*) FROM orders`+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+	return &domain.OrderCount{Total: total}, nil
+}
+
+// estimateCount returns the number of rows the query planner expects the filter to match.
+func (r OrderRepository) estimateCount(where string, args []interface{}) (int, error) {
+	var raw []byte
+	if err := r.replica.QueryRow(`EXPLAIN (FORMAT JSON) SELECT 1 FROM orders`+where, args...).Scan(&raw); err != nil {
+		return 0, fmt.Errorf("failed to explain orders query: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return 0, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+
+	if len(plans) == 0 {
+		return 0, fmt.Errorf("empty query plan")
+	}
+
+	return int(plans[0].Plan.Rows), nil
+}
@@ -12,48 +12,122 @@ import (
 
 type OrderRepository struct {
 	db *sql.DB
+	// replica serves listing, counting and stats queries
+	replica *sql.DB
 }
 
 func NewOrderRepository(db *sql.DB) OrderRepository {
-	return OrderRepository{db: db}
+	return OrderRepository{db: db, replica: db}
 }
 
-func (r *OrderRepository) buildQuery(filter *domain.OrderFilter) (string, []interface{}) {
-	query := `SELECT order_id, user_id, status, is_final, created_at, updated_at FROM orders`
+// WithReplica returns the repository reading listings, counts and stats from
+// the replica. They may lag behind the primary by the replication delay.
+func (r OrderRepository) WithReplica(replica *sql.DB) OrderRepository {
+	r.replica = replica
+	return r
+}
+
+// buildWhere translates the filter conditions into a WHERE clause.
+// Placeholders are numbered from $1 in the order of the returned args.
+func (r *OrderRepository) buildWhere(filter *domain.OrderFilter) (string, []interface{}) {
+	var where string
 	var args []interface{}
 	var conditions []string
 	placeholderIndex := 1
 
-	if len(filter.Status) > 0 {
-		statusPlaceholders := make([]string, len(filter.Status))
-		for i, status := range filter.Status {
-			statusPlaceholders[i] = fmt.Sprintf("$%d", placeholderIndex)
-			args = append(args, status)
+	// placeholders appends values to args and returns their placeholders
+	placeholders := func(values ...interface{}) string {
+		list := make([]string, len(values))
+		for i, value := range values {
+			list[i] = fmt.Sprintf("$%d", placeholderIndex)
+			args = append(args, value)
 			placeholderIndex++
 		}
-		conditions = append(conditions, fmt.Sprintf("status IN (%s)", strings.Join(statusPlaceholders, ",")))
+		return strings.Join(list, ",")
+	}
+
+	if len(filter.Status) > 0 {
+		conditions = append(conditions, fmt.Sprintf("status IN (%s)", placeholders(toArgs(filter.Status)...)))
+	}
+
+	if len(filter.UserIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("user_id IN (%s)", placeholders(toArgs(filter.UserIDs)...)))
 	}
 
-	if filter.UserID != "" {
-		conditions = append(conditions, fmt.Sprintf("user_id = $%d", placeholderIndex))
-		args = append(args, filter.UserID)
-		placeholderIndex++
+	if len(filter.OrderIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("order_id IN (%s)", placeholders(toArgs(filter.OrderIDs)...)))
 	}
 
 	if filter.IsFinal != nil {
-		conditions = append(conditions, fmt.Sprintf("is_final = $%d", placeholderIndex))
-		args = append(args, *filter.IsFinal)
-		placeholderIndex++
+		conditions = append(conditions, fmt.Sprintf("is_final = %s", placeholders(*filter.IsFinal)))
+	}
+
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= %s", placeholders(*filter.CreatedFrom)))
+	}
+
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < %s", placeholders(*filter.CreatedTo)))
+	}
+
+	if filter.UpdatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("updated_at >= %s", placeholders(*filter.UpdatedFrom)))
+	}
+
+	if filter.UpdatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("updated_at < %s", placeholders(*filter.UpdatedTo)))
+	}
+
+	if len(filter.EverInStatus) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM order_events e WHERE e.order_id = orders.order_id AND e.order_status IN (%s)"+
+				" AND (e.rejected_reason IS NULL OR e.rejected_reason = ''))",
+			placeholders(toArgs(filter.EverInStatus)...),
+		))
+	}
+
+	if filter.AmountFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("amount >= %s", placeholders(*filter.AmountFrom)))
+	}
+
+	if filter.AmountTo != nil {
+		conditions = append(conditions, fmt.Sprintf("amount <= %s", placeholders(*filter.AmountTo)))
+	}
+
+	if len(filter.Currencies) > 0 {
+		conditions = append(conditions, fmt.Sprintf("currency IN (%s)", placeholders(toArgs(filter.Currencies)...)))
+	}
+
+	if filter.StuckFor > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"is_final = FALSE AND updated_at < %s",
+			placeholders(filter.StuckBefore()),
+		))
 	}
 
 	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s", filter.SortBy, filter.SortOrder)
+	return where, args
+}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", placeholderIndex, placeholderIndex+1)
-	args = append(args, filter.Limit, filter.Offset)
+func (r *OrderRepository) buildQuery(filter *domain.OrderFilter) (string, []interface{}) {
+	where, args := r.buildWhere(filter)
+	query := `SELECT order_id, user_id, status, is_final, created_at, updated_at, version,
+			  amount, COALESCE(currency, ''), metadata FROM orders` + where
+	placeholderIndex := len(args) + 1
+
+	query += " ORDER BY " + orderByClause(filter.Sort)
+
+	// A non-positive limit selects all matching orders, which is used by exports
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", placeholderIndex, placeholderIndex+1)
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", placeholderIndex)
+		args = append(args, filter.Offset)
+	}
 
 	return query, args
 }
@@ -61,7 +135,7 @@ func (r *OrderRepository) buildQuery(filter *domain.OrderFilter) (string, []inte
 func (r OrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, error) {
 	query, args := r.buildQuery(filter)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.replica.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -74,8 +148,8 @@ func (r OrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, er
 	var orders []domain.Order
 
 	for rows.Next() {
-		var order domain.Order
-		if err := rows.Scan(&order.OrderID, &order.UserID, &order.Status, &order.IsFinal, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		order, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -91,12 +165,14 @@ func (r OrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, er
 
 func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 	query := `
-		SELECT o.order_id, o.user_id, o.status, o.is_final, o.created_at, o.updated_at,
-		       e.event_id, e.user_id, e.order_status, e.created_at, e.updated_at, e.is_final
+		SELECT o.order_id, o.user_id, o.status, o.is_final, o.created_at, o.updated_at, o.version,
+		       o.amount, COALESCE(o.currency, ''), o.metadata,
+		       e.event_id, e.user_id, e.order_status, e.created_at, e.updated_at, e.is_final, e.rejected_reason,
+		       e.amount, COALESCE(e.currency, ''), e.metadata
 		FROM orders o
 		LEFT JOIN order_events e ON o.order_id = e.order_id
 		WHERE o.order_id = $1
-		ORDER BY e.created_at ASC
+		ORDER BY e.created_at ASC, e.event_id ASC
 	`
 
 	rows, err := r.db.Query(query, orderID)
@@ -119,6 +195,8 @@ func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 			updateTime  sql.NullTime
 			isFinal     sql.NullBool
 			userID      sql.NullString
+			rejected    sql.NullString
+			details     domain.PaymentDetails
 		)
 
 		if order == nil {
@@ -130,12 +208,20 @@ func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 				&order.IsFinal,
 				&order.CreatedAt,
 				&order.UpdatedAt,
+				&order.Version,
+				&order.Amount,
+				&order.Currency,
+				scanJSON(&order.Metadata),
 				&eventID,
 				&userID,
 				&orderStatus,
 				&eventTime,
 				&updateTime,
 				&isFinal,
+				&rejected,
+				&details.Amount,
+				&details.Currency,
+				scanJSON(&details.Metadata),
 			); err != nil {
 				return nil, fmt.Errorf("failed to scan order row: %w", err)
 			}
@@ -147,12 +233,20 @@ func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 				new(bool),
 				new(time.Time),
 				new(time.Time),
+				new(int64),
+				new(*int64),
+				new(string),
+				new([]byte),
 				&eventID,
 				&userID,
 				&orderStatus,
 				&eventTime,
 				&updateTime,
 				&isFinal,
+				&rejected,
+				&details.Amount,
+				&details.Currency,
+				scanJSON(&details.Metadata),
 			); err != nil {
 				return nil, fmt.Errorf("failed to scan event row: %w", err)
 			}
@@ -160,13 +254,15 @@ func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 
 		if eventID.Valid {
 			event := domain.OrderEvent{
-				EventID:     eventID.String,
-				OrderID:     order.OrderID,
-				UserID:      userID.String,
-				OrderStatus: domain.OrderStatus(orderStatus.String),
-				CreatedAt:   eventTime.Time,
-				UpdatedAt:   updateTime.Time,
-				IsFinal:     isFinal.Bool,
+				EventID:        eventID.String,
+				OrderID:        order.OrderID,
+				UserID:         userID.String,
+				OrderStatus:    domain.OrderStatus(orderStatus.String),
+				CreatedAt:      eventTime.Time,
+				UpdatedAt:      updateTime.Time,
+				IsFinal:        isFinal.Bool,
+				RejectedReason: rejected.String,
+				PaymentDetails: details,
 			}
 			order.Events = append(order.Events, event)
 		}
@@ -187,47 +283,130 @@ func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
 	return order, nil
 }
 
+// Save inserts a new order or updates a stored one if its version did not change.
 func (r OrderRepository) Save(order *domain.Order) error {
-	tx, err := r.db.Begin()
+	var result sql.Result
+	var err error
+
+	if order.Version == 0 {
+		query := `
+			INSERT INTO orders (order_id, user_id, status, is_final, created_at, updated_at, version, amount, currency, metadata)
+			VALUES ($1, $2, $3, $4, $5, $6, 1, $7, NULLIF($8, ''), $9)
+			ON CONFLICT (order_id) DO NOTHING
+		`
+		result, err = r.db.Exec(query,
+			order.OrderID,
+			order.UserID,
+			order.Status,
+			order.IsFinal,
+			order.CreatedAt,
+			order.UpdatedAt,
+			order.Amount,
+			order.Currency,
+			jsonValue(order.Metadata),
+		)
+	} else {
+		query := `
+			UPDATE orders
+			SET user_id = $2,
+				status = $3,
+				is_final = $4,
+				created_at = $5,
+				updated_at = $6,
+				amount = $8,
+				currency = NULLIF($9, ''),
+				metadata = $10,
+				version = version + 1
+			WHERE order_id = $1 AND version = $7
+		`
+		result, err = r.db.Exec(query,
+			order.OrderID,
+			order.UserID,
+			order.Status,
+			order.IsFinal,
+			order.CreatedAt,
+			order.UpdatedAt,
+			order.Version,
+			order.Amount,
+			order.Currency,
+			jsonValue(order.Metadata),
+		)
+	}
+
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to save order: %w", err)
 	}
 
-	defer func() {
-		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				err = fmt.Errorf("rollback error: %v, original error: %w", rbErr, err)
-			}
-		} else {
-			err = tx.Commit()
-		}
-	}()
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save order: %w", err)
+	}
 
-	orderQuery := `
-		INSERT INTO orders (order_id, user_id, status, is_final, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (order_id) DO UPDATE 
-		SET user_id = EXCLUDED.user_id,
-			status = EXCLUDED.status,
-			is_final = EXCLUDED.is_final,
-			created_at = EXCLUDED.created_at,
-			updated_at = EXCLUDED.updated_at
-	`
+	// The order already exists or its version changed since it was read
+	if affected == 0 {
+		return domain.ErrConcurrentModification
+	}
+
+	order.Version++
+	return nil
+}
 
-	_, err = tx.Exec(orderQuery,
-		order.OrderID,
-		order.UserID,
-		order.Status,
-		order.IsFinal,
-		order.CreatedAt,
-		order.UpdatedAt,
+func scanOrder(row interface{ Scan(...interface{}) error }) (domain.Order, error) {
+	var order domain.Order
+	err := row.Scan(
+		&order.OrderID,
+		&order.UserID,
+		&order.Status,
+		&order.IsFinal,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Version,
+		&order.Amount,
+		&order.Currency,
+		scanJSON(&order.Metadata),
 	)
+	return order, err
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to save order: %w", err)
+// sortColumns whitelists the columns that can appear in ORDER BY.
+var sortColumns = map[domain.SortField]string{
+	domain.SortByCreatedAt: "created_at",
+	domain.SortByUpdatedAt: "updated_at",
+	domain.SortByOrderID:   "order_id",
+	domain.SortByUserID:    "user_id",
+	domain.SortByStatus:    "status",
+}
+
+// orderByClause translates the sort into SQL, ignoring fields that are not
+// whitelisted. order_id is always the last key to make pagination deterministic.
+func orderByClause(sort domain.OrderSort) string {
+	if len(sort) == 0 {
+		sort = domain.DefaultOrderSort
 	}
 
-	return nil
+	var keys []string
+	for _, key := range sort.WithTieBreaker() {
+		column, ok := sortColumns[key.Field]
+		if !ok {
+			continue
+		}
+
+		direction := "ASC"
+		if key.Descending {
+			direction = "DESC"
+		}
+		keys = append(keys, column+" "+direction)
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+func toArgs[T any](values []T) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
 }
 
 var _ domain.OrderRepository = new(OrderRepository)
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func TestBuildQueryFilters(t *testing.T) {
+	repo := OrderRepository{}
+	from := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	query, args := repo.buildQuery(domain.NewOrderFilter(
+		domain.WithStatus(domain.Chinazes),
+		domain.WithUserIDs("user1", "user2"),
+		domain.WithOrderIDs("order1"),
+		domain.WithCreatedBetween(&from, &to),
+		domain.WithUpdatedBetween(&from, nil),
+		domain.WithEverInStatus(domain.SbuVerificationPending),
+		domain.WithStuckFor(15*time.Minute),
+		domain.WithNow(to),
+	))
+
+	expectedConditions := []string{
+		"status IN ($1)",
+		"user_id IN ($2,$3)",
+		"order_id IN ($4)",
+		"created_at >= $5",
+		"created_at < $6",
+		"updated_at >= $7",
+		"e.order_status IN ($8)",
+		"is_final = FALSE AND updated_at < $9",
+		"LIMIT $10 OFFSET $11",
+	}
+	for _, condition := range expectedConditions {
+		if !strings.Contains(query, condition) {
+			t.Errorf("Expected query to contain %q, got %s", condition, query)
+		}
+	}
+
+	if len(args) != 11 {
+		t.Fatalf("Expected 11 args, got %d: %v", len(args), args)
+	}
+
+	if stuckBefore := to.Add(-15 * time.Minute); args[8] != stuckBefore {
+		t.Errorf("Expected stuck_for to be relative to the filter's now %v, got %v", stuckBefore, args[8])
+	}
+}
+
+func TestBuildQueryWithoutLimit(t *testing.T) {
+	repo := OrderRepository{}
+
+	query, args := repo.buildQuery(domain.NewOrderFilter(domain.WithLimit(0)))
+
+	if strings.Contains(query, "LIMIT") || strings.Contains(query, "WHERE") {
+		t.Errorf("Expected unfiltered query without limit, got %s", query)
+	}
+
+	if len(args) != 0 {
+		t.Errorf("Expected no args, got %v", args)
+	}
+}
+
+func TestBuildQuerySort(t *testing.T) {
+	repo := OrderRepository{}
+
+	sort, err := domain.ParseOrderSort("-updated_at,status")
+	if err != nil {
+		t.Fatalf("Failed to parse sort: %v", err)
+	}
+
+	query, _ := repo.buildQuery(domain.NewOrderFilter(domain.WithSort(sort)))
+
+	if !strings.Contains(query, " ORDER BY updated_at DESC, status ASC, order_id ASC LIMIT") {
+		t.Errorf("Expected multi-column sort with order_id tie-breaker, got %s", query)
+	}
+
+	query, _ = repo.buildQuery(domain.NewOrderFilter())
+
+	if !strings.Contains(query, " ORDER BY created_at DESC, order_id ASC LIMIT") {
+		t.Errorf("Expected default sort, got %s", query)
+	}
+}
+
+// orderByPattern matches an ORDER BY clause built only from whitelisted columns.
+var orderByPattern = regexp.MustCompile(
+	`^SELECT [^;]+ FROM orders ORDER BY ((created_at|updated_at|order_id|user_id|status) (ASC|DESC), )*order_id (ASC|DESC) LIMIT \$1 OFFSET \$2$`,
+)
+
+func FuzzBuildQuerySort(f *testing.F) {
+	seeds := []string{
+		"created_at",
+		"-updated_at,order_id",
+		"status,-user_id",
+		"created_at; DROP TABLE orders",
+		"-created_at desc",
+		"(SELECT 1)",
+		"created_at,created_at",
+		",",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	repo := OrderRepository{}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		sort, err := domain.ParseOrderSort(input)
+		if err == nil {
+			query, args := repo.buildQuery(domain.NewOrderFilter(domain.WithSort(sort)))
+			if !orderByPattern.MatchString(query) {
+				t.Fatalf("Unexpected query for sort %q: %s", input, query)
+			}
+			if len(args) != 2 {
+				t.Fatalf("Expected only limit and offset args, got %v", args)
+			}
+		}
+
+		// Sort keys that bypassed parsing must never reach the query either
+		raw := domain.OrderSort{{Field: domain.SortField(input)}, {Field: domain.SortField(input), Descending: true}}
+		query, _ := repo.buildQuery(domain.NewOrderFilter(domain.WithSort(raw)))
+		if !orderByPattern.MatchString(query) {
+			t.Fatalf("Unexpected query for raw sort field %q: %s", input, query)
+		}
+	})
+}
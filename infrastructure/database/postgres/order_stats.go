@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// buildStatsFilter returns a CTE selecting orders matching the filter,
+// which the stats queries join against.
+func (r *OrderRepository) buildStatsFilter(filter *domain.OrderStatsFilter) (string, []interface{}) {
+	var args []interface{}
+	var conditions []string
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	cte := `WITH filtered AS (SELECT order_id, status, created_at, amount, currency FROM orders`
+	if len(conditions) > 0 {
+		cte += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	cte += ")"
+
+	return cte, args
+}
+
+func (r OrderRepository) Stats(filter *domain.OrderStatsFilter) (*domain.OrderStats, error) {
+	cte, args := r.buildStatsFilter(filter)
+	stats := &domain.OrderStats{GroupBy: filter.GroupBy}
+
+	if err := r.statsTotals(cte, args, stats); err != nil {
+		return nil, err
+	}
+
+	groups, err := r.statsGroups(cte, args, filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	stats.Groups = groups
+
+	transitions, err := r.statsTransitions(cte, args)
+	if err != nil {
+		return nil, err
+	}
+	stats.Transitions = transitions
+
+	amounts, err := r.statsAmounts(cte, args)
+	if err != nil {
+		return nil, err
+	}
+	stats.Amounts = amounts
+
+	stats.CalculateRates()
+
+	return stats, nil
+}
+
+func (r OrderRepository) statsTotals(cte string, args []interface{}, stats *domain.OrderStats) error {
+	n := len(args)
+	query := cte + fmt.Sprintf(`,
+		reached AS (
+			SELECT e.order_id,
+			       bool_or(e.order_status = $%d) AS chinazes,
+			       bool_or(e.order_status = $%d) AS refunded,
+			       bool_or(e.order_status IN ($%d, $%d)) AS canceled
+			FROM order_events e
+			JOIN filtered f ON f.order_id = e.order_id
+			WHERE (e.rejected_reason IS NULL OR e.rejected_reason = '')
+			GROUP BY e.order_id
+		)
+		SELECT (SELECT COUNT(*) FROM filtered),
+		       COUNT(*) FILTER (WHERE chinazes),
+		       COUNT(*) FILTER (WHERE refunded),
+		       COUNT(*) FILTER (WHERE canceled)
+		FROM reached`, n+1, n+2, n+3, n+4)
+
+	args = append(args, domain.Chinazes, domain.GiveMyMoneyBack, domain.ChangedMyMind, domain.Failed)
+
+	err := r.replica.QueryRow(query, args...).Scan(&stats.Total, &stats.Chinazes, &stats.Refunded, &stats.Canceled)
+	if err != nil {
+		return fmt.Errorf("failed to query order totals: %w", err)
+	}
+
+	return nil
+}
+
+func (r OrderRepository) statsGroups(cte string, args []interface{}, groupBy domain.StatsGroupBy) ([]domain.StatsGroup, error) {
+	var query string
+	switch groupBy {
+	case domain.GroupByDay, domain.GroupByHour:
+		// groupBy is validated by domain.ParseStatsGroupBy, so it is safe to inline
+		query = cte + fmt.Sprintf(`
+			SELECT date_trunc('%s', created_at, 'UTC') AS bucket, COUNT(*)
+			FROM filtered
+			GROUP BY bucket
+			ORDER BY bucket`, groupBy)
+	default:
+		query = cte + `
+			SELECT status, COUNT(*)
+			FROM filtered
+			GROUP BY status`
+	}
+
+	rows, err := r.replica.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order groups: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	groups := []domain.StatsGroup{}
+	for rows.Next() {
+		var group domain.StatsGroup
+		if groupBy == domain.GroupByDay || groupBy == domain.GroupByHour {
+			var bucket time.Time
+			if err := rows.Scan(&bucket, &group.Count); err != nil {
+				return nil, fmt.Errorf("failed to scan order group: %w", err)
+			}
+			group.Key = bucket.UTC().Format(time.RFC3339)
+		} else {
+			if err := rows.Scan(&group.Key, &group.Count); err != nil {
+				return nil, fmt.Errorf("failed to scan order group: %w", err)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order groups: %w", err)
+	}
+
+	if groupBy == domain.GroupByStatus {
+		sort.SliceStable(groups, func(i, j int) bool {
+			return domain.OrderStatus(groups[i].Key).Value() < domain.OrderStatus(groups[j].Key).Value()
+		})
+	}
+
+	return groups, nil
+}
+
+func (r OrderRepository) statsTransitions(cte string, args []interface{}) ([]domain.TransitionStats, error) {
+	query := cte + `,
+		transitions AS (
+			SELECT LAG(e.order_status) OVER w AS from_status,
+			       e.order_status AS to_status,
+			       EXTRACT(EPOCH FROM e.created_at - LAG(e.created_at) OVER w) AS seconds
+			FROM order_events e
+			JOIN filtered f ON f.order_id = e.order_id
+			WHERE (e.rejected_reason IS NULL OR e.rejected_reason = '')
+			WINDOW w AS (PARTITION BY e.order_id ORDER BY e.created_at)
+		)
+		SELECT from_status, to_status, COUNT(*),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds)
+		FROM transitions
+		WHERE from_status IS NOT NULL
+		GROUP BY from_status, to_status`
+
+	rows, err := r.replica.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status transitions: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	transitions := []domain.TransitionStats{}
+	for rows.Next() {
+		var t domain.TransitionStats
+		if err := rows.Scan(&t.From, &t.To, &t.Count, &t.MedianSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan status transition: %w", err)
+		}
+		transitions = append(transitions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over status transitions: %w", err)
+	}
+
+	sort.SliceStable(transitions, func(i, j int) bool {
+		if transitions[i].From.Value() != transitions[j].From.Value() {
+			return transitions[i].From.Value() < transitions[j].From.Value()
+		}
+		return transitions[i].To.Value() < transitions[j].To.Value()
+	})
+
+	return transitions, nil
+}
+
+func (r OrderRepository) statsAmounts(cte string, args []interface{}) ([]domain.CurrencyTotal, error) {
+	query := cte + `
+		SELECT currency, COUNT(*), SUM(amount)
+		FROM filtered
+		WHERE amount IS NOT NULL
+		GROUP BY currency
+		ORDER BY currency`
+
+	rows, err := r.replica.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order amounts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	amounts := []domain.CurrencyTotal{}
+	for rows.Next() {
+		var total domain.CurrencyTotal
+		if err := rows.Scan(&total.Currency, &total.Count, &total.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan order amount: %w", err)
+		}
+		amounts = append(amounts, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order amounts: %w", err)
+	}
+
+	return amounts, nil
+}
+
+var _ domain.OrderStatsRepository = new(OrderRepository)
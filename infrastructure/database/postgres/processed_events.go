@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.ProcessedEvents = new(ProcessedEvents)
+
+// ProcessedEvents keeps events being processed in the processing_events table,
+// so all instances see the same set. Claims older than staleAfter are left
+// by crashed instances and can be taken over.
+type ProcessedEvents struct {
+	db         *sql.DB
+	staleAfter time.Duration
+}
+
+func NewProcessedEvents(db *sql.DB, staleAfter time.Duration) ProcessedEvents {
+	return ProcessedEvents{db: db, staleAfter: staleAfter}
+}
+
+func (p ProcessedEvents) Add(eventID string) (bool, error) {
+	query := `
+		INSERT INTO processing_events (event_id, started_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (event_id) DO UPDATE
+		SET started_at = EXCLUDED.started_at
+		WHERE processing_events.started_at < NOW() - $2 * INTERVAL '1 second'
+	`
+
+	result, err := p.db.Exec(query, eventID, p.staleAfter.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (p ProcessedEvents) Remove(eventID string) error {
+	if _, err := p.db.Exec(`DELETE FROM processing_events WHERE event_id = $1`, eventID); err != nil {
+		return fmt.Errorf("failed to release event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.ReconciliationAuditRepository = new(ReconciliationRepository)
+
+type ReconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) ReconciliationRepository {
+	return ReconciliationRepository{db: db}
+}
+
+func (r ReconciliationRepository) Add(correction domain.ReconciliationCorrection) error {
+	query := `
+		INSERT INTO reconciliation_corrections (run_id, event_id, order_id, status, source, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(
+		query,
+		correction.RunID,
+		correction.EventID,
+		correction.OrderID,
+		correction.Status,
+		correction.Source,
+		correction.Error,
+		correction.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add reconciliation correction: %w", err)
+	}
+
+	return nil
+}
+
+func (r ReconciliationRepository) List(filter domain.ReconciliationCorrectionFilter) ([]domain.ReconciliationCorrection, error) {
+	query := `SELECT run_id, event_id, order_id, status, source, error, created_at
+			  FROM reconciliation_corrections`
+	var args []interface{}
+	var conditions []string
+
+	if filter.RunID != "" {
+		args = append(args, filter.RunID)
+		conditions = append(conditions, fmt.Sprintf("run_id = $%d", len(args)))
+	}
+	if filter.OrderID != "" {
+		args = append(args, filter.OrderID)
+		conditions = append(conditions, fmt.Sprintf("order_id = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at DESC, run_id, event_id"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation corrections: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	var corrections []domain.ReconciliationCorrection
+	for rows.Next() {
+		var correction domain.ReconciliationCorrection
+		err := rows.Scan(
+			&correction.RunID,
+			&correction.EventID,
+			&correction.OrderID,
+			&correction.Status,
+			&correction.Source,
+			&correction.Error,
+			&correction.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation correction: %w", err)
+		}
+		corrections = append(corrections, correction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return corrections, nil
+}
@@ -0,0 +1,258 @@
+package postgres_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/therealyo/justdone/infrastructure/database/postgres"
+	"github.com/therealyo/justdone/internal/repotest"
+)
+
+const migrationsDir = "../../../migrations"
+
+// errUnavailable marks a test database that can't be started in this environment.
+var errUnavailable = errors.New("test database is not available")
+
+// testDatabase is started by the first test that needs it and stopped in TestMain.
+var testDatabase struct {
+	once sync.Once
+	db   *sql.DB
+	stop func()
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testDatabase.stop != nil {
+		testDatabase.stop()
+	}
+	os.Exit(code)
+}
+
+// TestRepositoryConformance runs the shared repository suite against Postgres.
+func TestRepositoryConformance(t *testing.T) {
+	db := openTestDatabase(t)
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		if _, err := db.Exec(`TRUNCATE orders, order_events, dead_letter_events, reconciliation_corrections`); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+		return repotest.Repositories{
+			Orders:         postgres.NewOrderRepository(db),
+			Events:         postgres.NewEventRepository(db),
+			DeadLetters:    postgres.NewDeadLetterRepository(db),
+			Reconciliation: postgres.NewReconciliationRepository(db),
+			Stats:          postgres.NewOrderRepository(db),
+			Idempotency:    postgres.NewIdempotencyStore(db),
+			EstimatesCount: true,
+		}
+	})
+}
+
+func TestProcessedEvents(t *testing.T) {
+	db := openTestDatabase(t)
+	first := postgres.NewProcessedEvents(db, time.Hour)
+	second := postgres.NewProcessedEvents(db, time.Hour)
+	eventID := "20000000-0000-4000-8000-000000000001"
+
+	if claimed, err := first.Add(eventID); err != nil || !claimed {
+		t.Fatalf("Expected first instance to claim the event, got %v, %v", claimed, err)
+	}
+	if claimed, err := second.Add(eventID); err != nil || claimed {
+		t.Fatalf("Expected second instance not to claim a claimed event, got %v, %v", claimed, err)
+	}
+
+	if err := first.Remove(eventID); err != nil {
+		t.Fatalf("Failed to release event: %v", err)
+	}
+	if claimed, err := second.Add(eventID); err != nil || !claimed {
+		t.Fatalf("Expected released event to be claimed again, got %v, %v", claimed, err)
+	}
+
+	// A claim older than staleAfter is taken over
+	if claimed, err := postgres.NewProcessedEvents(db, 0).Add(eventID); err != nil || !claimed {
+		t.Fatalf("Expected stale claim to be taken over, got %v, %v", claimed, err)
+	}
+}
+
+func TestLocker(t *testing.T) {
+	db := openTestDatabase(t)
+	locker := postgres.NewLocker(db)
+
+	unlock, err := locker.Lock("00000000-0000-4000-8000-000000000001")
+	if err != nil {
+		t.Fatalf("Failed to lock order: %v", err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		unlockSecond, err := locker.Lock("00000000-0000-4000-8000-000000000001")
+		if err == nil {
+			unlockSecond()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("Expected second lock of the order to wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected second lock to be acquired after release")
+	}
+}
+
+// openTestDatabase returns the migrated test database. POSTGRES_TEST_URL
+// selects a disposable database whose tables are recreated, otherwise a local
+// server is started from embedded-postgres binaries. The binaries are read
+// from the POSTGRES_TEST_CACHE directory (~/.embedded-postgres-go by default)
+// and downloaded into it when missing. A database that can't be started fails
+// the test, it is only skipped when POSTGRES_TEST_SKIP is set.
+func openTestDatabase(t *testing.T) *sql.DB {
+	if testing.Short() {
+		t.Skip("skipping Postgres tests in short mode")
+	}
+
+	testDatabase.once.Do(func() {
+		testDatabase.db, testDatabase.stop, testDatabase.err = startTestDatabase()
+	})
+
+	if errors.Is(testDatabase.err, errUnavailable) {
+		if os.Getenv("POSTGRES_TEST_SKIP") != "" {
+			t.Skip(testDatabase.err)
+		}
+		t.Fatalf("%v (set POSTGRES_TEST_URL or POSTGRES_TEST_CACHE, or POSTGRES_TEST_SKIP=1 to skip)", testDatabase.err)
+	}
+	if testDatabase.err != nil {
+		t.Fatal(testDatabase.err)
+	}
+
+	return testDatabase.db
+}
+
+func startTestDatabase() (*sql.DB, func(), error) {
+	connection := os.Getenv("POSTGRES_TEST_URL")
+	stop := func() {}
+
+	if connection == "" {
+		port, err := freePort()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: no free port: %v", errUnavailable, err)
+		}
+
+		runtimePath, err := os.MkdirTemp("", "embedded-postgres")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		config := embeddedpostgres.DefaultConfig().
+			Port(port).
+			Locale("C").
+			RuntimePath(runtimePath).
+			Logger(io.Discard)
+		if cache := os.Getenv("POSTGRES_TEST_CACHE"); cache != "" {
+			config = config.CachePath(cache)
+		}
+		server := embeddedpostgres.NewDatabase(config)
+		if err := server.Start(); err != nil {
+			os.RemoveAll(runtimePath)
+			return nil, nil, fmt.Errorf("%w: %v", errUnavailable, err)
+		}
+		stop = func() {
+			if err := server.Stop(); err != nil {
+				fmt.Printf("error stopping embedded Postgres: %v\n", err)
+			}
+			os.RemoveAll(runtimePath)
+		}
+
+		connection = config.GetConnectionURL() + "?sslmode=disable"
+	}
+
+	db, err := postgres.New(connection, postgres.Retry{Attempts: 1})
+	if err == nil {
+		err = migrate(db)
+	}
+	if err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("failed to prepare test database: %w", err)
+	}
+
+	return db, func() {
+		db.Close()
+		stop()
+	}, nil
+}
+
+// migrate rolls back and reapplies all goose migrations, so the schema
+// matches the migrations even in a reused database.
+func migrate(db *sql.DB) error {
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no migrations found")
+	}
+	sort.Strings(files)
+
+	up := make([]string, len(files))
+	down := make([]string, len(files))
+	for i, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		up[i], down[i] = splitMigration(string(content))
+	}
+
+	for i := len(down) - 1; i >= 0; i-- {
+		if _, err := db.Exec(down[i]); err != nil {
+			return fmt.Errorf("roll back %s: %w", files[i], err)
+		}
+	}
+
+	for i := range up {
+		if _, err := db.Exec(up[i]); err != nil {
+			return fmt.Errorf("apply %s: %w", files[i], err)
+		}
+	}
+
+	return nil
+}
+
+// splitMigration returns the Up and Down sections of a goose migration.
+func splitMigration(content string) (string, string) {
+	content = strings.SplitN(content, "-- +goose Up", 2)[1]
+	sections := strings.SplitN(content, "-- +goose Down", 2)
+	if len(sections) == 1 {
+		return sections[0], ""
+	}
+	return sections[0], sections[1]
+}
+
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return uint32(listener.Addr().(*net.TCPAddr).Port), nil
+}
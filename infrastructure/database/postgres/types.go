@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonScanner scans a nullable JSON column into raw. The bytes are copied,
+// since the driver may reuse them for the next row.
+type jsonScanner struct {
+	raw *json.RawMessage
+}
+
+func scanJSON(raw *json.RawMessage) jsonScanner {
+	return jsonScanner{raw: raw}
+}
+
+func (s jsonScanner) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s.raw = nil
+	case []byte:
+		*s.raw = append(json.RawMessage(nil), v...)
+	case string:
+		*s.raw = json.RawMessage(v)
+	default:
+		return fmt.Errorf("unsupported json type %T", value)
+	}
+	return nil
+}
+
+// jsonValue stores empty JSON as NULL. JSON is sent as text, lib/pq would
+// encode []byte as bytea.
+func jsonValue(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// New opens the database file, creating it if needed, and applies pending
+// migrations. Foreign keys are enforced like in Postgres, and write
+// transactions take the lock up front so concurrent writers wait for
+// busy_timeout instead of failing.
+func New(path string) (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate",
+		path,
+	)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// migrate applies the Up sections of embedded goose migrations that were not
+// applied yet, each in its own transaction.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+
+		var applied int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		content, err := migrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		if err := applyMigration(db, version, upSection(string(content))); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(db *sql.DB, version, up string) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(up); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, formatTime(time.Now())); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// upSection returns the SQL between the goose Up and Down annotations.
+func upSection(content string) string {
+	if _, after, found := strings.Cut(content, "-- +goose Up"); found {
+		content = after
+	}
+	up, _, _ := strings.Cut(content, "-- +goose Down")
+	return up
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.DeadLetterRepository = new(DeadLetterRepository)
+
+type DeadLetterRepository struct {
+	db *sql.DB
+}
+
+func NewDeadLetterRepository(db *sql.DB) DeadLetterRepository {
+	return DeadLetterRepository{db: db}
+}
+
+func (r DeadLetterRepository) Add(event domain.OrderEvent, reason string) error {
+	eventID, err := normalizeUUID(event.EventID)
+	if err != nil {
+		return err
+	}
+
+	orderID, err := normalizeUUID(event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter event: %w", err)
+	}
+
+	query := `
+		INSERT INTO dead_letter_events (event_id, order_id, reason, payload, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (event_id) DO UPDATE
+		SET reason = excluded.reason,
+			payload = excluded.payload,
+			attempts = dead_letter_events.attempts + 1,
+			updated_at = excluded.updated_at
+	`
+
+	now := formatTime(time.Now())
+	if _, err := r.db.Exec(query, eventID, orderID, reason, string(payload), now, now); err != nil {
+		return fmt.Errorf("failed to add dead letter event: %w", err)
+	}
+
+	return nil
+}
+
+func (r DeadLetterRepository) Get(eventID string) (*domain.DeadLetterEvent, error) {
+	eventID, err := normalizeUUID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT event_id, order_id, reason, payload, attempts, created_at, updated_at
+			  FROM dead_letter_events WHERE event_id = ?`
+
+	event, err := scanDeadLetter(r.db.QueryRow(query, eventID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get dead letter event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (r DeadLetterRepository) List(filter domain.DeadLetterFilter) ([]domain.DeadLetterEvent, error) {
+	query := `SELECT event_id, order_id, reason, payload, attempts, created_at, updated_at
+			  FROM dead_letter_events`
+	var args []interface{}
+
+	if filter.OrderID != "" {
+		orderID, err := normalizeUUID(filter.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		query += " WHERE order_id = ?"
+		args = append(args, orderID)
+	}
+
+	query += " ORDER BY updated_at DESC, event_id"
+
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letter events: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	var events []domain.DeadLetterEvent
+	for rows.Next() {
+		event, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r DeadLetterRepository) Delete(eventIDs ...string) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+
+	args, err := normalizeUUIDs(eventIDs)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		`DELETE FROM dead_letter_events WHERE event_id IN (%s)`,
+		strings.TrimSuffix(strings.Repeat("?,", len(args)), ","),
+	)
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to delete dead letter events: %w", err)
+	}
+
+	return nil
+}
+
+func scanDeadLetter(row interface{ Scan(...interface{}) error }) (*domain.DeadLetterEvent, error) {
+	var event domain.DeadLetterEvent
+	var payload string
+
+	err := row.Scan(
+		&event.EventID,
+		&event.OrderID,
+		&event.Reason,
+		&payload,
+		&event.Attempts,
+		scanTime(&event.CreatedAt),
+		scanTime(&event.UpdatedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &event.Payload); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter event: %w", err)
+	}
+
+	return &event, nil
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/therealyo/justdone/domain"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type EventRepository struct {
+	db *sql.DB
+}
+
+func NewEventRepository(db *sql.DB) EventRepository {
+	return EventRepository{db: db}
+}
+
+func (r EventRepository) Get(eventID string) (*domain.OrderEvent, error) {
+	eventID, err := normalizeUUID(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT event_id, order_id, user_id, order_status, created_at, updated_at, is_final, rejected_reason,
+			  amount, COALESCE(currency, ''), metadata
+			  FROM order_events WHERE event_id = ?`
+
+	var event domain.OrderEvent
+	var rejectedReason sql.NullString
+
+	err = r.db.QueryRow(query, eventID).Scan(
+		&event.EventID,
+		&event.OrderID,
+		&event.UserID,
+		&event.OrderStatus,
+		scanTime(&event.CreatedAt),
+		scanTime(&event.UpdatedAt),
+		&event.IsFinal,
+		&rejectedReason,
+		&event.Amount,
+		&event.Currency,
+		scanJSON(&event.Metadata),
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	event.RejectedReason = rejectedReason.String
+
+	return &event, nil
+}
+
+func (r EventRepository) Create(event domain.OrderEvent) error {
+	ids, err := normalizeUUIDs([]string{event.EventID, event.OrderID, event.UserID})
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO order_events (event_id, order_id, user_id, order_status, created_at, updated_at, is_final, rejected_reason,
+			  amount, currency, metadata)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?)`
+
+	_, err = r.db.Exec(query,
+		ids[0],
+		ids[1],
+		ids[2],
+		event.OrderStatus,
+		formatTime(event.CreatedAt),
+		formatTime(event.UpdatedAt),
+		event.IsFinal,
+		event.RejectedReason,
+		event.Amount,
+		event.Currency,
+		jsonValue(event.Metadata),
+	)
+
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return domain.ErrEventConflict
+		}
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+
+	return nil
+}
+
+func (r EventRepository) Update(event domain.OrderEvent) error {
+	eventID, err := normalizeUUID(event.EventID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE order_events
+			  SET order_status = ?, is_final = ?, updated_at = ?, rejected_reason = NULLIF(?, '')
+			  WHERE event_id = ?`
+
+	_, err = r.db.Exec(query,
+		event.OrderStatus,
+		event.IsFinal,
+		formatTime(event.UpdatedAt),
+		event.RejectedReason,
+		eventID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	return nil
+}
+
+func (r EventRepository) Delete(eventID string) error {
+	eventID, err := normalizeUUID(eventID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`DELETE FROM order_events WHERE event_id = ?`, eventID)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	return nil
+}
+
+var _ domain.EventRepository = new(EventRepository)
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.IdempotencyStore = new(IdempotencyStore)
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+func NewIdempotencyStore(db *sql.DB) IdempotencyStore {
+	return IdempotencyStore{db: db}
+}
+
+func (s IdempotencyStore) Get(key string) (*domain.IdempotentResponse, error) {
+	query := `SELECT key, request_hash, status, headers, body, expires_at
+			  FROM idempotency_keys WHERE key = ? AND expires_at > ?`
+
+	var response domain.IdempotentResponse
+	var headers json.RawMessage
+	err := s.db.QueryRow(query, key, formatTime(time.Now())).Scan(
+		&response.Key,
+		&response.RequestHash,
+		&response.Status,
+		scanJSON(&headers),
+		&response.Body,
+		scanTime(&response.ExpiresAt),
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotent response: %w", err)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &response.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode idempotent response headers: %w", err)
+		}
+	}
+
+	return &response, nil
+}
+
+// Save stores the response, replacing an expired one for the same key,
+// and purges other expired responses.
+func (s IdempotencyStore) Save(response domain.IdempotentResponse) error {
+	var headers json.RawMessage
+	if len(response.Headers) > 0 {
+		encoded, err := json.Marshal(response.Headers)
+		if err != nil {
+			return fmt.Errorf("failed to encode idempotent response headers: %w", err)
+		}
+		headers = encoded
+	}
+
+	now := formatTime(time.Now())
+
+	if _, err := s.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= ? AND key <> ?`, now, response.Key); err != nil {
+		return fmt.Errorf("failed to purge idempotent responses: %w", err)
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (key, request_hash, status, headers, body, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE
+		SET request_hash = excluded.request_hash,
+			status = excluded.status,
+			headers = excluded.headers,
+			body = excluded.body,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at
+	`
+
+	_, err := s.db.Exec(query,
+		response.Key,
+		response.RequestHash,
+		response.Status,
+		jsonValue(headers),
+		response.Body,
+		now,
+		formatTime(response.ExpiresAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+-- UUIDs are stored as lower case text and timestamps as fixed width UTC text
+-- with microsecond precision, see types.go.
+CREATE TABLE orders (
+    order_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    is_final BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE order_events (
+    event_id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(order_id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    order_status TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    is_final BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+CREATE INDEX idx_orders_user_id ON orders(user_id);
+CREATE INDEX idx_order_events_order_id_created_at ON order_events(order_id, created_at);
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP TABLE IF EXISTS order_events;
+DROP TABLE IF EXISTS orders;
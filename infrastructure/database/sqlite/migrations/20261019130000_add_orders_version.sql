@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+ALTER TABLE orders DROP COLUMN version;
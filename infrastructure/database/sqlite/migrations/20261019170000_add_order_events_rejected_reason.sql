@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+ALTER TABLE order_events ADD COLUMN rejected_reason TEXT;
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+ALTER TABLE order_events DROP COLUMN rejected_reason;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+CREATE TABLE reconciliation_corrections (
+    run_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    order_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    source TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (run_id, event_id)
+);
+
+CREATE INDEX idx_reconciliation_corrections_order_id ON reconciliation_corrections(order_id);
+CREATE INDEX idx_reconciliation_corrections_created_at ON reconciliation_corrections(created_at DESC, run_id, event_id);
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP TABLE IF EXISTS reconciliation_corrections;
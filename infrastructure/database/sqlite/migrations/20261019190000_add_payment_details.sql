@@ -0,0 +1,25 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+ALTER TABLE orders ADD COLUMN amount INTEGER;
+ALTER TABLE orders ADD COLUMN currency TEXT;
+ALTER TABLE orders ADD COLUMN metadata TEXT;
+
+ALTER TABLE order_events ADD COLUMN amount INTEGER;
+ALTER TABLE order_events ADD COLUMN currency TEXT;
+ALTER TABLE order_events ADD COLUMN metadata TEXT;
+
+CREATE INDEX idx_orders_currency_amount ON orders(currency, amount) WHERE amount IS NOT NULL;
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP INDEX IF EXISTS idx_orders_currency_amount;
+
+ALTER TABLE order_events DROP COLUMN metadata;
+ALTER TABLE order_events DROP COLUMN currency;
+ALTER TABLE order_events DROP COLUMN amount;
+
+ALTER TABLE orders DROP COLUMN metadata;
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE orders DROP COLUMN amount;
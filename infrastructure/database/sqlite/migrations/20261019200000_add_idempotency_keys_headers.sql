@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+ALTER TABLE idempotency_keys ADD COLUMN headers TEXT;
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+ALTER TABLE idempotency_keys DROP COLUMN headers;
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// StreamOrders calls fn for every order matching the filter while reading rows
+// from the result cursor, so the whole result is never buffered.
+// Iteration stops at the first error returned by fn.
+func (r OrderRepository) StreamOrders(filter *domain.OrderFilter, fn func(domain.Order) error) error {
+	query, args, err := r.buildQuery(filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query orders: %w", err)
+	}
+
+	return streamRows(rows, func(rows *sql.Rows) error {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order row: %w", err)
+		}
+		return fn(order)
+	})
+}
+
+// StreamEvents calls fn for every event of the order in creation order.
+func (r OrderRepository) StreamEvents(orderID string, fn func(domain.OrderEvent) error) error {
+	orderID, err := normalizeUUID(orderID)
+	if err != nil {
+		return err
+	}
+
+	query := `SELECT event_id, order_id, user_id, order_status, created_at, updated_at, is_final, rejected_reason,
+			  amount, COALESCE(currency, ''), metadata
+			  FROM order_events WHERE order_id = ? ORDER BY created_at ASC, event_id ASC`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+
+	return streamRows(rows, func(rows *sql.Rows) error {
+		var event domain.OrderEvent
+		var rejectedReason sql.NullString
+		if err := rows.Scan(
+			&event.EventID,
+			&event.OrderID,
+			&event.UserID,
+			&event.OrderStatus,
+			scanTime(&event.CreatedAt),
+			scanTime(&event.UpdatedAt),
+			&event.IsFinal,
+			&rejectedReason,
+			&event.Amount,
+			&event.Currency,
+			scanJSON(&event.Metadata),
+		); err != nil {
+			return fmt.Errorf("failed to scan event row: %w", err)
+		}
+		event.RejectedReason = rejectedReason.String
+		return fn(event)
+	})
+}
+
+func streamRows(rows *sql.Rows, scan func(*sql.Rows) error) error {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return nil
+}
+
+var _ domain.OrderExportRepository = new(OrderRepository)
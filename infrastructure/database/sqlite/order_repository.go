@@ -0,0 +1,321 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+type OrderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) OrderRepository {
+	return OrderRepository{db: db}
+}
+
+// buildWhere translates the filter conditions into a WHERE clause with
+// positional placeholders in the order of the returned args.
+func (r *OrderRepository) buildWhere(filter *domain.OrderFilter) (string, []interface{}, error) {
+	var where string
+	var args []interface{}
+	var conditions []string
+
+	// placeholders appends values to args and returns their placeholders
+	placeholders := func(values ...interface{}) string {
+		args = append(args, values...)
+		return strings.TrimSuffix(strings.Repeat("?,", len(values)), ",")
+	}
+
+	if len(filter.Status) > 0 {
+		conditions = append(conditions, fmt.Sprintf("status IN (%s)", placeholders(toArgs(filter.Status)...)))
+	}
+
+	if len(filter.UserIDs) > 0 {
+		userIDs, err := normalizeUUIDs(filter.UserIDs)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("user_id IN (%s)", placeholders(userIDs...)))
+	}
+
+	if len(filter.OrderIDs) > 0 {
+		orderIDs, err := normalizeUUIDs(filter.OrderIDs)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("order_id IN (%s)", placeholders(orderIDs...)))
+	}
+
+	if filter.IsFinal != nil {
+		conditions = append(conditions, fmt.Sprintf("is_final = %s", placeholders(*filter.IsFinal)))
+	}
+
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= %s", placeholders(formatTime(*filter.CreatedFrom))))
+	}
+
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < %s", placeholders(formatTime(*filter.CreatedTo))))
+	}
+
+	if filter.UpdatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("updated_at >= %s", placeholders(formatTime(*filter.UpdatedFrom))))
+	}
+
+	if filter.UpdatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("updated_at < %s", placeholders(formatTime(*filter.UpdatedTo))))
+	}
+
+	if len(filter.EverInStatus) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM order_events e WHERE e.order_id = orders.order_id AND e.order_status IN (%s)"+
+				" AND (e.rejected_reason IS NULL OR e.rejected_reason = ''))",
+			placeholders(toArgs(filter.EverInStatus)...),
+		))
+	}
+
+	if filter.AmountFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("amount >= %s", placeholders(*filter.AmountFrom)))
+	}
+
+	if filter.AmountTo != nil {
+		conditions = append(conditions, fmt.Sprintf("amount <= %s", placeholders(*filter.AmountTo)))
+	}
+
+	if len(filter.Currencies) > 0 {
+		conditions = append(conditions, fmt.Sprintf("currency IN (%s)", placeholders(toArgs(filter.Currencies)...)))
+	}
+
+	if filter.StuckFor > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"is_final = FALSE AND updated_at < %s",
+			placeholders(formatTime(filter.StuckBefore())),
+		))
+	}
+
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return where, args, nil
+}
+
+func (r *OrderRepository) buildQuery(filter *domain.OrderFilter) (string, []interface{}, error) {
+	where, args, err := r.buildWhere(filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM orders` + where
+	query += " ORDER BY " + orderByClause(filter.Sort)
+
+	// A non-positive limit selects all matching orders, which is used by exports
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	return query, args, nil
+}
+
+func (r OrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, error) {
+	var orders []domain.Order
+
+	err := r.StreamOrders(filter, func(order domain.Order) error {
+		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Count always counts exactly, SQLite has no planner estimates to fall back to.
+func (r OrderRepository) Count(filter *domain.OrderFilter, exactLimit int) (*domain.OrderCount, error) {
+	where, args, err := r.buildWhere(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM orders`+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	return &domain.OrderCount{Total: total}, nil
+}
+
+func (r OrderRepository) Get(orderID string) (*domain.Order, error) {
+	orderID, err := normalizeUUID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := scanOrder(r.db.QueryRow(`SELECT `+orderColumns+` FROM orders WHERE order_id = ?`, orderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	err = r.StreamEvents(orderID, func(event domain.OrderEvent) error {
+		order.Events = append(order.Events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order.Events) > 0 {
+		order.LastEvent = &order.Events[len(order.Events)-1]
+	}
+
+	return &order, nil
+}
+
+// Save inserts a new order or updates a stored one if its version did not change.
+func (r OrderRepository) Save(order *domain.Order) error {
+	orderID, err := normalizeUUID(order.OrderID)
+	if err != nil {
+		return err
+	}
+
+	userID, err := normalizeUUID(order.UserID)
+	if err != nil {
+		return err
+	}
+
+	var result sql.Result
+	if order.Version == 0 {
+		query := `
+			INSERT INTO orders (order_id, user_id, status, is_final, created_at, updated_at, version, amount, currency, metadata)
+			VALUES (?, ?, ?, ?, ?, ?, 1, ?, NULLIF(?, ''), ?)
+			ON CONFLICT (order_id) DO NOTHING
+		`
+		result, err = r.db.Exec(query,
+			orderID,
+			userID,
+			order.Status,
+			order.IsFinal,
+			formatTime(order.CreatedAt),
+			formatTime(order.UpdatedAt),
+			order.Amount,
+			order.Currency,
+			jsonValue(order.Metadata),
+		)
+	} else {
+		query := `
+			UPDATE orders
+			SET user_id = ?,
+				status = ?,
+				is_final = ?,
+				created_at = ?,
+				updated_at = ?,
+				amount = ?,
+				currency = NULLIF(?, ''),
+				metadata = ?,
+				version = version + 1
+			WHERE order_id = ? AND version = ?
+		`
+		result, err = r.db.Exec(query,
+			userID,
+			order.Status,
+			order.IsFinal,
+			formatTime(order.CreatedAt),
+			formatTime(order.UpdatedAt),
+			order.Amount,
+			order.Currency,
+			jsonValue(order.Metadata),
+			orderID,
+			order.Version,
+		)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to save order: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save order: %w", err)
+	}
+
+	// The order already exists or its version changed since it was read
+	if affected == 0 {
+		return domain.ErrConcurrentModification
+	}
+
+	order.Version++
+	return nil
+}
+
+const orderColumns = `order_id, user_id, status, is_final, created_at, updated_at, version,
+	amount, COALESCE(currency, ''), metadata`
+
+func scanOrder(row interface{ Scan(...interface{}) error }) (domain.Order, error) {
+	var order domain.Order
+	err := row.Scan(
+		&order.OrderID,
+		&order.UserID,
+		&order.Status,
+		&order.IsFinal,
+		scanTime(&order.CreatedAt),
+		scanTime(&order.UpdatedAt),
+		&order.Version,
+		&order.Amount,
+		&order.Currency,
+		scanJSON(&order.Metadata),
+	)
+	return order, err
+}
+
+// sortColumns whitelists the columns that can appear in ORDER BY.
+var sortColumns = map[domain.SortField]string{
+	domain.SortByCreatedAt: "created_at",
+	domain.SortByUpdatedAt: "updated_at",
+	domain.SortByOrderID:   "order_id",
+	domain.SortByUserID:    "user_id",
+	domain.SortByStatus:    "status",
+}
+
+// orderByClause translates the sort into SQL, ignoring fields that are not
+// whitelisted. order_id is always the last key to make pagination deterministic.
+func orderByClause(sort domain.OrderSort) string {
+	if len(sort) == 0 {
+		sort = domain.DefaultOrderSort
+	}
+
+	var keys []string
+	for _, key := range sort.WithTieBreaker() {
+		column, ok := sortColumns[key.Field]
+		if !ok {
+			continue
+		}
+		direction := "ASC"
+		if key.Descending {
+			direction = "DESC"
+		}
+		keys = append(keys, column+" "+direction)
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+func toArgs[T any](values []T) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+var _ domain.OrderRepository = new(OrderRepository)
@@ -0,0 +1,266 @@
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// buildStatsFilter returns a CTE selecting orders matching the filter,
+// which the stats queries join against.
+func (r *OrderRepository) buildStatsFilter(filter *domain.OrderStatsFilter) (string, []interface{}, error) {
+	var args []interface{}
+	var conditions []string
+
+	if filter.From != nil {
+		args = append(args, formatTime(*filter.From))
+		conditions = append(conditions, "created_at >= ?")
+	}
+
+	if filter.To != nil {
+		args = append(args, formatTime(*filter.To))
+		conditions = append(conditions, "created_at < ?")
+	}
+
+	if filter.UserID != "" {
+		userID, err := normalizeUUID(filter.UserID)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, userID)
+		conditions = append(conditions, "user_id = ?")
+	}
+
+	cte := `WITH filtered AS (SELECT order_id, status, created_at, amount, currency FROM orders`
+	if len(conditions) > 0 {
+		cte += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	cte += ")"
+
+	return cte, args, nil
+}
+
+func (r OrderRepository) Stats(filter *domain.OrderStatsFilter) (*domain.OrderStats, error) {
+	cte, args, err := r.buildStatsFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	stats := &domain.OrderStats{GroupBy: filter.GroupBy}
+
+	if err := r.statsTotals(cte, args, stats); err != nil {
+		return nil, err
+	}
+
+	groups, err := r.statsGroups(cte, args, filter.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	stats.Groups = groups
+
+	transitions, err := r.statsTransitions(cte, args)
+	if err != nil {
+		return nil, err
+	}
+	stats.Transitions = transitions
+
+	amounts, err := r.statsAmounts(cte, args)
+	if err != nil {
+		return nil, err
+	}
+	stats.Amounts = amounts
+
+	stats.CalculateRates()
+
+	return stats, nil
+}
+
+func (r OrderRepository) statsTotals(cte string, args []interface{}, stats *domain.OrderStats) error {
+	query := cte + `
+		SELECT (SELECT COUNT(*) FROM filtered),
+		       COUNT(DISTINCT CASE WHEN e.order_status = ? THEN e.order_id END),
+		       COUNT(DISTINCT CASE WHEN e.order_status = ? THEN e.order_id END),
+		       COUNT(DISTINCT CASE WHEN e.order_status IN (?, ?) THEN e.order_id END)
+		FROM order_events e
+		JOIN filtered f ON f.order_id = e.order_id
+		WHERE (e.rejected_reason IS NULL OR e.rejected_reason = '')`
+
+	args = append(args, domain.Chinazes, domain.GiveMyMoneyBack, domain.ChangedMyMind, domain.Failed)
+
+	err := r.db.QueryRow(query, args...).Scan(&stats.Total, &stats.Chinazes, &stats.Refunded, &stats.Canceled)
+	if err != nil {
+		return fmt.Errorf("failed to query order totals: %w", err)
+	}
+
+	return nil
+}
+
+func (r OrderRepository) statsGroups(cte string, args []interface{}, groupBy domain.StatsGroupBy) ([]domain.StatsGroup, error) {
+	// Timestamps are stored in UTC using timeLayout, so buckets are prefixes
+	// completed to RFC3339
+	var key string
+	switch groupBy {
+	case domain.GroupByDay:
+		key = `substr(created_at, 1, 10) || 'T00:00:00Z'`
+	case domain.GroupByHour:
+		key = `substr(created_at, 1, 13) || ':00:00Z'`
+	default:
+		key = `status`
+	}
+
+	query := cte + fmt.Sprintf(`
+		SELECT %s AS bucket, COUNT(*)
+		FROM filtered
+		GROUP BY bucket
+		ORDER BY bucket`, key)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order groups: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	groups := []domain.StatsGroup{}
+	for rows.Next() {
+		var group domain.StatsGroup
+		if err := rows.Scan(&group.Key, &group.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan order group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order groups: %w", err)
+	}
+
+	if groupBy == domain.GroupByStatus {
+		sort.SliceStable(groups, func(i, j int) bool {
+			return domain.OrderStatus(groups[i].Key).Value() < domain.OrderStatus(groups[j].Key).Value()
+		})
+	}
+
+	return groups, nil
+}
+
+type transitionKey struct {
+	from domain.OrderStatus
+	to   domain.OrderStatus
+}
+
+// statsTransitions pairs consecutive events with LAG. SQLite has no
+// percentile_cont, so the medians are calculated here.
+func (r OrderRepository) statsTransitions(cte string, args []interface{}) ([]domain.TransitionStats, error) {
+	query := cte + `,
+		transitions AS (
+			SELECT LAG(e.order_status) OVER w AS from_status,
+			       e.order_status AS to_status,
+			       LAG(e.created_at) OVER w AS from_time,
+			       e.created_at AS to_time
+			FROM order_events e
+			JOIN filtered f ON f.order_id = e.order_id
+			WHERE (e.rejected_reason IS NULL OR e.rejected_reason = '')
+			WINDOW w AS (PARTITION BY e.order_id ORDER BY e.created_at)
+		)
+		SELECT from_status, to_status, from_time, to_time
+		FROM transitions
+		WHERE from_status IS NOT NULL`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status transitions: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	durations := make(map[transitionKey][]float64)
+	for rows.Next() {
+		var key transitionKey
+		var from, to time.Time
+		if err := rows.Scan(&key.from, &key.to, scanTime(&from), scanTime(&to)); err != nil {
+			return nil, fmt.Errorf("failed to scan status transition: %w", err)
+		}
+		durations[key] = append(durations[key], to.Sub(from).Seconds())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over status transitions: %w", err)
+	}
+
+	transitions := make([]domain.TransitionStats, 0, len(durations))
+	for key, values := range durations {
+		transitions = append(transitions, domain.TransitionStats{
+			From:          key.from,
+			To:            key.to,
+			Count:         len(values),
+			MedianSeconds: median(values),
+		})
+	}
+
+	sort.Slice(transitions, func(i, j int) bool {
+		if transitions[i].From.Value() != transitions[j].From.Value() {
+			return transitions[i].From.Value() < transitions[j].From.Value()
+		}
+		return transitions[i].To.Value() < transitions[j].To.Value()
+	})
+
+	return transitions, nil
+}
+
+// median interpolates between the middle values like percentile_cont(0.5).
+func median(values []float64) float64 {
+	sort.Float64s(values)
+
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+func (r OrderRepository) statsAmounts(cte string, args []interface{}) ([]domain.CurrencyTotal, error) {
+	query := cte + `
+		SELECT currency, COUNT(*), SUM(amount)
+		FROM filtered
+		WHERE amount IS NOT NULL
+		GROUP BY currency
+		ORDER BY currency`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order amounts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	amounts := []domain.CurrencyTotal{}
+	for rows.Next() {
+		var total domain.CurrencyTotal
+		if err := rows.Scan(&total.Currency, &total.Count, &total.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan order amount: %w", err)
+		}
+		amounts = append(amounts, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over order amounts: %w", err)
+	}
+
+	return amounts, nil
+}
+
+var _ domain.OrderStatsRepository = new(OrderRepository)
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.ReconciliationAuditRepository = new(ReconciliationRepository)
+
+type ReconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) ReconciliationRepository {
+	return ReconciliationRepository{db: db}
+}
+
+func (r ReconciliationRepository) Add(correction domain.ReconciliationCorrection) error {
+	ids, err := normalizeUUIDs([]string{correction.RunID, correction.EventID, correction.OrderID})
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO reconciliation_corrections (run_id, event_id, order_id, status, source, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	args := append(ids, correction.Status, correction.Source, correction.Error, formatTime(correction.CreatedAt))
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to add reconciliation correction: %w", err)
+	}
+
+	return nil
+}
+
+func (r ReconciliationRepository) List(filter domain.ReconciliationCorrectionFilter) ([]domain.ReconciliationCorrection, error) {
+	query := `SELECT run_id, event_id, order_id, status, source, error, created_at
+			  FROM reconciliation_corrections`
+	var args []interface{}
+	var conditions []string
+
+	if filter.RunID != "" {
+		runID, err := normalizeUUID(filter.RunID)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "run_id = ?")
+		args = append(args, runID)
+	}
+	if filter.OrderID != "" {
+		orderID, err := normalizeUUID(filter.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, "order_id = ?")
+		args = append(args, orderID)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at DESC, run_id, event_id"
+
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reconciliation corrections: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Printf("error closing rows: %v\n", err)
+		}
+	}()
+
+	var corrections []domain.ReconciliationCorrection
+	for rows.Next() {
+		var correction domain.ReconciliationCorrection
+		err := rows.Scan(
+			&correction.RunID,
+			&correction.EventID,
+			&correction.OrderID,
+			&correction.Status,
+			&correction.Source,
+			&correction.Error,
+			scanTime(&correction.CreatedAt),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation correction: %w", err)
+		}
+		corrections = append(corrections, correction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over rows: %w", err)
+	}
+
+	return corrections, nil
+}
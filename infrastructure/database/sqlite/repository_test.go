@@ -0,0 +1,79 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/infrastructure/database/sqlite"
+	"github.com/therealyo/justdone/internal/repotest"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		db, err := sqlite.New(filepath.Join(t.TempDir(), "justdone.db"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return repotest.Repositories{
+			Orders:         sqlite.NewOrderRepository(db),
+			Events:         sqlite.NewEventRepository(db),
+			DeadLetters:    sqlite.NewDeadLetterRepository(db),
+			Reconciliation: sqlite.NewReconciliationRepository(db),
+			Stats:          sqlite.NewOrderRepository(db),
+			Idempotency:    sqlite.NewIdempotencyStore(db),
+		}
+	})
+}
+
+func TestPostgresSemantics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "justdone.db")
+	db, err := sqlite.New(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	orders := sqlite.NewOrderRepository(db)
+
+	orderID := "A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11"
+	createdAt := time.Date(2024, 8, 1, 12, 0, 0, 123456789, time.FixedZone("EEST", 3*60*60))
+	err = orders.Save(&domain.Order{
+		OrderID:   orderID,
+		UserID:    "{b0eebc999c0b4ef8bb6d6bb9bd380a11}",
+		Status:    domain.CoolOrderCreated,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to save order: %v", err)
+	}
+
+	if _, err := orders.Get("not-a-uuid"); err == nil {
+		t.Error("Expected error for invalid UUID")
+	}
+
+	// Reopening applies no migrations twice
+	db.Close()
+	db, err = sqlite.New(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	order, err := sqlite.NewOrderRepository(db).Get(strings.ToLower(orderID))
+	if err != nil || order == nil {
+		t.Fatalf("Expected order to be found, got %v, %v", order, err)
+	}
+
+	if order.OrderID != "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11" || order.UserID != "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11" {
+		t.Errorf("Expected canonical lower case UUIDs, got %s and %s", order.OrderID, order.UserID)
+	}
+
+	expected := time.Date(2024, 8, 1, 9, 0, 0, 123457000, time.UTC)
+	if !order.CreatedAt.Equal(expected) || order.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected created_at %v rounded to microseconds, got %v", expected, order.CreatedAt)
+	}
+}
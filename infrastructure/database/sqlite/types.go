@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errInvalidUUID = errors.New("invalid input syntax for type uuid")
+
+// timeLayout mirrors Postgres timestamptz: UTC with microsecond precision.
+// The fixed width keeps the lexicographic order of stored values chronological,
+// so timestamps can be compared and sorted as text.
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Round(time.Microsecond).Format(timeLayout)
+}
+
+// timeScanner scans a stored timestamp into t.
+type timeScanner struct {
+	t *time.Time
+}
+
+func scanTime(t *time.Time) timeScanner {
+	return timeScanner{t: t}
+}
+
+func (s timeScanner) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported timestamp type %T", value)
+	}
+
+	parsed, err := time.Parse(timeLayout, raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	*s.t = parsed
+
+	return nil
+}
+
+// jsonScanner scans a nullable JSON column into raw. The bytes are copied,
+// since the driver may reuse them for the next row.
+type jsonScanner struct {
+	raw *json.RawMessage
+}
+
+func scanJSON(raw *json.RawMessage) jsonScanner {
+	return jsonScanner{raw: raw}
+}
+
+func (s jsonScanner) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s.raw = nil
+	case []byte:
+		*s.raw = append(json.RawMessage(nil), v...)
+	case string:
+		*s.raw = json.RawMessage(v)
+	default:
+		return fmt.Errorf("unsupported json type %T", value)
+	}
+	return nil
+}
+
+// jsonValue stores empty JSON as NULL.
+func jsonValue(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// normalizeUUID validates a UUID and returns it in the lower case hyphenated
+// form Postgres uses for uuid values. Like Postgres it accepts upper case,
+// braces and missing hyphens.
+func normalizeUUID(value string) (string, error) {
+	hex := strings.ToLower(strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}"), "-", ""))
+	if len(hex) != 32 {
+		return "", fmt.Errorf("%w: %q", errInvalidUUID, value)
+	}
+	for _, c := range hex {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", fmt.Errorf("%w: %q", errInvalidUUID, value)
+		}
+	}
+
+	return hex[0:8] + "-" + hex[8:12] + "-" + hex[12:16] + "-" + hex[16:20] + "-" + hex[20:32], nil
+}
+
+func normalizeUUIDs(values []string) ([]interface{}, error) {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		id, err := normalizeUUID(value)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = id
+	}
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/internal/auth"
+)
+
+const principalKey = "principal"
+
+// errBackofficeDisabled rejects back office requests while authentication is disabled,
+// since nobody could be told apart from the back office.
+var errBackofficeDisabled = errors.New("back office endpoints require AUTH_ENABLED=true")
+
+type authMiddleware struct {
+	auth *auth.Authenticator
+}
+
+// handle authenticates the request with either the X-API-Key header
+// or an Authorization: Bearer token and stores the principal in the context.
+func (m authMiddleware) handle(c *gin.Context) {
+	var (
+		principal *auth.Principal
+		err       = auth.ErrUnauthenticated
+	)
+
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		principal, err = m.auth.AuthenticateAPIKey(key)
+	} else if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		principal, err = m.auth.AuthenticateToken(strings.TrimPrefix(header, "Bearer "))
+	}
+
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="justdone"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+// accessPolicy decides which orders the caller of a read endpoint may see.
+// A nil authenticator means authentication is disabled and everything is visible.
+type accessPolicy struct {
+	auth *auth.Authenticator
+}
+
+// restrictedUser returns the user the caller is limited to,
+// or an empty string if the caller may read orders of any user.
+func (p accessPolicy) restrictedUser(c *gin.Context) string {
+	if p.auth == nil {
+		return ""
+	}
+
+	principal := principalFromContext(c)
+	if p.auth.IsBackoffice(principal) {
+		return ""
+	}
+	return principal.UserID
+}
+
+func (p accessPolicy) canAccess(c *gin.Context, userID string) bool {
+	if p.auth == nil {
+		return true
+	}
+	return p.auth.CanAccess(principalFromContext(c), userID)
+}
+
+func principalFromContext(c *gin.Context) *auth.Principal {
+	if value, ok := c.Get(principalKey); ok {
+		if principal, ok := value.(*auth.Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// backofficeMiddleware lets only backoffice principals through,
+// it must run after the auth middleware.
+type backofficeMiddleware struct {
+	auth *auth.Authenticator
+}
+
+func (m backofficeMiddleware) handle(c *gin.Context) {
+	if !m.auth.IsBackoffice(principalFromContext(c)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+		return
+	}
+	c.Next()
+}
+
+func newBackofficeMiddleware(authenticator *auth.Authenticator) gin.HandlerFunc {
+	if authenticator == nil {
+		return func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errBackofficeDisabled.Error()})
+		}
+	}
+	return backofficeMiddleware{auth: authenticator}.handle
+}
+
+func newAuthMiddleware(authenticator *auth.Authenticator) gin.HandlerFunc {
+	if authenticator == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return authMiddleware{auth: authenticator}.handle
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type discardDeadLetterHandler struct {
+	deadLetters usecase.DeadLetters
+}
+
+// DiscardDeadLetterHandler godoc
+// @Summary      Discard a dead letter event
+// @Description  Removes the event from the dead letters without processing it.
+// @Tags         admin
+// @Param        event_id  path      string  true  "Event ID"
+// @Success      204
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/dead-letters/{event_id} [delete]
+func (h discardDeadLetterHandler) handle(c *gin.Context) {
+	var uri deadLetterURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.deadLetters.Discard(uri.EventID)
+	switch {
+	case errors.Is(err, domain.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func newDiscardDeadLetterHandler(deadLetters usecase.DeadLetters) discardDeadLetterHandler {
+	return discardDeadLetterHandler{deadLetters: deadLetters}
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	// exportFlushEvery is the number of rows written before flushing to the client
+	exportFlushEvery = 100
+)
+
+type exportColumn[T any] struct {
+	name  string
+	value func(T) interface{}
+}
+
+var orderExportColumns = []exportColumn[domain.Order]{
+	{"order_id", func(o domain.Order) interface{} { return o.OrderID }},
+	{"user_id", func(o domain.Order) interface{} { return o.UserID }},
+	{"status", func(o domain.Order) interface{} { return o.Status }},
+	{"is_final", func(o domain.Order) interface{} { return o.IsFinal }},
+	{"created_at", func(o domain.Order) interface{} { return o.CreatedAt }},
+	{"updated_at", func(o domain.Order) interface{} { return o.UpdatedAt }},
+	{"amount", func(o domain.Order) interface{} { return o.Amount }},
+	{"currency", func(o domain.Order) interface{} { return o.Currency }},
+	{"metadata", func(o domain.Order) interface{} { return o.Metadata }},
+}
+
+var eventExportColumns = []exportColumn[domain.OrderEvent]{
+	{"event_id", func(e domain.OrderEvent) interface{} { return e.EventID }},
+	{"order_id", func(e domain.OrderEvent) interface{} { return e.OrderID }},
+	{"user_id", func(e domain.OrderEvent) interface{} { return e.UserID }},
+	{"order_status", func(e domain.OrderEvent) interface{} { return e.OrderStatus }},
+	{"created_at", func(e domain.OrderEvent) interface{} { return e.CreatedAt }},
+	{"updated_at", func(e domain.OrderEvent) interface{} { return e.UpdatedAt }},
+	{"is_final", func(e domain.OrderEvent) interface{} { return e.IsFinal }},
+	{"rejected_reason", func(e domain.OrderEvent) interface{} { return e.RejectedReason }},
+	{"amount", func(e domain.OrderEvent) interface{} { return e.Amount }},
+	{"currency", func(e domain.OrderEvent) interface{} { return e.Currency }},
+	{"metadata", func(e domain.OrderEvent) interface{} { return e.Metadata }},
+}
+
+// selectColumns picks the requested comma separated columns in the requested order.
+// An empty selection returns all available columns.
+func selectColumns[T any](available []exportColumn[T], requested string) ([]exportColumn[T], error) {
+	if strings.TrimSpace(requested) == "" {
+		return available, nil
+	}
+
+	var selected []exportColumn[T]
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+
+		found := false
+		for _, column := range available {
+			if column.name == name {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown column: %s", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// exportWriter writes rows to the response as they are produced.
+// Headers are sent lazily, so a failure before the first row
+// can still be reported with a regular error response.
+type exportWriter[T any] struct {
+	c        *gin.Context
+	format   string
+	filename string
+	columns  []exportColumn[T]
+	csv      *csv.Writer
+	rows     int
+	started  bool
+}
+
+func (w *exportWriter[T]) begin() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	contentType := "text/csv; charset=utf-8"
+	if w.format == exportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, w.filename, w.format))
+	w.c.Status(http.StatusOK)
+
+	if w.format == exportFormatCSV {
+		w.csv = csv.NewWriter(w.c.Writer)
+		header := make([]string, len(w.columns))
+		for i, column := range w.columns {
+			header[i] = column.name
+		}
+		return w.csv.Write(header)
+	}
+
+	return nil
+}
+
+func (w *exportWriter[T]) write(item T) error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+
+	var err error
+	if w.format == exportFormatCSV {
+		err = w.writeCSV(item)
+	} else {
+		err = w.writeNDJSON(item)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *exportWriter[T]) writeCSV(item T) error {
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i] = formatCSVValue(column.value(item))
+	}
+	return w.csv.Write(record)
+}
+
+// writeNDJSON writes the item as a JSON object keeping the selected column order.
+func (w *exportWriter[T]) writeNDJSON(item T) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range w.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		value, err := json.Marshal(column.value(item))
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strconv.Quote(column.name))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	_, err := w.c.Writer.Write(buf.Bytes())
+	return err
+}
+
+func (w *exportWriter[T]) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// finish flushes remaining rows and sends headers for empty exports.
+func (w *exportWriter[T]) finish() error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	case json.RawMessage:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func newExportWriter[T any](c *gin.Context, format, filename string, columns []exportColumn[T]) *exportWriter[T] {
+	return &exportWriter[T]{
+		c:        c,
+		format:   format,
+		filename: fmt.Sprintf("%s-%s", filename, time.Now().UTC().Format("20060102T150405Z")),
+		columns:  columns,
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type exportOrderEventsHandler struct {
+	orders  usecase.Orders
+	exports usecase.Exports
+	access  accessPolicy
+}
+
+type exportOrderEventsRequest struct {
+	OrderID string `uri:"order_id" binding:"required,uuid"`
+}
+
+type exportOrderEventsQuery struct {
+	Format  string `form:"format,default=csv" binding:"oneof=csv ndjson"`
+	Columns string `form:"columns"`
+}
+
+// ExportOrderEventsHandler godoc
+// @Summary      Export order events
+// @Description  Stream all stored events of an order as CSV or NDJSON.
+// @Tags         orders
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Param        order_id  path      string  true   "ID of the order"
+// @Param        format    query     string  false  "Export format (csv/ndjson). Default is csv."
+// @Param        columns   query     string  false  "Comma separated columns (event_id,order_id,user_id,order_status,created_at,updated_at,is_final,rejected_reason,amount,currency,metadata). Default is all."
+// @Success      200       {file}    file
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /orders/{order_id}/events/export [get]
+func (h exportOrderEventsHandler) handle(c *gin.Context) {
+	var req exportOrderEventsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query exportOrderEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns, err := selectColumns(eventExportColumns, query.Columns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := h.orders.GetOrder(req.OrderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if order == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrOrderNotFound.Error()})
+		return
+	}
+
+	if !h.access.canAccess(c, order.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+		return
+	}
+
+	writer := newExportWriter(c, query.Format, fmt.Sprintf("order-%s-events", req.OrderID), columns)
+
+	err = h.exports.ExportEvents(req.OrderID, writer.write)
+	if err == nil {
+		err = writer.finish()
+	}
+
+	if err != nil {
+		if !writer.started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		fmt.Printf("error exporting order events: %v\n", err)
+		c.Abort()
+	}
+}
+
+func newExportOrderEventsHandler(orders usecase.Orders, exports usecase.Exports, access accessPolicy) exportOrderEventsHandler {
+	return exportOrderEventsHandler{orders: orders, exports: exports, access: access}
+}
@@ -0,0 +1,208 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+const (
+	exportOrder1 = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	exportOrder2 = "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	exportOrder3 = "c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	exportUser1  = "d0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	exportUser2  = "e0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+)
+
+// failingExports fails before streaming the first order.
+type failingExports struct {
+	*inmemory.OrderRepository
+}
+
+func (failingExports) StreamOrders(*domain.OrderFilter, func(domain.Order) error) error {
+	return errors.New("database is unavailable")
+}
+
+func newExportOrdersRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	orders := inmemory.NewOrderRepository(inmemory.NewStorage())
+	createdAt := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	amount := int64(1250)
+	for i, order := range []domain.Order{
+		{OrderID: exportOrder1, UserID: exportUser1, Status: domain.CoolOrderCreated},
+		{OrderID: exportOrder2, UserID: exportUser1, Status: domain.Chinazes, IsFinal: true,
+			PaymentDetails: domain.PaymentDetails{Amount: &amount, Currency: "EUR", Metadata: json.RawMessage(`{"invoice":"A-1"}`)}},
+		{OrderID: exportOrder3, UserID: exportUser2, Status: domain.ChangedMyMind, IsFinal: true},
+	} {
+		order.CreatedAt = createdAt.Add(time.Duration(i) * time.Hour)
+		order.UpdatedAt = order.CreatedAt
+		if err := orders.Save(&order); err != nil {
+			t.Fatalf("Failed to save order: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/orders/export", newExportOrdersHandler(usecase.NewExports(orders, clock.New()), accessPolicy{}).handle)
+	router.GET("/failing/export", newExportOrdersHandler(usecase.NewExports(failingExports{orders}, clock.New()), accessPolicy{}).handle)
+	return router
+}
+
+func TestExportOrders(t *testing.T) {
+	router := newExportOrdersRouter(t)
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		extension   string
+		rows        []string
+	}{
+		{
+			name:        "csv",
+			query:       "sort=created_at",
+			contentType: "text/csv; charset=utf-8",
+			extension:   "csv",
+			rows: []string{
+				"order_id,user_id,status,is_final,created_at,updated_at,amount,currency,metadata",
+				exportOrder1 + "," + exportUser1 + ",cool_order_created,false,2024-08-01T12:00:00Z,2024-08-01T12:00:00Z,,,",
+				exportOrder2 + "," + exportUser1 + `,chinazes,true,2024-08-01T13:00:00Z,2024-08-01T13:00:00Z,1250,EUR,"{""invoice"":""A-1""}"`,
+				exportOrder3 + "," + exportUser2 + ",changed_my_mind,true,2024-08-01T14:00:00Z,2024-08-01T14:00:00Z,,,",
+			},
+		},
+		{
+			name:        "csv columns",
+			query:       "columns=status,order_id&sort=created_at",
+			contentType: "text/csv; charset=utf-8",
+			extension:   "csv",
+			rows: []string{
+				"status,order_id",
+				"cool_order_created," + exportOrder1,
+				"chinazes," + exportOrder2,
+				"changed_my_mind," + exportOrder3,
+			},
+		},
+		{
+			name:        "ndjson columns",
+			query:       "format=ndjson&columns=order_id,amount,metadata&sort=created_at",
+			contentType: "application/x-ndjson",
+			extension:   "ndjson",
+			rows: []string{
+				`{"order_id":"` + exportOrder1 + `","amount":null,"metadata":null}`,
+				`{"order_id":"` + exportOrder2 + `","amount":1250,"metadata":{"invoice":"A-1"}}`,
+				`{"order_id":"` + exportOrder3 + `","amount":null,"metadata":null}`,
+			},
+		},
+		{
+			name:        "filters",
+			query:       "format=ndjson&columns=order_id&user_id=" + exportUser1 + "&is_final=true",
+			contentType: "application/x-ndjson",
+			extension:   "ndjson",
+			rows:        []string{`{"order_id":"` + exportOrder2 + `"}`},
+		},
+		{
+			name:        "sort",
+			query:       "columns=order_id&status=cool_order_created&status=changed_my_mind&sort=-created_at",
+			contentType: "text/csv; charset=utf-8",
+			extension:   "csv",
+			rows:        []string{"order_id", exportOrder3, exportOrder1},
+		},
+		{
+			name:        "empty csv has a header",
+			query:       "columns=order_id,status&currency=USD",
+			contentType: "text/csv; charset=utf-8",
+			extension:   "csv",
+			rows:        []string{"order_id,status"},
+		},
+		{
+			name:        "empty ndjson",
+			query:       "format=ndjson&currency=USD",
+			contentType: "application/x-ndjson",
+			extension:   "ndjson",
+		},
+	}
+
+	disposition := regexp.MustCompile(`^attachment; filename="orders-\d{8}T\d{6}Z\.(\w+)"$`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/export?"+tt.query, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Expected content type %q, got %q", tt.contentType, contentType)
+			}
+			match := disposition.FindStringSubmatch(w.Header().Get("Content-Disposition"))
+			if match == nil || match[1] != tt.extension {
+				t.Errorf("Expected a %s attachment, got %q", tt.extension, w.Header().Get("Content-Disposition"))
+			}
+
+			body := strings.TrimSuffix(w.Body.String(), "\n")
+			var rows []string
+			if body != "" {
+				rows = strings.Split(body, "\n")
+			}
+			if strings.Join(rows, "\n") != strings.Join(tt.rows, "\n") {
+				t.Errorf("Expected rows\n%s\ngot\n%s", strings.Join(tt.rows, "\n"), body)
+			}
+		})
+	}
+
+	// Every CSV row has the same number of fields as the header
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/export", nil))
+	if _, err := csv.NewReader(w.Body).ReadAll(); err != nil {
+		t.Errorf("Expected a valid CSV export, got %v", err)
+	}
+}
+
+func TestExportOrdersErrors(t *testing.T) {
+	router := newExportOrdersRouter(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"unknown column", "/orders/export?columns=order_id,price", http.StatusBadRequest},
+		{"empty column", "/orders/export?columns=order_id,", http.StatusBadRequest},
+		{"unknown format", "/orders/export?format=xlsx", http.StatusBadRequest},
+		{"invalid filter", "/orders/export?status=paid", http.StatusBadRequest},
+		{"invalid sort", "/orders/export?sort=amount", http.StatusBadRequest},
+		{"failure before the first row", "/failing/export", http.StatusInternalServerError},
+		{"ndjson failure before the first row", "/failing/export?format=ndjson", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if disposition := w.Header().Get("Content-Disposition"); disposition != "" {
+				t.Errorf("Expected no attachment, got %q", disposition)
+			}
+
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+				t.Errorf("Expected a JSON error, got %s", w.Body)
+			}
+		})
+	}
+}
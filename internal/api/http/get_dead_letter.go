@@ -0,0 +1,55 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getDeadLetterHandler struct {
+	deadLetters usecase.DeadLetters
+}
+
+// deadLetterURI is the path of a single dead letter event.
+type deadLetterURI struct {
+	EventID string `uri:"event_id" binding:"required,uuid"`
+}
+
+// GetDeadLetterHandler godoc
+// @Summary      Inspect a dead letter event
+// @Description  The delivered event with the reason it was not applied and the number of attempts.
+// @Tags         admin
+// @Produce      json
+// @Param        event_id  path      string  true  "Event ID"
+// @Success      200       {object}  domain.DeadLetterEvent
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/dead-letters/{event_id} [get]
+func (h getDeadLetterHandler) handle(c *gin.Context) {
+	var uri deadLetterURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	event, err := h.deadLetters.Get(uri.EventID)
+	switch {
+	case errors.Is(err, domain.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, event)
+	}
+}
+
+func newGetDeadLetterHandler(deadLetters usecase.DeadLetters) getDeadLetterHandler {
+	return getDeadLetterHandler{deadLetters: deadLetters}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getDeadLettersHandler struct {
+	deadLetters usecase.DeadLetters
+}
+
+type getDeadLettersRequest struct {
+	OrderID string `form:"order_id" binding:"omitempty,uuid"`
+	Limit   int    `form:"limit,default=10" binding:"min=1"`
+	Offset  int    `form:"offset,default=0" binding:"min=0"`
+}
+
+// GetDeadLettersHandler godoc
+// @Summary      List dead letter events
+// @Description  Events that were rejected, failed or ignored by the processor, most recently failed first.
+// @Tags         admin
+// @Produce      json
+// @Param        order_id  query     string  false  "ID of the order to list events of."
+// @Param        limit     query     int     false  "Number of events to return. Default is 10."
+// @Param        offset    query     int     false  "Offset for pagination. Default is 0."
+// @Success      200       {array}   domain.DeadLetterEvent
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/dead-letters [get]
+func (h getDeadLettersHandler) handle(c *gin.Context) {
+	var req getDeadLettersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.deadLetters.List(domain.DeadLetterFilter{
+		OrderID: req.OrderID,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if events == nil {
+		events = []domain.DeadLetterEvent{}
+	}
+
+	links := newPageLinks(c.Request.URL, req.Limit, req.Offset)
+	links.setHeader(c, len(events), nil)
+	c.JSON(http.StatusOK, events)
+}
+
+func newGetDeadLettersHandler(deadLetters usecase.DeadLetters) getDeadLettersHandler {
+	return getDeadLettersHandler{deadLetters: deadLetters}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/ratelimit"
 	"github.com/therealyo/justdone/internal/usecase"
 )
 
@@ -17,19 +19,32 @@ type getOrderEventsRequest struct {
 }
 
 type getOrderEventsHandler struct {
-	orders   usecase.Orders
-	timeout  time.Duration
-	notifier domain.OrderObserver
+	orders        usecase.Orders
+	timeout       time.Duration
+	buffer        int
+	notifier      domain.OrderObserver
+	access        accessPolicy
+	subscriptions *ratelimit.ConnectionLimiter
+	retryAfter    time.Duration
 }
 
 // GetOrderEventsHandler godoc
 // @Summary      Stream order events
 // @Description  Stream events for an order using Server-Side Events (SSE).
+// @Description  Events rejected for an illegal transition are sent as "anomaly" events with a rejected_reason.
+// @Description  Orders staying in a status longer than its SLA are reported with "stuck" events.
+// @Description  A client that can't keep up with the events (SSE_CLIENT_BUFFER) gets a "resync" event and is
+// @Description  disconnected, it has to subscribe again to get the stored events of the order.
 // @Tags         orders
 // @Accept       json
 // @Produce      text/event-stream
 // @Param        order_id  path   string  true  "ID of the order"
 // @Success      200  {object}  domain.OrderEvent  "Stream of order events"
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /orders/{order_id}/events [get]
 func (h getOrderEventsHandler) handle(c *gin.Context) {
 	var req getOrderEventsRequest
@@ -44,20 +59,26 @@ func (h getOrderEventsHandler) handle(c *gin.Context) {
 		return
 	}
 
-	// Make buffered channel to avoid incorrect order of events
-	clientChan := make(chan domain.OrderEvent, 1)
-	client := domain.OrderEventsSubscriber{
-		EventChan:  clientChan,
-		Disconnect: make(chan bool),
-		Timeout:    h.timeout,
+	if order != nil && !h.access.canAccess(c, order.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+		return
+	}
+
+	release, err := h.subscriptions.Acquire(req.OrderID, clientKey(c))
+	if err != nil {
+		tooManyRequests(c, h.retryAfter)
+		return
 	}
+	defer release()
+
+	client := domain.NewOrderEventsSubscriber(h.buffer, h.timeout)
 
 	h.notifier.RegisterClient(req.OrderID, client)
 	defer h.notifier.UnregisterClient(req.OrderID, client)
 
 	if order != nil {
 		for _, event := range order.Events {
-			if event.OrderStatus.Value() <= order.Status.Value() {
+			if event.RejectedReason == "" && event.OrderStatus.Value() <= order.Status.Value() {
 				data, _ := json.Marshal(event)
 				h.notifier.AddProcessedEvent(req.OrderID, event)
 				c.SSEvent("message", string(data))
@@ -78,12 +99,28 @@ func (h getOrderEventsHandler) handle(c *gin.Context) {
 		case <-c.Request.Context().Done():
 			fmt.Println("Client disconnected: close connection")
 			return false
-		case event, ok := <-clientChan:
-			if !ok {
-				fmt.Println("Client channel closed, stopping stream")
-				return false
+		case notification := <-client.Events():
+			// Stuck notices are not events of the order
+			if stuck := notification.Stuck; stuck != nil {
+				if h.access.canAccess(c, stuck.UserID) {
+					data, _ := json.Marshal(stuck)
+					c.SSEvent("stuck", string(data))
+					c.Writer.Flush()
+				}
+				return true
+			}
+			event := notification.Event
+			// The order may not exist yet on subscription, so ownership is checked per event
+			if !h.access.canAccess(c, event.UserID) {
+				return true
 			}
 			data, _ := json.Marshal(event)
+			// Rejected events are reported as anomalies, they did not change the order
+			if event.RejectedReason != "" {
+				c.SSEvent("anomaly", string(data))
+				c.Writer.Flush()
+				return true
+			}
 			c.SSEvent("message", string(data))
 			c.Writer.Flush()
 			if event.IsFinal {
@@ -92,13 +129,35 @@ func (h getOrderEventsHandler) handle(c *gin.Context) {
 			}
 			return true
 
-		case <-client.Disconnect:
-			fmt.Println("Client disconnected")
+		case <-client.Done():
+			// Events were lost, the client has to reload the order and subscribe again
+			if client.Reason() == domain.DisconnectResync {
+				data, _ := json.Marshal(gin.H{"order_id": req.OrderID})
+				c.SSEvent("resync", string(data))
+				c.Writer.Flush()
+			}
+			fmt.Println("Client disconnected:", client.Reason())
 			return false
 		}
 	})
 }
 
-func newGetOrderEventsHandler(orders usecase.Orders, notifier domain.OrderObserver, timeout time.Duration) getOrderEventsHandler {
-	return getOrderEventsHandler{orders: orders, notifier: notifier, timeout: timeout}
+func newGetOrderEventsHandler(
+	orders usecase.Orders,
+	notifier domain.OrderObserver,
+	timeout time.Duration,
+	buffer int,
+	access accessPolicy,
+	subscriptions *ratelimit.ConnectionLimiter,
+	retryAfter time.Duration,
+) getOrderEventsHandler {
+	return getOrderEventsHandler{
+		orders:        orders,
+		notifier:      notifier,
+		timeout:       timeout,
+		buffer:        buffer,
+		access:        access,
+		subscriptions: subscriptions,
+		retryAfter:    retryAfter,
+	}
 }
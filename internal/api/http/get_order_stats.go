@@ -0,0 +1,120 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getOrderStatsHandler struct {
+	stats  usecase.Stats
+	access accessPolicy
+}
+
+type getOrderStatsRequest struct {
+	From    string `form:"from"`
+	To      string `form:"to"`
+	UserID  string `form:"user_id"`
+	GroupBy string `form:"group_by,default=status"`
+}
+
+// GetOrderStatsHandler godoc
+// @Summary      Retrieve order statistics
+// @Description  Order counts grouped by status, day or hour, conversion to chinazes, refund and cancel rates
+// @Description  and median time between statuses.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        from      query     string  false  "Include orders created at or after this time (RFC3339 or YYYY-MM-DD)."
+// @Param        to        query     string  false  "Include orders created before this time (RFC3339 or YYYY-MM-DD)."
+// @Param        user_id   query     string  false  "ID of the user to calculate statistics for."
+// @Param        group_by  query     string  false  "Grouping of order counts (status/day/hour). Default is status."
+// @Success      200       {object}  domain.OrderStats
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      429       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /orders/stats [get]
+func (h getOrderStatsHandler) handle(c *gin.Context) {
+	var req getOrderStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// End users can only see statistics of their own orders
+	if userID := h.access.restrictedUser(c); userID != "" {
+		if req.UserID != "" && req.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+			return
+		}
+		req.UserID = userID
+	}
+
+	filter, err := req.build()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.stats.GetStats(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (req getOrderStatsRequest) build() (*domain.OrderStatsFilter, error) {
+	groupBy, err := domain.ParseStatsGroupBy(req.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := parseTimeParam("from", req.From)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseTimeParam("to", req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	return domain.NewOrderStatsFilter(
+		domain.WithStatsPeriod(from, to),
+		domain.WithStatsUserID(req.UserID),
+		domain.WithStatsGroupBy(groupBy),
+	), nil
+}
+
+// parseTimeParam parses an optional RFC3339 timestamp or a YYYY-MM-DD date in UTC.
+func parseTimeParam(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid %s value: %s", name, value)
+}
+
+func newGetOrderStatsHandler(stats usecase.Stats, access accessPolicy) getOrderStatsHandler {
+	return getOrderStatsHandler{stats: stats, access: access}
+}
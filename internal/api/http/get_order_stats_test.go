@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+func TestGetOrderStatsParams(t *testing.T) {
+	orders := inmemory.NewOrderRepository(inmemory.NewStorage())
+	for i, createdAt := range []time.Time{
+		time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 8, 2, 12, 0, 0, 0, time.UTC),
+	} {
+		order := &domain.Order{
+			OrderID:   []string{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}[i],
+			UserID:    "c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+			Status:    domain.CoolOrderCreated,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		if err := orders.Save(order); err != nil {
+			t.Fatalf("Failed to save order: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/orders/stats", newGetOrderStatsHandler(usecase.NewStats(orders), accessPolicy{}).handle)
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		groupBy domain.StatsGroupBy
+		total   int
+	}{
+		{"default grouping", "", http.StatusOK, domain.GroupByStatus, 2},
+		{"by day", "group_by=day", http.StatusOK, domain.GroupByDay, 2},
+		{"by hour", "group_by=hour", http.StatusOK, domain.GroupByHour, 2},
+		{"unknown grouping", "group_by=week", http.StatusBadRequest, "", 0},
+		{"from date", "from=2024-08-02", http.StatusOK, domain.GroupByStatus, 1},
+		{"to timestamp", "to=2024-08-01T13:00:00Z", http.StatusOK, domain.GroupByStatus, 1},
+		{"period", "from=2024-08-01&to=2024-08-03", http.StatusOK, domain.GroupByStatus, 2},
+		{"invalid from", "from=01.08.2024", http.StatusBadRequest, "", 0},
+		{"invalid to", "to=yesterday", http.StatusBadRequest, "", 0},
+		{"empty period", "from=2024-08-02&to=2024-08-02", http.StatusBadRequest, "", 0},
+		{"reversed period", "from=2024-08-02&to=2024-08-01", http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/stats?"+tt.query, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var body map[string]string
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+					t.Errorf("Expected an error message, got %s", w.Body)
+				}
+				return
+			}
+
+			var stats domain.OrderStats
+			if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+				t.Fatalf("Failed to decode stats: %v", err)
+			}
+			if stats.GroupBy != tt.groupBy || stats.Total != tt.total {
+				t.Errorf("Expected %d orders grouped by %s, got %d grouped by %s", tt.total, tt.groupBy, stats.Total, stats.GroupBy)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getOrderTimelineHandler struct {
+	orders usecase.Orders
+	access accessPolicy
+}
+
+type getOrderTimelineRequest struct {
+	OrderID string `uri:"order_id" binding:"required,uuid"`
+}
+
+// GetOrderTimelineHandler godoc
+// @Summary      Order timeline
+// @Description  Transitions of an order built from its stored events, with the time spent in every status,
+// @Description  events ignored as out of sequence, and when and by what (timer or terminal event) the order was finalized.
+// @Tags         orders
+// @Produce      json
+// @Param        order_id  path      string  true  "ID of the order"
+// @Success      200       {object}  domain.OrderTimeline
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /orders/{order_id}/timeline [get]
+func (h getOrderTimelineHandler) handle(c *gin.Context) {
+	var req getOrderTimelineRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timeline, err := h.orders.GetTimeline(req.OrderID)
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case !h.access.canAccess(c, timeline.UserID):
+		c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+	default:
+		c.JSON(http.StatusOK, timeline)
+	}
+}
+
+func newGetOrderTimelineHandler(orders usecase.Orders, access accessPolicy) getOrderTimelineHandler {
+	return getOrderTimelineHandler{orders: orders, access: access}
+}
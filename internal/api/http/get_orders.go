@@ -3,40 +3,91 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/auth"
 	"github.com/therealyo/justdone/internal/usecase"
 )
 
 type getOrdersHandler struct {
 	orders usecase.Orders
+	access accessPolicy
+}
+
+// orderFilterParams are the query filters shared by order listing and export.
+type orderFilterParams struct {
+	Status      []string `form:"status"`
+	UserIDs     []string `form:"user_id" binding:"dive,uuid"`
+	OrderIDs    []string `form:"order_id" binding:"dive,uuid"`
+	IsFinal     *bool    `form:"is_final"`
+	CreatedFrom string   `form:"created_from"`
+	CreatedTo   string   `form:"created_to"`
+	UpdatedFrom string   `form:"updated_from"`
+	UpdatedTo   string   `form:"updated_to"`
+	EverStatus  []string `form:"ever_status"`
+	StuckFor    int      `form:"stuck_for" binding:"min=0"`
+	AmountMin   *int64   `form:"amount_min" binding:"omitempty,min=0"`
+	AmountMax   *int64   `form:"amount_max" binding:"omitempty,min=0"`
+	Currencies  []string `form:"currency" binding:"dive,iso4217"`
+	Sort        string   `form:"sort"`
+	SortBy      string   `form:"sort_by"`
+	SortOrder   string   `form:"sort_order"`
 }
 
 type getOrdersRequest struct {
-	Status    []string `form:"status"`
-	UserID    string   `form:"user_id"`
-	Limit     int      `form:"limit,default=10"`
-	Offset    int      `form:"offset,default=0"`
-	IsFinal   *bool    `form:"is_final"`
-	SortBy    string   `form:"sort_by,default=created_at"`
-	SortOrder string   `form:"sort_order,default=desc"`
+	orderFilterParams
+	Limit    int  `form:"limit,default=10" binding:"min=1"`
+	Offset   int  `form:"offset,default=0" binding:"min=0"`
+	Envelope bool `form:"envelope"`
+}
+
+// ordersPage is the opt-in response envelope of GET /orders.
+type ordersPage struct {
+	Items          []domain.Order `json:"items"`
+	Total          int            `json:"total"`
+	TotalEstimated bool           `json:"total_estimated"`
+	Limit          int            `json:"limit"`
+	Offset         int            `json:"offset"`
+	Next           *string        `json:"next"`
 }
 
 // GetOrdersHandler godoc
 // @Summary      Retrieve a list of orders
 // @Description  Retrieve a list of orders with optional filtering and sorting.
+// @Description  Pagination links are returned in the Link header.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
-// @Param        status     query     []string  false  "List of order statuses to filter by. Required if `is_final` is not provided."
-// @Param        user_id    query     string    false  "ID of the user to filter orders by."
-// @Param        limit      query     int       false  "Number of orders to return. Default is 10."
-// @Param        offset     query     int       false  "Offset for pagination. Default is 0."
-// @Param        is_final   query     bool      false  "Final status of the order. Required if `status` is not provided."
-// @Param        sort_by    query     string    false  "Field to sort by (created_at/updated_at). Default is created_at."
-// @Param        sort_order query     string    false  "Sort order (asc/desc). Default is desc."
-// @Success      200        {array}   domain.Order
+// @Param        status       query     []string  false  "List of order statuses to filter by."
+// @Param        user_id      query     []string  false  "IDs of the users to filter orders by."
+// @Param        order_id     query     []string  false  "IDs of the orders to return."
+// @Param        is_final     query     bool      false  "Final status of the order."
+// @Param        created_from query     string    false  "Orders created at or after this time (RFC3339 or YYYY-MM-DD)."
+// @Param        created_to   query     string    false  "Orders created before this time (RFC3339 or YYYY-MM-DD)."
+// @Param        updated_from query     string    false  "Orders updated at or after this time (RFC3339 or YYYY-MM-DD)."
+// @Param        updated_to   query     string    false  "Orders updated before this time (RFC3339 or YYYY-MM-DD)."
+// @Param        ever_status  query     []string  false  "Orders that have ever been in any of the statuses."
+// @Param        stuck_for    query     int       false  "Non-final orders not updated for this many minutes."
+// @Param        amount_min   query     int       false  "Orders with an amount of at least this many minor units."
+// @Param        amount_max   query     int       false  "Orders with an amount of at most this many minor units."
+// @Param        currency     query     []string  false  "ISO 4217 currencies of the order amount to filter by."
+// @Param        limit        query     int       false  "Number of orders to return. Default is 10."
+// @Param        offset       query     int       false  "Offset for pagination. Default is 0."
+// @Param        sort         query     string    false  "Comma separated fields to sort by (created_at/updated_at/order_id/user_id/status), prefix with - for descending order. Default is -created_at."
+// @Param        sort_by      query     string    false  "Deprecated, use sort. Field to sort by."
+// @Param        sort_order   query     string    false  "Deprecated, use sort. Sort order (asc/desc)."
+// @Param        envelope     query     bool      false  "Wrap orders in a page envelope with the total count. Default is false."
+// @Success      200          {array}   domain.Order  "Bare array, or {items, total, total_estimated, limit, offset, next} when envelope is true"
+// @Header       200          {string}  Link  "Pagination links (first, prev, next, last)"
+// @Failure      400          {object}  map[string]string
+// @Failure      401          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Failure      429          {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Router       /orders [get]
 func (h getOrdersHandler) handle(c *gin.Context) {
 	var req getOrdersRequest
@@ -50,6 +101,11 @@ func (h getOrdersHandler) handle(c *gin.Context) {
 		return
 	}
 
+	if !req.restrict(c, h.access) {
+		c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+		return
+	}
+
 	filters, err := req.build()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -62,49 +118,194 @@ func (h getOrdersHandler) handle(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	// The bare array stays the default, so the count is only paid for when asked
+	if !req.Envelope {
+		links := newPageLinks(c.Request.URL, req.Limit, req.Offset)
+		links.setHeader(c, len(orders), nil)
+		c.JSON(http.StatusOK, orders)
+		return
+	}
+
+	count, err := h.orders.CountOrders(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if orders == nil {
+		orders = []domain.Order{}
+	}
+
+	links := newPageLinks(c.Request.URL, req.Limit, req.Offset)
+	links.setHeader(c, len(orders), count)
+
+	c.JSON(http.StatusOK, ordersPage{
+		Items:          orders,
+		Total:          count.Total,
+		TotalEstimated: count.Estimated,
+		Limit:          req.Limit,
+		Offset:         req.Offset,
+		Next:           links.next(len(orders), count),
+	})
 }
 
-func (req getOrdersRequest) validate() error {
-	if len(req.Status) > 0 && req.IsFinal != nil {
+func (req getOrdersRequest) build() (*domain.OrderFilter, error) {
+	filterOptions, err := req.options()
+	if err != nil {
+		return nil, err
+	}
+
+	filterOptions = append(filterOptions,
+		domain.WithLimit(req.Limit),
+		domain.WithOffset(req.Offset),
+	)
+
+	return domain.NewOrderFilter(filterOptions...), nil
+}
+
+func (p orderFilterParams) validate() error {
+	if len(p.Status) > 0 && p.IsFinal != nil {
 		return fmt.Errorf("cannot specify both status and is_final")
 	}
 
-	if len(req.Status) == 0 && req.IsFinal == nil {
-		return fmt.Errorf("must specify either status or is_final")
+	if p.AmountMin != nil && p.AmountMax != nil && *p.AmountMin > *p.AmountMax {
+		return fmt.Errorf("amount_min must not be greater than amount_max")
 	}
 
 	return nil
 }
 
-func (req getOrdersRequest) build() (*domain.OrderFilter, error) {
+// restrict limits end users to their own orders. It returns false
+// if the caller asked for orders of another user.
+func (p *orderFilterParams) restrict(c *gin.Context, access accessPolicy) bool {
+	userID := access.restrictedUser(c)
+	if userID == "" {
+		return true
+	}
+
+	for _, id := range p.UserIDs {
+		if id != userID {
+			return false
+		}
+	}
+
+	p.UserIDs = []string{userID}
+	return true
+}
+
+func (p orderFilterParams) options() ([]domain.FilterOption, error) {
+	sort, err := p.sort()
+	if err != nil {
+		return nil, err
+	}
+
 	filterOptions := []domain.FilterOption{
-		domain.WithUserID(req.UserID),
-		domain.WithLimit(req.Limit),
-		domain.WithOffset(req.Offset),
-		domain.WithSortBy(req.SortBy),
-		domain.WithSortOrder(req.SortOrder),
-	}
-
-	if len(req.Status) > 0 {
-		statuses := make([]domain.OrderStatus, len(req.Status))
-		for i, s := range req.Status {
-			status, err := domain.ParseOrderStatus(s)
-			if err != nil {
-				return nil, fmt.Errorf("invalid status value: %s", s)
-			}
-			statuses[i] = status
+		domain.WithUserIDs(p.UserIDs...),
+		domain.WithOrderIDs(p.OrderIDs...),
+		domain.WithSort(sort),
+		domain.WithStuckFor(time.Duration(p.StuckFor) * time.Minute),
+		domain.WithAmountBetween(p.AmountMin, p.AmountMax),
+		domain.WithCurrencies(p.Currencies...),
+	}
+
+	if len(p.Status) > 0 {
+		statuses, err := parseStatuses(p.Status)
+		if err != nil {
+			return nil, err
 		}
 		filterOptions = append(filterOptions, domain.WithStatus(statuses...))
 	}
 
-	if req.IsFinal != nil {
-		filterOptions = append(filterOptions, domain.WithIsFinal(req.IsFinal))
+	if len(p.EverStatus) > 0 {
+		statuses, err := parseStatuses(p.EverStatus)
+		if err != nil {
+			return nil, err
+		}
+		filterOptions = append(filterOptions, domain.WithEverInStatus(statuses...))
+	}
+
+	if p.IsFinal != nil {
+		filterOptions = append(filterOptions, domain.WithIsFinal(p.IsFinal))
 	}
 
-	return domain.NewOrderFilter(filterOptions...), nil
+	createdFrom, createdTo, err := parseTimeRange("created", p.CreatedFrom, p.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedFrom, updatedTo, err := parseTimeRange("updated", p.UpdatedFrom, p.UpdatedTo)
+	if err != nil {
+		return nil, err
+	}
+
+	filterOptions = append(filterOptions,
+		domain.WithCreatedBetween(createdFrom, createdTo),
+		domain.WithUpdatedBetween(updatedFrom, updatedTo),
+	)
+
+	return filterOptions, nil
+}
+
+// sort parses the sort parameter, falling back to the legacy sort_by and sort_order pair.
+// A nil result means the default sort.
+func (p orderFilterParams) sort() (domain.OrderSort, error) {
+	if p.Sort != "" {
+		return domain.ParseOrderSort(p.Sort)
+	}
+
+	if p.SortBy == "" && p.SortOrder == "" {
+		return nil, nil
+	}
+
+	field := domain.SortByCreatedAt
+	if p.SortBy != "" {
+		var err error
+		if field, err = domain.ParseSortField(p.SortBy); err != nil {
+			return nil, err
+		}
+	}
+
+	switch strings.ToLower(p.SortOrder) {
+	case "", "desc":
+		return domain.OrderSort{{Field: field, Descending: true}}, nil
+	case "asc":
+		return domain.OrderSort{{Field: field}}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q", domain.ErrInvalidSort, p.SortOrder)
+	}
+}
+
+func parseStatuses(values []string) ([]domain.OrderStatus, error) {
+	statuses := make([]domain.OrderStatus, len(values))
+	for i, s := range values {
+		status, err := domain.ParseOrderStatus(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status value: %s", s)
+		}
+		statuses[i] = status
+	}
+	return statuses, nil
+}
+
+// parseTimeRange parses <name>_from and <name>_to parameters.
+func parseTimeRange(name, fromValue, toValue string) (*time.Time, *time.Time, error) {
+	from, err := parseTimeParam(name+"_from", fromValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	to, err := parseTimeParam(name+"_to", toValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("%s_from must be before %s_to", name, name)
+	}
+
+	return from, to, nil
 }
 
-func newGetOrdersHandler(orders usecase.Orders) getOrdersHandler {
-	return getOrdersHandler{orders: orders}
+func newGetOrdersHandler(orders usecase.Orders, access accessPolicy) getOrdersHandler {
+	return getOrdersHandler{orders: orders, access: access}
 }
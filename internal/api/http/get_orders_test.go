@@ -0,0 +1,128 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+// estimatingOrders estimates counts like a database above the exact limit.
+type estimatingOrders struct {
+	*inmemory.OrderRepository
+}
+
+func (estimatingOrders) Count(*domain.OrderFilter, int) (*domain.OrderCount, error) {
+	return &domain.OrderCount{Total: 1000, Estimated: true}, nil
+}
+
+func TestGetOrdersPagination(t *testing.T) {
+	orders := inmemory.NewOrderRepository(inmemory.NewStorage())
+	createdAt := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	for i, orderID := range []string{
+		"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+	} {
+		order := &domain.Order{
+			OrderID:   orderID,
+			UserID:    "d0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+			Status:    domain.CoolOrderCreated,
+			CreatedAt: createdAt.Add(time.Duration(i) * time.Hour),
+			UpdatedAt: createdAt,
+		}
+		if err := orders.Save(order); err != nil {
+			t.Fatalf("Failed to save order: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/orders", newGetOrdersHandler(usecase.NewOrders(orders, 100, clock.New()), accessPolicy{}).handle)
+	router.GET("/estimated", newGetOrdersHandler(usecase.NewOrders(estimatingOrders{orders}, 100, clock.New()), accessPolicy{}).handle)
+
+	tests := []struct {
+		name      string
+		path      string
+		items     int
+		total     int
+		estimated bool
+		next      string
+		link      string
+	}{
+		{
+			name: "bare array", path: "/orders?limit=2", items: 2,
+			link: `</orders?limit=2&offset=0>; rel="first", </orders?limit=2&offset=2>; rel="next"`,
+		},
+		{
+			name: "exact envelope", path: "/orders?envelope=true&limit=2", items: 2, total: 3,
+			next: "/orders?envelope=true&limit=2&offset=2",
+			link: `</orders?envelope=true&limit=2&offset=0>; rel="first", </orders?envelope=true&limit=2&offset=2>; rel="next", </orders?envelope=true&limit=2&offset=2>; rel="last"`,
+		},
+		{
+			name: "last page envelope", path: "/orders?envelope=true&limit=2&offset=2", items: 1, total: 3,
+			link: `</orders?envelope=true&limit=2&offset=0>; rel="first", </orders?envelope=true&limit=2&offset=0>; rel="prev", </orders?envelope=true&limit=2&offset=2>; rel="last"`,
+		},
+		{
+			name: "empty envelope", path: "/orders?envelope=true&status=failed", items: 0, total: 0,
+			link: `</orders?envelope=true&limit=10&offset=0&status=failed>; rel="first", </orders?envelope=true&limit=10&offset=0&status=failed>; rel="last"`,
+		},
+		{
+			name: "estimated envelope", path: "/estimated?envelope=true&limit=2&offset=2", items: 1, total: 1000, estimated: true,
+			next: "/estimated?envelope=true&limit=2&offset=4",
+			link: `</estimated?envelope=true&limit=2&offset=0>; rel="first", </estimated?envelope=true&limit=2&offset=0>; rel="prev", </estimated?envelope=true&limit=2&offset=4>; rel="next"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if link := w.Header().Get("Link"); link != tt.link {
+				t.Errorf("Expected Link header\n%s\ngot\n%s", tt.link, link)
+			}
+			// The version is internal to optimistic locking
+			if strings.Contains(w.Body.String(), `"version"`) {
+				t.Errorf("Expected orders without their version, got %s", w.Body)
+			}
+
+			if !strings.Contains(tt.path, "envelope=true") {
+				var items []domain.Order
+				if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil || len(items) != tt.items {
+					t.Errorf("Expected an array of %d orders, got %s", tt.items, w.Body)
+				}
+				return
+			}
+
+			var page struct {
+				Items          []domain.Order `json:"items"`
+				Total          int            `json:"total"`
+				TotalEstimated bool           `json:"total_estimated"`
+				Next           *string        `json:"next"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatalf("Failed to decode page: %v", err)
+			}
+			if page.Items == nil || len(page.Items) != tt.items {
+				t.Errorf("Expected %d items, got %s", tt.items, w.Body)
+			}
+			if page.Total != tt.total || page.TotalEstimated != tt.estimated {
+				t.Errorf("Expected total %d estimated %v, got %d estimated %v", tt.total, tt.estimated, page.Total, page.TotalEstimated)
+			}
+			if (page.Next == nil) != (tt.next == "") || (page.Next != nil && *page.Next != tt.next) {
+				t.Errorf("Expected next %q, got %v", tt.next, page.Next)
+			}
+		})
+	}
+}
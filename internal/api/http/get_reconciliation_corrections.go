@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getReconciliationCorrectionsHandler struct {
+	reconciliation usecase.Reconciliation
+}
+
+type getReconciliationCorrectionsRequest struct {
+	RunID   string `form:"run_id" binding:"omitempty,uuid"`
+	OrderID string `form:"order_id" binding:"omitempty,uuid"`
+	Limit   int    `form:"limit,default=10" binding:"min=1"`
+	Offset  int    `form:"offset,default=0" binding:"min=0"`
+}
+
+// GetReconciliationCorrectionsHandler godoc
+// @Summary      List reconciliation corrections
+// @Description  Audit trail of synthetic events created by reconciliation runs, most recent first.
+// @Description  Corrections that were not applied have an error.
+// @Tags         admin
+// @Produce      json
+// @Param        run_id    query     string  false  "ID of the reconciliation run."
+// @Param        order_id  query     string  false  "ID of the order."
+// @Param        limit     query     int     false  "Number of corrections to return. Default is 10."
+// @Param        offset    query     int     false  "Offset for pagination. Default is 0."
+// @Success      200       {array}   domain.ReconciliationCorrection
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/reconciliation/corrections [get]
+func (h getReconciliationCorrectionsHandler) handle(c *gin.Context) {
+	var req getReconciliationCorrectionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	corrections, err := h.reconciliation.Corrections(domain.ReconciliationCorrectionFilter{
+		RunID:   req.RunID,
+		OrderID: req.OrderID,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if corrections == nil {
+		corrections = []domain.ReconciliationCorrection{}
+	}
+
+	links := newPageLinks(c.Request.URL, req.Limit, req.Offset)
+	links.setHeader(c, len(corrections), nil)
+	c.JSON(http.StatusOK, corrections)
+}
+
+func newGetReconciliationCorrectionsHandler(reconciliation usecase.Reconciliation) getReconciliationCorrectionsHandler {
+	return getReconciliationCorrectionsHandler{reconciliation: reconciliation}
+}
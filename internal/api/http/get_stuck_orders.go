@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type getStuckOrdersHandler struct {
+	stuckOrders *usecase.StuckOrders
+	access      accessPolicy
+}
+
+type getStuckOrdersRequest struct {
+	UserID string `form:"user_id" binding:"omitempty,uuid"`
+	Limit  int    `form:"limit,default=100" binding:"min=1,max=1000"`
+}
+
+// GetStuckOrdersHandler godoc
+// @Summary      List stuck orders
+// @Description  Non-final orders that stayed in their status longer than the SLA of the status (STUCK_ORDER_SLA),
+// @Description  the longest stuck first.
+// @Tags         orders
+// @Produce      json
+// @Param        user_id  query     string  false  "ID of the user to list stuck orders for."
+// @Param        limit    query     int     false  "Maximum number of orders (1-1000). Default is 100."
+// @Success      200      {array}   domain.OrderStuck
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      403      {object}  map[string]string
+// @Failure      429      {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /orders/stuck [get]
+func (h getStuckOrdersHandler) handle(c *gin.Context) {
+	var req getStuckOrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// End users can only see their own orders
+	if userID := h.access.restrictedUser(c); userID != "" {
+		if req.UserID != "" && req.UserID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+			return
+		}
+		req.UserID = userID
+	}
+
+	stuck, err := h.stuckOrders.List(req.UserID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stuck)
+}
+
+func newGetStuckOrdersHandler(stuckOrders *usecase.StuckOrders, access accessPolicy) getStuckOrdersHandler {
+	return getStuckOrdersHandler{stuckOrders: stuckOrders, access: access}
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/metrics"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	idempotencyReplayed  = "Idempotent-Replayed"
+	// maxWebhookBodySize bounds the webhook payloads read into memory
+	maxWebhookBodySize = 64 << 10
+	// inFlightWait is how long a duplicate waits for the response to the
+	// request in flight before it is told to retry
+	inFlightWait = 5 * time.Second
+)
+
+// idempotencyMiddleware replays the stored response when a webhook is
+// delivered again with the same Idempotency-Key header or event_id,
+// instead of processing it a second time.
+type idempotencyMiddleware struct {
+	store    domain.IdempotencyStore
+	ttl      time.Duration
+	wait     time.Duration
+	inFlight *inFlightRequests
+}
+
+// inFlightRequests tracks the idempotency keys of the requests being
+// processed, so duplicates can wait for their response.
+type inFlightRequests struct {
+	mu   sync.Mutex
+	done map[string]chan struct{}
+}
+
+// claim marks the keys as in flight and returns the function releasing them.
+// If another request holds one of the keys, nothing is claimed and the
+// channel closed when that request is done is returned instead.
+func (r *inFlightRequests) claim(keys []string) (func(), <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range keys {
+		if done, exists := r.done[key]; exists {
+			return nil, done
+		}
+	}
+
+	done := make(chan struct{})
+	for _, key := range keys {
+		r.done[key] = done
+	}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, key := range keys {
+			delete(r.done, key)
+		}
+		close(done)
+	}, nil
+}
+
+// responseRecorder keeps a copy of the response body for storing it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(data string) (int, error) {
+	r.body.WriteString(data)
+	return r.ResponseWriter.WriteString(data)
+}
+
+func (m idempotencyMiddleware) handle(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+			})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(hash[:])
+	headerKey, keys := idempotencyKeys(c.GetHeader(idempotencyKeyHeader), body)
+
+	// A duplicate of a request in flight waits for its response, which is
+	// replayed if it was stored and processed again otherwise
+	timeout := time.NewTimer(m.wait)
+	defer timeout.Stop()
+	for {
+		if m.replay(c, headerKey, keys, requestHash) {
+			return
+		}
+
+		release, busy := m.inFlight.claim(keys)
+		if busy == nil {
+			defer release()
+			break
+		}
+
+		select {
+		case <-busy:
+		case <-timeout.C:
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": domain.ErrEventInProgress.Error()})
+			return
+		case <-c.Request.Context().Done():
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": c.Request.Context().Err().Error()})
+			return
+		}
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+
+	c.Next()
+
+	status := recorder.Status()
+	if !isReplayable(status) {
+		return
+	}
+
+	headers := recorder.Header().Clone()
+	headers.Del("Content-Length")
+
+	for _, key := range keys {
+		err := m.store.Save(domain.IdempotentResponse{
+			Key:         key,
+			RequestHash: requestHash,
+			Status:      status,
+			Headers:     headers,
+			Body:        recorder.body.Bytes(),
+			ExpiresAt:   time.Now().Add(m.ttl),
+		})
+		if err != nil {
+			fmt.Printf("error saving idempotency key %s: %v\n", key, err)
+		}
+	}
+}
+
+// replay writes the stored response to the request and reports whether the
+// request was handled.
+func (m idempotencyMiddleware) replay(c *gin.Context, headerKey string, keys []string, requestHash string) bool {
+	for _, key := range keys {
+		stored, err := m.store.Get(key)
+		if err != nil {
+			// The store is an optimization, the processor still rejects duplicates
+			fmt.Printf("error reading idempotency key %s: %v\n", key, err)
+			continue
+		}
+		if stored == nil {
+			continue
+		}
+
+		if stored.RequestHash != requestHash {
+			if key == headerKey {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "Idempotency-Key was already used for a different request",
+				})
+				return true
+			}
+			// A different payload with a known event_id is left to the processor
+			continue
+		}
+
+		metrics.Counter("webhook_idempotent_replays").Add(1)
+		for name, values := range stored.Headers {
+			c.Writer.Header()[name] = values
+		}
+		contentType := c.Writer.Header().Get("Content-Type")
+		if contentType == "" {
+			// Responses stored before their headers were kept
+			contentType = gin.MIMEJSON
+		}
+		c.Header(idempotencyReplayed, "true")
+		c.Data(stored.Status, contentType, stored.Body)
+		c.Abort()
+		return true
+	}
+
+	return false
+}
+
+// idempotencyKeys returns the store keys of the request, the header key first.
+// Header and event keys are prefixed so they can't collide.
+func idempotencyKeys(header string, body []byte) (string, []string) {
+	var keys []string
+	var headerKey string
+
+	if header != "" {
+		headerKey = "key:" + header
+		keys = append(keys, headerKey)
+	}
+
+	var event struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(body, &event); err == nil && event.EventID != "" {
+		keys = append(keys, "event:"+event.EventID)
+	}
+
+	return headerKey, keys
+}
+
+// isReplayable reports whether the response would be the same on retry.
+// Missing orders, conflicts with events in flight and server errors are
+// transient, so JustPay! retries must be processed again.
+func isReplayable(status int) bool {
+	switch {
+	case status >= 200 && status < 300:
+		return true
+	case status == http.StatusBadRequest, status == http.StatusGone:
+		return true
+	default:
+		return false
+	}
+}
+
+func newIdempotencyMiddleware(store domain.IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return idempotencyMiddleware{
+		store:    store,
+		ttl:      ttl,
+		wait:     inFlightWait,
+		inFlight: &inFlightRequests{done: make(map[string]chan struct{})},
+	}.handle
+}
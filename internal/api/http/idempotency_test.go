@@ -0,0 +1,196 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/internal/inmemory"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/webhook", newIdempotencyMiddleware(inmemory.NewIdempotencyStore(), time.Hour), func(c *gin.Context) {
+		calls++
+		if calls > 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "event already exists"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Event created"})
+	})
+
+	send := func(body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send(`{"event_id":"event1"}`, "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", first.Code)
+	}
+
+	// A retry of the event gets the original response without processing
+	retry := send(`{"event_id":"event1"}`, "")
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed 200 %s, got %d %s", first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(idempotencyReplayed) != "true" || calls != 1 {
+		t.Errorf("Expected replayed response without processing, got %d calls", calls)
+	}
+
+	// Responses to conflicts are not stored
+	if w := send(`{"event_id":"event2"}`, ""); w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", w.Code)
+	}
+	if w := send(`{"event_id":"event2"}`, ""); w.Code != http.StatusConflict || calls != 3 {
+		t.Errorf("Expected conflict to be processed again, got %d after %d calls", w.Code, calls)
+	}
+
+	// A header key can't be reused for another payload
+	calls = 0
+	if w := send(`{"event_id":"event4"}`, "key2"); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if w := send(`{"event_id":"event5"}`, "key2"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reused key, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareReplaysHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/webhook", newIdempotencyMiddleware(inmemory.NewIdempotencyStore(), time.Hour), func(c *gin.Context) {
+		c.Header("Location", "/orders/order1")
+		c.Data(http.StatusAccepted, "application/problem+json", []byte(`{"error":"stored"}`))
+	})
+
+	var responses []*httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"event_id":"event1"}`)))
+		responses = append(responses, w)
+	}
+
+	replay := responses[1]
+	if replay.Header().Get(idempotencyReplayed) != "true" {
+		t.Fatalf("Expected replayed response, got headers %v", replay.Header())
+	}
+	if replay.Code != http.StatusAccepted || replay.Body.String() != `{"error":"stored"}` {
+		t.Errorf("Expected replayed 202 with the original body, got %d %s", replay.Code, replay.Body)
+	}
+	if got := replay.Header().Get("Location"); got != "/orders/order1" {
+		t.Errorf("Expected replayed Location header, got %q", got)
+	}
+	if got := replay.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Expected replayed Content-Type header, got %q", got)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsLargeBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	called := false
+	router := gin.New()
+	router.POST("/webhook", newIdempotencyMiddleware(inmemory.NewIdempotencyStore(), time.Hour), func(c *gin.Context) {
+		called = true
+	})
+
+	body := `{"event_id":"event1","padding":"` + strings.Repeat("x", maxWebhookBodySize) + `"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+	if w.Code != http.StatusRequestEntityTooLarge || called {
+		t.Errorf("Expected 413 without processing, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareWaitsForRequestInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, test := range []struct {
+		name string
+		wait time.Duration
+		// status of the duplicate sent while the first request is processed
+		status int
+		calls  int
+	}{
+		{name: "replays the response", wait: 5 * time.Second, status: http.StatusOK, calls: 1},
+		{name: "asks to retry after the wait", wait: 10 * time.Millisecond, status: http.StatusConflict, calls: 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			middleware := idempotencyMiddleware{
+				store:    inmemory.NewIdempotencyStore(),
+				ttl:      time.Hour,
+				wait:     test.wait,
+				inFlight: &inFlightRequests{done: make(map[string]chan struct{})},
+			}
+
+			var mu sync.Mutex
+			calls := 0
+			started := make(chan struct{})
+			finish := make(chan struct{})
+			router := gin.New()
+			router.POST("/webhook", middleware.handle, func(c *gin.Context) {
+				mu.Lock()
+				calls++
+				first := calls == 1
+				mu.Unlock()
+				if first {
+					close(started)
+					<-finish
+				}
+				c.JSON(http.StatusOK, gin.H{"message": "Event created"})
+			})
+
+			send := func() *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{"event_id":"event1"}`)))
+				return w
+			}
+
+			first := make(chan *httptest.ResponseRecorder)
+			go func() { first <- send() }()
+			<-started
+
+			duplicate := make(chan *httptest.ResponseRecorder)
+			go func() { duplicate <- send() }()
+
+			var w *httptest.ResponseRecorder
+			if test.status == http.StatusConflict {
+				w = <-duplicate
+				close(finish)
+			} else {
+				// Give the duplicate time to find the request in flight
+				time.Sleep(50 * time.Millisecond)
+				close(finish)
+				w = <-duplicate
+			}
+			if original := <-first; original.Code != http.StatusOK {
+				t.Fatalf("Expected 200 for the first request, got %d", original.Code)
+			}
+
+			if w.Code != test.status || calls != test.calls {
+				t.Errorf("Expected %d after %d calls, got %d after %d", test.status, test.calls, w.Code, calls)
+			}
+			if test.status == http.StatusConflict && w.Header().Get("Retry-After") == "" {
+				t.Error("Expected Retry-After for the request in flight")
+			}
+			if test.status == http.StatusOK && w.Header().Get(idempotencyReplayed) != "true" {
+				t.Error("Expected the response of the first request to be replayed")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+)
+
+// pageLinks builds offset pagination links keeping all other query parameters.
+type pageLinks struct {
+	url    url.URL
+	limit  int
+	offset int
+}
+
+func (p pageLinks) link(offset int) string {
+	query := p.url.Query()
+	query.Set("limit", strconv.Itoa(p.limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	u := url.URL{Path: p.url.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// next returns the link to the next page or nil on the last page. Without a count
+// a full page is assumed to have a successor.
+func (p pageLinks) next(items int, count *domain.OrderCount) *string {
+	hasNext := items == p.limit
+	if count != nil {
+		hasNext = p.offset+p.limit < count.Total
+	}
+
+	if !hasNext {
+		return nil
+	}
+
+	link := p.link(p.offset + p.limit)
+	return &link
+}
+
+// setHeader sets the Link header as described in RFC 8288.
+// The last page is only linked when the total count is known.
+func (p pageLinks) setHeader(c *gin.Context, items int, count *domain.OrderCount) {
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, p.link(0))}
+
+	if p.offset > 0 {
+		prev := p.offset - p.limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, p.link(prev)))
+	}
+
+	if next := p.next(items, count); next != nil {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, *next))
+	}
+
+	if count != nil && !count.Estimated {
+		last := 0
+		if count.Total > 0 {
+			last = (count.Total - 1) / p.limit * p.limit
+		}
+		links = append(links, fmt.Sprintf(`<%s>; rel="last"`, p.link(last)))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+func newPageLinks(u *url.URL, limit, offset int) pageLinks {
+	return pageLinks{url: *u, limit: limit, offset: offset}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+)
+
+func TestPageLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		items  int
+		count  *domain.OrderCount
+		next   string
+		link   string
+	}{
+		{
+			name: "full first page without count", limit: 2, offset: 0, items: 2,
+			next: "/orders?limit=2&offset=2&status=chinazes",
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=2&status=chinazes>; rel="next"`,
+		},
+		{
+			name: "short page without count", limit: 2, offset: 2, items: 1,
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=0&status=chinazes>; rel="prev"`,
+		},
+		{
+			name: "prev is clamped to the first page", limit: 2, offset: 1, items: 2,
+			next: "/orders?limit=2&offset=3&status=chinazes",
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=0&status=chinazes>; rel="prev", </orders?limit=2&offset=3&status=chinazes>; rel="next"`,
+		},
+		{
+			name: "exact count with more pages", limit: 2, offset: 2, items: 2, count: &domain.OrderCount{Total: 5},
+			next: "/orders?limit=2&offset=4&status=chinazes",
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=0&status=chinazes>; rel="prev", </orders?limit=2&offset=4&status=chinazes>; rel="next", </orders?limit=2&offset=4&status=chinazes>; rel="last"`,
+		},
+		{
+			name: "full last page with exact count", limit: 2, offset: 2, items: 2, count: &domain.OrderCount{Total: 4},
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=0&status=chinazes>; rel="prev", </orders?limit=2&offset=2&status=chinazes>; rel="last"`,
+		},
+		{
+			name: "no orders", limit: 2, offset: 0, items: 0, count: &domain.OrderCount{Total: 0},
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=0&status=chinazes>; rel="last"`,
+		},
+		{
+			name: "estimated count has no last page", limit: 2, offset: 0, items: 2, count: &domain.OrderCount{Total: 100, Estimated: true},
+			next: "/orders?limit=2&offset=2&status=chinazes",
+			link: `</orders?limit=2&offset=0&status=chinazes>; rel="first", </orders?limit=2&offset=2&status=chinazes>; rel="next"`,
+		},
+	}
+
+	u, _ := url.Parse("/orders?status=chinazes&limit=10&offset=7")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links := newPageLinks(u, tt.limit, tt.offset)
+
+			next := links.next(tt.items, tt.count)
+			if (next == nil) != (tt.next == "") || (next != nil && *next != tt.next) {
+				t.Errorf("Expected next %q, got %v", tt.next, next)
+			}
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, u.String(), nil)
+			links.setHeader(c, tt.items, tt.count)
+			if link := c.Writer.Header().Get("Link"); link != tt.link {
+				t.Errorf("Expected Link header\n%s\ngot\n%s", tt.link, link)
+			}
+		})
+	}
+}
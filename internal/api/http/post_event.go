@@ -1,16 +1,26 @@
 package http
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/metrics"
 	"github.com/therealyo/justdone/internal/usecase"
 )
 
+// maxMetadataSize limits the size of event metadata in bytes.
+const maxMetadataSize = 16 << 10
+
 type postEventHandler struct {
 	events usecase.Events
+	// sequenceViolationStatus is returned for events rejected for an illegal transition
+	sequenceViolationStatus int
 }
 
 type postEventRequest struct {
@@ -20,6 +30,41 @@ type postEventRequest struct {
 	OrderStatus string    `json:"order_status" binding:"required,oneof=cool_order_created sbu_verification_pending confirmed_by_mayor changed_my_mind failed chinazes give_my_money_back"`
 	CreatedAt   time.Time `json:"created_at" binding:"required"`
 	UpdatedAt   time.Time `json:"updated_at" binding:"required"`
+	// Amount is in minor units of the currency, e.g. cents
+	Amount *int64 `json:"amount" binding:"omitempty,min=0"`
+	// Currency is an ISO 4217 code, required with the amount
+	Currency string `json:"currency" binding:"required_with=Amount,excluded_without=Amount,omitempty,iso4217" example:"EUR"`
+	// Metadata is a free-form JSON object of up to 16KB
+	Metadata json.RawMessage `json:"metadata" swaggertype:"object"`
+}
+
+// metadata validates the metadata of the request and returns it compacted,
+// or nil if it was not sent.
+func (req postEventRequest) metadata() (json.RawMessage, error) {
+	raw := bytes.TrimSpace(req.Metadata)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] != '{' {
+		return nil, errors.New("metadata must be a JSON object")
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, raw); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if compacted.Len() > maxMetadataSize {
+		return nil, fmt.Errorf("metadata exceeds %d bytes", maxMetadataSize)
+	}
+
+	return compacted.Bytes(), nil
+}
+
+// sequenceViolationResponse reports the illegal transition of a rejected event.
+type sequenceViolationResponse struct {
+	Error string             `json:"error"`
+	From  domain.OrderStatus `json:"from,omitempty"`
+	To    domain.OrderStatus `json:"to"`
 }
 
 // PostEventHandler godoc
@@ -30,6 +75,14 @@ type postEventRequest struct {
 // @Produce      json
 // @Param        event   body      postEventRequest  true  "Event"
 // @Success      200  {object}  domain.OrderEvent
+// @Success      202  {object}  sequenceViolationResponse  "Stored but not applied, the status is configurable"
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string  "Duplicate event, with Retry-After while the original is processed"
+// @Failure      410  {object}  map[string]string
+// @Failure      413  {object}  map[string]string
+// @Failure      422  {object}  map[string]string  "Idempotency-Key reused for another payload"
+// @Failure      503  {object}  map[string]string
 // @Router       /webhooks/payments/orders [post]
 func (h postEventHandler) handle(c *gin.Context) {
 	var req postEventRequest
@@ -38,7 +91,13 @@ func (h postEventHandler) handle(c *gin.Context) {
 		return
 	}
 
-	err := h.events.Create(&domain.OrderEvent{
+	metadata, err := req.metadata()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.events.Create(&domain.OrderEvent{
 		EventID:     req.EventID,
 		OrderID:     req.OrderID,
 		UserID:      req.UserID,
@@ -46,15 +105,37 @@ func (h postEventHandler) handle(c *gin.Context) {
 		CreatedAt:   req.CreatedAt,
 		UpdatedAt:   req.UpdatedAt,
 		IsFinal:     domain.OrderStatus(req.OrderStatus).IsFinal(),
+		PaymentDetails: domain.PaymentDetails{
+			Amount:   req.Amount,
+			Currency: req.Currency,
+			Metadata: metadata,
+		},
 	})
 
+	var violation *domain.SequenceViolation
+
 	switch {
+	case errors.As(err, &violation):
+		metrics.Counter("webhook_sequence_violations").Add(1)
+		c.AbortWithStatusJSON(h.sequenceViolationStatus, sequenceViolationResponse{
+			Error: violation.Error(),
+			From:  violation.From,
+			To:    violation.To,
+		})
 	case err == domain.ErrEventConflict:
 		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err == domain.ErrEventInProgress:
+		// A duplicate handled by another instance, retrying gets its outcome
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
 	case err == domain.ErrOrderAlreadyFinal:
 		c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": err.Error()})
 	case err == domain.ErrOrderNotFound:
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrConcurrentModification):
+		// The event was not stored, so it can be delivered again
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 	case err != nil:
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	default:
@@ -62,6 +143,6 @@ func (h postEventHandler) handle(c *gin.Context) {
 	}
 }
 
-func newPostEventHandler(events usecase.Events) postEventHandler {
-	return postEventHandler{events: events}
+func newPostEventHandler(events usecase.Events, sequenceViolationStatus int) postEventHandler {
+	return postEventHandler{events: events, sequenceViolationStatus: sequenceViolationStatus}
 }
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostEventPaymentDetails(t *testing.T) {
+	const event = `"event_id": "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"order_id": "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"user_id": "c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11",
+		"order_status": "cool_order_created",
+		"created_at": "2024-08-01T12:00:00Z",
+		"updated_at": "2024-08-01T12:00:00Z"`
+
+	tests := []struct {
+		name     string
+		details  string
+		valid    bool
+		metadata string
+	}{
+		{"none", ``, true, ""},
+		{"amount", `, "amount": 1250, "currency": "EUR"`, true, ""},
+		{"zero amount", `, "amount": 0, "currency": "JPY"`, true, ""},
+		{"metadata", `, "metadata": { "invoice": "A-1", "items": [1, 2] }`, true, `{"invoice":"A-1","items":[1,2]}`},
+		{"null metadata", `, "metadata": null`, true, ""},
+		{"negative amount", `, "amount": -1, "currency": "EUR"`, false, ""},
+		{"amount without currency", `, "amount": 1250`, false, ""},
+		{"currency without amount", `, "currency": "EUR"`, false, ""},
+		{"unknown currency", `, "amount": 1250, "currency": "EURO"`, false, ""},
+		{"lower case currency", `, "amount": 1250, "currency": "eur"`, false, ""},
+		{"metadata array", `, "metadata": [1, 2]`, false, ""},
+		{"metadata string", `, "metadata": "invoice"`, false, ""},
+		{"metadata too large", `, "metadata": {"note": "` + strings.Repeat("x", maxMetadataSize) + `"}`, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/webhooks/payments/orders", strings.NewReader("{"+event+tt.details+"}"))
+
+			var req postEventRequest
+			err := c.ShouldBindJSON(&req)
+			var metadata []byte
+			if err == nil {
+				metadata, err = req.metadata()
+			}
+
+			if tt.valid && err != nil {
+				t.Fatalf("Expected event to be valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("Expected event to be rejected")
+			}
+			if string(metadata) != tt.metadata {
+				t.Errorf("Expected metadata %q, got %q", tt.metadata, metadata)
+			}
+		})
+	}
+}
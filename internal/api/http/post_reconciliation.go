@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+// settlementMaxSize limits uploaded settlement files
+const settlementMaxSize = 32 << 20
+
+type postReconciliationHandler struct {
+	reconciliation usecase.Reconciliation
+}
+
+type postReconciliationRequest struct {
+	Format     string `form:"format"`
+	Correct    bool   `form:"correct"`
+	PeriodFrom string `form:"period_from"`
+	PeriodTo   string `form:"period_to"`
+}
+
+// PostReconciliationHandler godoc
+// @Summary      Reconcile a settlement file
+// @Description  Matches rows of a JustPay! settlement file to orders by order_id and reports missing orders,
+// @Description  status mismatches and orders final on one side only. Stored orders created in the period that are
+// @Description  missing from the file are reported too, the period defaults to the creation times of the stored
+// @Description  orders in the file. The file is a CSV with a header or a JSON array with order_id, status and
+// @Description  optional user_id and is_final. With correct=true the missing events of every discrepancy are
+// @Description  processed as synthetic events and recorded in the audit trail.
+// @Tags         admin
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file         formData  file    true   "Settlement file"
+// @Param        format       query     string  false  "File format (csv/json). Default is the file extension."
+// @Param        correct      query     bool    false  "Create corrective events. Default is false."
+// @Param        period_from  query     string  false  "Start of the settlement period (RFC3339 or YYYY-MM-DD), inclusive"
+// @Param        period_to    query     string  false  "End of the settlement period (RFC3339 or YYYY-MM-DD), exclusive"
+// @Success      200          {object}  domain.ReconciliationReport
+// @Failure      400          {object}  map[string]string
+// @Failure      401          {object}  map[string]string
+// @Failure      403          {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/reconciliation [post]
+func (h postReconciliationHandler) handle(c *gin.Context) {
+	var req postReconciliationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, err := parseTimeRange("period", req.PeriodFrom, req.PeriodTo)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, settlementMaxSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, err := settlementFormat(req.Format, header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	records, err := parseSettlement(file, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.reconciliation.Reconcile(header.Filename, records, domain.SettlementPeriod{From: from, To: to}, req.Correct)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func newPostReconciliationHandler(reconciliation usecase.Reconciliation) postReconciliationHandler {
+	return postReconciliationHandler{reconciliation: reconciliation}
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/internal/metrics"
+	"github.com/therealyo/justdone/internal/ratelimit"
+)
+
+type rateLimitMiddleware struct {
+	limiter *ratelimit.KeyedLimiter
+}
+
+// handle rejects the request with 429 once the caller's token bucket is empty.
+func (m rateLimitMiddleware) handle(c *gin.Context) {
+	allowed, retryAfter := m.limiter.Allow(clientKey(c))
+	if !allowed {
+		metrics.Counter("orders_rate_limited").Add(1)
+		tooManyRequests(c, retryAfter)
+		return
+	}
+
+	c.Next()
+}
+
+// clientKey identifies the caller by user ID when authenticated as an end user
+// and by IP address otherwise. The address is only taken from X-Forwarded-For
+// when the request comes from a trusted proxy.
+func clientKey(c *gin.Context) string {
+	if principal := principalFromContext(c); principal != nil && principal.UserID != "" {
+		return "user:" + principal.UserID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func tooManyRequests(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+}
+
+func newRateLimitMiddleware(limiter *ratelimit.KeyedLimiter) gin.HandlerFunc {
+	return rateLimitMiddleware{limiter: limiter}.handle
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/usecase"
+)
+
+type retryDeadLetterHandler struct {
+	deadLetters usecase.DeadLetters
+}
+
+// RetryDeadLetterHandler godoc
+// @Summary      Retry a dead letter event
+// @Description  Feeds the event to the processor again. An applied event is removed from the dead letters,
+// @Description  otherwise the reason is updated and another attempt counted.
+// @Tags         admin
+// @Produce      json
+// @Param        event_id  path      string  true  "Event ID"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Failure      403       {object}  map[string]string
+// @Failure      404       {object}  map[string]string
+// @Failure      409       {object}  map[string]string
+// @Failure      410       {object}  map[string]string
+// @Failure      503       {object}  map[string]string
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Router       /admin/dead-letters/{event_id}/retry [post]
+func (h retryDeadLetterHandler) handle(c *gin.Context) {
+	var uri deadLetterURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.deadLetters.Retry(uri.EventID)
+	var violation *domain.SequenceViolation
+
+	switch {
+	case errors.Is(err, domain.ErrDeadLetterNotFound), errors.Is(err, domain.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrEventConflict), errors.As(err, &violation):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrOrderAlreadyFinal):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrEventInProgress):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrConcurrentModification):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+	}
+}
+
+func newRetryDeadLetterHandler(deadLetters usecase.DeadLetters) retryDeadLetterHandler {
+	return retryDeadLetterHandler{deadLetters: deadLetters}
+}
@@ -1,11 +1,14 @@
 package http
 
 import (
-	"time"
+	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/therealyo/justdone/config"
 	"github.com/therealyo/justdone/docs"
 	"github.com/therealyo/justdone/internal/app"
+	"github.com/therealyo/justdone/internal/metrics"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -13,26 +16,36 @@ import (
 	_ "github.com/therealyo/justdone/docs"
 )
 
-const CLIENT_TIMEOUT = 1 * time.Minute
-
 type Server struct {
 	app    *app.Application
+	config *config.Config
 	router *gin.Engine
 }
 
-func NewServer(app *app.Application) *Server {
+func NewServer(app *app.Application, config *config.Config) *Server {
 	return &Server{
 		app:    app,
+		config: config,
 		router: gin.Default(),
 	}
 }
 
 func (s *Server) Setup() (*Server, error) {
 	docs.SwaggerInfo.Version = "1.0"
-	docs.SwaggerInfo.Host = "localhost:8080"
+	swaggerPort := s.config.Swagger.Port
+	if swaggerPort == 0 {
+		swaggerPort = s.config.App.Port
+	}
+	docs.SwaggerInfo.Host = fmt.Sprintf("%s:%d", s.config.Swagger.Host, swaggerPort)
 	docs.SwaggerInfo.BasePath = "/"
 	docs.SwaggerInfo.Schemes = []string{"http"}
 
+	// Without trusted proxies X-Forwarded-For is ignored, so callers can't pick
+	// a new address for every request to get around the rate limits
+	if err := s.router.SetTrustedProxies(s.config.HTTP.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	s.router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "OK",
@@ -41,25 +54,100 @@ func (s *Server) Setup() (*Server, error) {
 
 	s.router.POST(
 		"/webhooks/payments/orders",
-		newPostEventHandler(s.app.Events).handle,
+		newIdempotencyMiddleware(s.app.Idempotency, s.app.IdempotencyTTL),
+		newPostEventHandler(s.app.Events, s.app.SequenceViolationStatus).handle,
 	)
 
-	ordersGroup := s.router.Group("/orders")
+	metrics.Publish("sse_subscribers", func() interface{} {
+		return s.app.Subscriptions.Usage()
+	})
+	metrics.Publish("orders_rate_limit_buckets", func() interface{} {
+		return s.app.OrdersLimiter.Keys()
+	})
+	metrics.Publish("db_pools", func() interface{} {
+		return metrics.DBStats(s.app.Databases)
+	})
+
+	// Metrics include process details such as the command line, so only the back office can read them
+	s.router.GET(
+		"/metrics",
+		newAuthMiddleware(s.app.Auth),
+		newBackofficeMiddleware(s.app.Auth),
+		metrics.Handler(),
+	)
+
+	access := accessPolicy{auth: s.app.Auth}
+
+	ordersGroup := s.router.Group("/orders", newAuthMiddleware(s.app.Auth))
 
 	ordersGroup.GET(
 		":order_id/events",
-		newGetOrderEventsHandler(s.app.Orders, s.app.Notifier, CLIENT_TIMEOUT).handle,
+		newGetOrderEventsHandler(
+			s.app.Orders,
+			s.app.Notifier,
+			s.config.SSE.ClientTimeout,
+			s.config.SSE.ClientBuffer,
+			access,
+			s.app.Subscriptions,
+			s.app.SubscribeRetryAfter,
+		).handle,
 	)
 	ordersGroup.GET(
 		"",
-		newGetOrdersHandler(s.app.Orders).handle,
+		newRateLimitMiddleware(s.app.OrdersLimiter),
+		newGetOrdersHandler(s.app.Orders, access).handle,
+	)
+	ordersGroup.GET(
+		"export",
+		newRateLimitMiddleware(s.app.OrdersLimiter),
+		newExportOrdersHandler(s.app.Exports, access).handle,
+	)
+	ordersGroup.GET(
+		":order_id/events/export",
+		newExportOrderEventsHandler(s.app.Orders, s.app.Exports, access).handle,
+	)
+	ordersGroup.GET(
+		":order_id/timeline",
+		newGetOrderTimelineHandler(s.app.Orders, access).handle,
+	)
+	ordersGroup.GET(
+		"stuck",
+		newRateLimitMiddleware(s.app.OrdersLimiter),
+		newGetStuckOrdersHandler(s.app.StuckOrders, access).handle,
+	)
+	ordersGroup.GET(
+		"stats",
+		newRateLimitMiddleware(s.app.OrdersLimiter),
+		newGetOrderStatsHandler(s.app.Stats, access).handle,
+	)
+
+	adminGroup := s.router.Group(
+		"/admin",
+		newAuthMiddleware(s.app.Auth),
+		newBackofficeMiddleware(s.app.Auth),
 	)
 
+	adminGroup.GET("dead-letters", newGetDeadLettersHandler(s.app.DeadLetters).handle)
+	adminGroup.GET("dead-letters/:event_id", newGetDeadLetterHandler(s.app.DeadLetters).handle)
+	adminGroup.POST("dead-letters/:event_id/retry", newRetryDeadLetterHandler(s.app.DeadLetters).handle)
+	adminGroup.DELETE("dead-letters/:event_id", newDiscardDeadLetterHandler(s.app.DeadLetters).handle)
+
+	adminGroup.POST("reconciliation", newPostReconciliationHandler(s.app.Reconciliation).handle)
+	adminGroup.GET("reconciliation/corrections", newGetReconciliationCorrectionsHandler(s.app.Reconciliation).handle)
+
 	s.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	return s, nil
 }
 
 func (s *Server) Run(addr string) error {
-	return s.router.Run(addr)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: s.config.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       s.config.HTTP.ReadTimeout,
+		WriteTimeout:      s.config.HTTP.WriteTimeout,
+		IdleTimeout:       s.config.HTTP.IdleTimeout,
+	}
+	return server.ListenAndServe()
 }
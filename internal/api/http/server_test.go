@@ -0,0 +1,195 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/therealyo/justdone/config"
+	"github.com/therealyo/justdone/internal/app"
+)
+
+// newTestServer sets up the server over in-memory storage,
+// env overrides the defaults of the config.
+func newTestServer(t *testing.T, env map[string]string) *Server {
+	t.Helper()
+
+	t.Setenv("STORAGE", "memory")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	application, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create app: %v", err)
+	}
+	server, err := NewServer(application, cfg).Setup()
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	return server
+}
+
+// serve sends the request from remoteAddr with the headers.
+func serve(server *Server, method, path, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	server := newTestServer(t, map[string]string{"ORDERS_RATE_LIMIT": "0.001", "ORDERS_RATE_BURST": "1"})
+
+	if w := serve(server, http.MethodGet, "/orders", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", w.Code)
+	}
+
+	// A new forwarded address does not get the caller a new bucket
+	w := serve(server, http.MethodGet, "/orders", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.2"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the spoofed address to be rate limited, got %d", w.Code)
+	}
+}
+
+func TestRateLimitTrustsConfiguredProxies(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"ORDERS_RATE_LIMIT":    "0.001",
+		"ORDERS_RATE_BURST":    "1",
+		"HTTP_TRUSTED_PROXIES": "203.0.113.0/24",
+	})
+
+	// Clients behind the proxy are limited separately
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		if w := serve(server, http.MethodGet, "/orders", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": client}); w.Code != http.StatusOK {
+			t.Fatalf("Expected the first request of %s to pass, got %d", client, w.Code)
+		}
+	}
+	w := serve(server, http.MethodGet, "/orders", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the second request of a client to be rate limited, got %d", w.Code)
+	}
+}
+
+// backofficeRequests change or expose data of every user.
+var backofficeRequests = []struct {
+	method string
+	path   string
+}{
+	{http.MethodGet, "/metrics"},
+	{http.MethodGet, "/admin/dead-letters"},
+	{http.MethodPost, "/admin/dead-letters/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11/retry"},
+	{http.MethodDelete, "/admin/dead-letters/a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+	{http.MethodPost, "/admin/reconciliation?correct=true"},
+	{http.MethodGet, "/admin/reconciliation/corrections"},
+}
+
+func TestBackofficeEndpointsWithoutAuth(t *testing.T) {
+	server := newTestServer(t, map[string]string{"AUTH_ENABLED": "false"})
+
+	for _, r := range backofficeRequests {
+		w := serve(server, r.method, r.path, "203.0.113.7:1234", nil)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected %s %s to be forbidden without auth, got %d", r.method, r.path, w.Code)
+		}
+	}
+}
+
+func TestBackofficeEndpointsWithAuth(t *testing.T) {
+	const apiKey, secret = "backoffice-key", "jwt-secret"
+	server := newTestServer(t, map[string]string{
+		"AUTH_ENABLED":    "true",
+		"AUTH_API_KEYS":   apiKey,
+		"AUTH_JWT_SECRET": secret,
+	})
+	userToken := signToken(t, secret, jwt.MapClaims{"user_id": "c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"})
+
+	for _, r := range backofficeRequests {
+		if w := serve(server, r.method, r.path, "203.0.113.7:1234", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %s %s to require credentials, got %d", r.method, r.path, w.Code)
+		}
+
+		w := serve(server, r.method, r.path, "203.0.113.7:1234", map[string]string{"Authorization": "Bearer " + userToken})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected %s %s to be forbidden for end users, got %d", r.method, r.path, w.Code)
+		}
+	}
+
+	// The back office gets through
+	for _, path := range []string{"/metrics", "/admin/dead-letters"} {
+		if w := serve(server, http.MethodGet, path, "203.0.113.7:1234", map[string]string{"X-API-Key": apiKey}); w.Code != http.StatusOK {
+			t.Errorf("Expected GET %s to be allowed for the back office, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestEndUsersCannotReadOrdersOfOtherUsers(t *testing.T) {
+	const (
+		secret  = "jwt-secret"
+		orderID = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+		owner   = "c0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+		other   = "d0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	)
+	server := newTestServer(t, map[string]string{"AUTH_ENABLED": "true", "AUTH_JWT_SECRET": secret})
+
+	event := `{"event_id": "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "order_id": "` + orderID + `", "user_id": "` + owner + `",
+		"order_status": "cool_order_created", "created_at": "2024-08-01T12:00:00Z", "updated_at": "2024-08-01T12:00:00Z"}`
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks/payments/orders", strings.NewReader(event)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Failed to post event: %d %s", w.Code, w.Body)
+	}
+
+	ownerToken := map[string]string{"Authorization": "Bearer " + signToken(t, secret, jwt.MapClaims{"user_id": owner})}
+	otherToken := map[string]string{"Authorization": "Bearer " + signToken(t, secret, jwt.MapClaims{"user_id": other})}
+
+	for _, path := range []string{
+		"/orders/" + orderID + "/events",
+		"/orders/" + orderID + "/events/export",
+		"/orders/" + orderID + "/timeline",
+		"/orders?user_id=" + owner,
+		"/orders/export?user_id=" + owner,
+		"/orders/stats?user_id=" + owner,
+		"/orders/stuck?user_id=" + owner,
+	} {
+		if w := serve(server, http.MethodGet, path, "203.0.113.7:1234", otherToken); w.Code != http.StatusForbidden {
+			t.Errorf("Expected GET %s to be forbidden for another user, got %d", path, w.Code)
+		}
+	}
+
+	// The owner reads the order, other users' listings only contain their own orders
+	for _, path := range []string{"/orders/" + orderID + "/timeline", "/orders/" + orderID + "/events/export"} {
+		if w := serve(server, http.MethodGet, path, "203.0.113.7:1234", ownerToken); w.Code != http.StatusOK {
+			t.Errorf("Expected GET %s to be allowed for the owner, got %d", path, w.Code)
+		}
+	}
+	w = serve(server, http.MethodGet, "/orders", "203.0.113.7:1234", otherToken)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), orderID) {
+		t.Errorf("Expected no orders for another user, got %d %s", w.Code, w.Body)
+	}
+}
+
+// signToken returns an HMAC signed token with the claims, expiring in an hour unless set.
+func signToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/therealyo/justdone/domain"
+)
+
+const (
+	settlementFormatCSV  = "csv"
+	settlementFormatJSON = "json"
+)
+
+// settlementRow is a row of a settlement file, IsFinal defaults to the finality of the status.
+type settlementRow struct {
+	OrderID string `json:"order_id"`
+	UserID  string `json:"user_id"`
+	Status  string `json:"status"`
+	IsFinal *bool  `json:"is_final"`
+}
+
+// settlementFormat returns the requested format, or the one of the file extension.
+func settlementFormat(format, filename string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+	if format != settlementFormatCSV && format != settlementFormatJSON {
+		return "", fmt.Errorf("unsupported settlement format %q, expected csv or json", format)
+	}
+	return format, nil
+}
+
+// parseSettlement reads a CSV file with a header row or a JSON array of
+// objects. Both have order_id and status, and optionally user_id and is_final,
+// other columns are ignored.
+func parseSettlement(r io.Reader, format string) ([]domain.SettlementRecord, error) {
+	var rows []settlementRow
+	var err error
+	if format == settlementFormatJSON {
+		err = json.NewDecoder(r).Decode(&rows)
+	} else {
+		rows, err = readSettlementCSV(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse settlement file: %w", err)
+	}
+
+	records := make([]domain.SettlementRecord, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		record, err := row.record()
+		if err != nil {
+			return nil, fmt.Errorf("settlement row %d: %w", i+1, err)
+		}
+		if seen[record.OrderID] {
+			return nil, fmt.Errorf("settlement row %d: duplicate order_id %s", i+1, record.OrderID)
+		}
+		seen[record.OrderID] = true
+		records[i] = record
+	}
+
+	return records, nil
+}
+
+func readSettlementCSV(r io.Reader) ([]settlementRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"order_id", "status"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	var rows []settlementRow
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		row := settlementRow{OrderID: value("order_id"), UserID: value("user_id"), Status: value("status")}
+		if isFinal := value("is_final"); isFinal != "" {
+			parsed, err := strconv.ParseBool(isFinal)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid is_final %q", len(rows)+1, isFinal)
+			}
+			row.IsFinal = &parsed
+		}
+		rows = append(rows, row)
+	}
+}
+
+// record validates the row. IDs are returned in the canonical form the
+// storage returns them in, so records can be matched to stored orders.
+func (row settlementRow) record() (domain.SettlementRecord, error) {
+	orderID, err := uuid.Parse(row.OrderID)
+	if err != nil {
+		return domain.SettlementRecord{}, fmt.Errorf("invalid order_id %q", row.OrderID)
+	}
+
+	status, err := domain.ParseOrderStatus(row.Status)
+	if err != nil {
+		return domain.SettlementRecord{}, fmt.Errorf("invalid status %q", row.Status)
+	}
+
+	record := domain.SettlementRecord{OrderID: orderID.String(), Status: status, IsFinal: status.IsFinal()}
+	if row.IsFinal != nil {
+		record.IsFinal = *row.IsFinal
+	}
+	if row.UserID != "" {
+		userID, err := uuid.Parse(row.UserID)
+		if err != nil {
+			return domain.SettlementRecord{}, fmt.Errorf("invalid user_id %q", row.UserID)
+		}
+		record.UserID = userID.String()
+	}
+
+	return record, nil
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func TestParseSettlement(t *testing.T) {
+	csv := "order_id,amount,status,is_final\n" +
+		"A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11,100,chinazes,true\n" +
+		"b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11,200,failed,\n"
+	json := `[{"order_id": "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "status": "chinazes", "is_final": true},
+		{"order_id": "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "user_id": "", "status": "failed"}]`
+
+	want := []domain.SettlementRecord{
+		{OrderID: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", Status: domain.Chinazes, IsFinal: true},
+		{OrderID: "b0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", Status: domain.Failed, IsFinal: true},
+	}
+	for format, content := range map[string]string{settlementFormatCSV: csv, settlementFormatJSON: json} {
+		records, err := parseSettlement(strings.NewReader(content), format)
+		if err != nil {
+			t.Fatalf("Failed to parse %s settlement: %v", format, err)
+		}
+		if len(records) != len(want) || records[0] != want[0] || records[1] != want[1] {
+			t.Errorf("Expected %s settlement %+v, got %+v", format, want, records)
+		}
+	}
+
+	for _, content := range []string{
+		"status\nchinazes\n",
+		"order_id,status\na0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11,paid\n",
+		"order_id,status\na0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11,chinazes\nA0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11,chinazes\n",
+	} {
+		if _, err := parseSettlement(strings.NewReader(content), settlementFormatCSV); err == nil {
+			t.Errorf("Expected settlement %q to be rejected", content)
+		}
+	}
+}
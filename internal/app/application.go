@@ -1,44 +1,102 @@
 package app
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/therealyo/justdone/config"
 	"github.com/therealyo/justdone/domain"
-	"github.com/therealyo/justdone/infrastructure/database/postgres"
-	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/auth"
+	"github.com/therealyo/justdone/internal/ratelimit"
 	"github.com/therealyo/justdone/internal/sse"
 	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock"
 )
 
 type Application struct {
 	Orders   usecase.Orders
 	Events   usecase.Events
+	Stats    usecase.Stats
+	Exports  usecase.Exports
 	Notifier domain.OrderObserver
-}
+	// StuckOrders are orders staying in a status longer than its SLA
+	StuckOrders *usecase.StuckOrders
+	// DeadLetters are events the processor rejected, failed on or ignored
+	DeadLetters usecase.DeadLetters
+	// Reconciliation matches JustPay! settlement files to orders
+	Reconciliation usecase.Reconciliation
+	// SequenceViolationStatus is the webhook response status of events rejected for an illegal transition
+	SequenceViolationStatus int
+	// Idempotency stores webhook responses replayed to retried deliveries
+	Idempotency    domain.IdempotencyStore
+	IdempotencyTTL time.Duration
+	// Auth is nil when authentication of read endpoints is disabled
+	Auth *auth.Authenticator
+
+	OrdersLimiter       *ratelimit.KeyedLimiter
+	Subscriptions       *ratelimit.ConnectionLimiter
+	SubscribeRetryAfter time.Duration
 
-const ORDER_FINALIZING_TIMEOUT = 30 * time.Second
+	// Databases are the connection pools by name, empty for in-memory storage
+	Databases map[string]*sql.DB
+}
 
 func New(config *config.Config) (*Application, error) {
-	db, err := postgres.New(config.Postgres.ConnectionString)
+	storage, err := newStorage(config)
 	if err != nil {
 		return nil, err
 	}
 
-	sseNotifier := sse.NewSSENotifier()
-	orders := postgres.NewOrderRepository(db)
+	var authenticator *auth.Authenticator
+	if config.Auth.Enabled {
+		authenticator, err = auth.New(auth.Config{
+			APIKeys:         config.Auth.APIKeys,
+			JWTSecret:       config.Auth.JWTSecret,
+			JWKSFile:        config.Auth.JWKSFile,
+			BackofficeScope: config.Auth.BackofficeScope,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	systemClock := clock.New()
+	sseNotifier := sse.NewSSENotifier(systemClock)
 
 	orderProcessor := domain.NewOrderProcessor(
-		orders,
-		postgres.NewEventRepository(db),
+		storage.orders,
+		storage.events,
 		sseNotifier,
-		inmemory.NewProcessedEvents(),
-		ORDER_FINALIZING_TIMEOUT,
+		storage.processing,
+		storage.locker,
+		storage.deadLetters,
+		config.Orders.FinalizingTimeout,
+		systemClock,
 	)
 
 	return &Application{
-		Orders:   usecase.NewOrders(orders),
-		Events:   usecase.NewEvents(orderProcessor),
-		Notifier: sseNotifier,
+		Orders:                  usecase.NewOrders(storage.orders, config.Orders.CountExactLimit, systemClock),
+		Events:                  usecase.NewEvents(orderProcessor),
+		Stats:                   usecase.NewStats(storage.stats),
+		Exports:                 usecase.NewExports(storage.exports, systemClock),
+		DeadLetters:             usecase.NewDeadLetters(storage.deadLetters, orderProcessor),
+		Reconciliation:          usecase.NewReconciliation(storage.orders, storage.reconciliation, orderProcessor, systemClock),
+		Notifier:                sseNotifier,
+		StuckOrders:             usecase.NewStuckOrders(storage.orders, sseNotifier, config.StuckOrderSLA(), systemClock),
+		SequenceViolationStatus: config.Webhook.SequenceViolationStatus,
+		Idempotency:             storage.idempotency,
+		IdempotencyTTL:          config.Idempotency.TTL,
+		Auth:                    authenticator,
+		OrdersLimiter: ratelimit.NewKeyedLimiter(
+			config.RateLimit.OrdersPerSecond,
+			config.RateLimit.OrdersBurst,
+		),
+		Subscriptions: ratelimit.NewConnectionLimiter(ratelimit.ConnectionLimits{
+			PerOrder: config.RateLimit.SubscribersPerOrder,
+			PerUser:  config.RateLimit.SubscribersPerUser,
+			Total:    config.RateLimit.SubscribersTotal,
+		}),
+		SubscribeRetryAfter: config.RateLimit.SubscribeRetryAfter,
+		Databases:           storage.databases,
 	}, nil
 }
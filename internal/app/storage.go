@@ -0,0 +1,152 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/therealyo/justdone/config"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/infrastructure/database/postgres"
+	"github.com/therealyo/justdone/infrastructure/database/sqlite"
+	"github.com/therealyo/justdone/internal/inmemory"
+)
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+// storage groups repositories of the selected backend. Only Postgres can be
+// shared by several instances, so other backends lock orders in memory.
+type storage struct {
+	orders      domain.OrderRepository
+	events      domain.EventRepository
+	stats       domain.OrderStatsRepository
+	exports     domain.OrderExportRepository
+	processing  domain.ProcessedEvents
+	locker      domain.Locker
+	idempotency domain.IdempotencyStore
+	deadLetters domain.DeadLetterRepository
+	// reconciliation is the audit trail of reconciliation corrections
+	reconciliation domain.ReconciliationAuditRepository
+	// databases are the connection pools by name, for exporting their stats
+	databases map[string]*sql.DB
+}
+
+func newStorage(config *config.Config) (*storage, error) {
+	switch config.Storage.Driver {
+	case StoragePostgres:
+		if config.Postgres.ConnectionString == "" {
+			return nil, errors.New("POSTGRES_URL is required for postgres storage")
+		}
+
+		db, err := openPostgres(config.Postgres.ConnectionString, config)
+		if err != nil {
+			return nil, err
+		}
+		databases := map[string]*sql.DB{"primary": db}
+
+		// A held order lock keeps its connection while the event is processed
+		// with another one. With a shared limited pool, lock holders could take
+		// every connection and wait for each other forever.
+		locks, err := openPostgres(config.Postgres.ConnectionString, config)
+		if err != nil {
+			closeDatabases(databases)
+			return nil, err
+		}
+		locks.SetMaxOpenConns(config.Database.MaxLockConns)
+		databases["locks"] = locks
+
+		orders := postgres.NewOrderRepository(db)
+		if config.Postgres.ReplicaConnectionString != "" {
+			replica, err := openPostgres(config.Postgres.ReplicaConnectionString, config)
+			if err != nil {
+				closeDatabases(databases)
+				return nil, fmt.Errorf("replica: %w", err)
+			}
+			databases["replica"] = replica
+			orders = orders.WithReplica(replica)
+		}
+
+		return &storage{
+			orders:         orders,
+			events:         postgres.NewEventRepository(db),
+			stats:          orders,
+			exports:        orders,
+			processing:     postgres.NewProcessedEvents(db, config.Orders.ProcessingStaleAfter),
+			locker:         postgres.NewLocker(locks),
+			idempotency:    postgres.NewIdempotencyStore(db),
+			deadLetters:    postgres.NewDeadLetterRepository(db),
+			reconciliation: postgres.NewReconciliationRepository(db),
+			databases:      databases,
+		}, nil
+	case StorageSQLite:
+		db, err := sqlite.New(config.SQLite.Path)
+		if err != nil {
+			return nil, err
+		}
+		configurePool(db, config)
+
+		orders := sqlite.NewOrderRepository(db)
+		return &storage{
+			orders:         orders,
+			events:         sqlite.NewEventRepository(db),
+			stats:          orders,
+			exports:        orders,
+			processing:     inmemory.NewProcessedEvents(),
+			locker:         inmemory.NewLocker(),
+			idempotency:    sqlite.NewIdempotencyStore(db),
+			deadLetters:    sqlite.NewDeadLetterRepository(db),
+			reconciliation: sqlite.NewReconciliationRepository(db),
+			databases:      map[string]*sql.DB{"primary": db},
+		}, nil
+	case StorageMemory:
+		memory := inmemory.NewStorage()
+		orders := inmemory.NewOrderRepository(memory)
+		return &storage{
+			orders:         orders,
+			events:         inmemory.NewEventRepository(memory),
+			stats:          orders,
+			exports:        orders,
+			processing:     inmemory.NewProcessedEvents(),
+			locker:         inmemory.NewLocker(),
+			idempotency:    inmemory.NewIdempotencyStore(),
+			deadLetters:    inmemory.NewDeadLetterRepository(),
+			reconciliation: inmemory.NewReconciliationRepository(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", config.Storage.Driver)
+	}
+}
+
+// openPostgres connects to Postgres, retrying while it starts up.
+func openPostgres(connection string, config *config.Config) (*sql.DB, error) {
+	db, err := postgres.New(connection, postgres.Retry{
+		Attempts:   config.Database.ConnectAttempts,
+		Backoff:    config.Database.ConnectBackoff,
+		MaxBackoff: config.Database.ConnectMaxBackoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+	configurePool(db, config)
+	return db, nil
+}
+
+// closeDatabases closes the pools opened before a later one failed.
+func closeDatabases(databases map[string]*sql.DB) {
+	for name, db := range databases {
+		if err := db.Close(); err != nil {
+			fmt.Printf("error closing %s database: %v\n", name, err)
+		}
+	}
+}
+
+func configurePool(db *sql.DB, config *config.Config) {
+	db.SetMaxOpenConns(config.Database.MaxOpenConns)
+	db.SetMaxIdleConns(config.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(config.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.Database.ConnMaxIdleTime)
+}
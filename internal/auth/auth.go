@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrForbidden       = errors.New("access to resource is forbidden")
+)
+
+// Principal describes the caller of a read endpoint.
+type Principal struct {
+	UserID string
+	Scopes []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Config holds the credentials accepted by the Authenticator.
+type Config struct {
+	APIKeys         []string
+	JWTSecret       string
+	JWKSFile        string
+	BackofficeScope string
+}
+
+// Authenticator validates static API keys and JWT bearer tokens.
+// API keys are treated as back office credentials, while tokens must carry
+// a user_id claim unless they are granted the back office scope.
+type Authenticator struct {
+	apiKeys         [][]byte
+	secret          []byte
+	keys            *keySet
+	backofficeScope string
+}
+
+func (a *Authenticator) AuthenticateAPIKey(key string) (*Principal, error) {
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare(k, []byte(key)) == 1 {
+			return &Principal{Scopes: []string{a.backofficeScope}}, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+func (a *Authenticator) AuthenticateToken(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	// Tokens without exp would be valid forever
+	parsed, err := jwt.ParseWithClaims(token, claims, a.keyFunc, jwt.WithValidMethods(a.validMethods()), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		return nil, ErrUnauthenticated
+	}
+
+	principal := &Principal{Scopes: scopesFromClaims(claims)}
+	if userID, ok := claims["user_id"].(string); ok {
+		principal.UserID = userID
+	}
+
+	if principal.UserID == "" && !principal.HasScope(a.backofficeScope) {
+		return nil, ErrUnauthenticated
+	}
+
+	return principal, nil
+}
+
+// IsBackoffice reports whether the principal may read orders of any user.
+func (a *Authenticator) IsBackoffice(p *Principal) bool {
+	return p != nil && p.HasScope(a.backofficeScope)
+}
+
+// CanAccess reports whether the principal may read orders of the given user.
+func (a *Authenticator) CanAccess(p *Principal, userID string) bool {
+	if a.IsBackoffice(p) {
+		return true
+	}
+	return p != nil && p.UserID != "" && p.UserID == userID
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.secret) == 0 {
+			return nil, errors.New("hmac tokens are not accepted")
+		}
+		return a.secret, nil
+	default:
+		if a.keys == nil {
+			return nil, errors.New("asymmetric tokens are not accepted")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return a.keys.lookup(kid)
+	}
+}
+
+func (a *Authenticator) validMethods() []string {
+	var methods []string
+	if len(a.secret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if a.keys != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "ES256", "ES384", "ES512")
+	}
+	return methods
+}
+
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	var scopes []string
+
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(scope)...)
+	}
+
+	if list, ok := claims["scopes"].([]interface{}); ok {
+		for _, s := range list {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+	}
+
+	return scopes
+}
+
+func New(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{
+		secret:          []byte(cfg.JWTSecret),
+		backofficeScope: cfg.BackofficeScope,
+	}
+
+	for _, key := range cfg.APIKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			a.apiKeys = append(a.apiKeys, []byte(key))
+		}
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadKeySet(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+
+	if len(a.apiKeys) == 0 && len(a.secret) == 0 && a.keys == nil {
+		return nil, errors.New("auth enabled but no api keys, jwt secret or jwks file configured")
+	}
+
+	return a, nil
+}
@@ -0,0 +1,113 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/therealyo/justdone/internal/auth"
+)
+
+const secret = "test-secret"
+
+func newAuthenticator(t *testing.T) *auth.Authenticator {
+	t.Helper()
+	authenticator, err := auth.New(auth.Config{
+		APIKeys:         []string{"backoffice-key"},
+		JWTSecret:       secret,
+		BackofficeScope: "orders:read:all",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	return authenticator
+}
+
+func signToken(t *testing.T, key string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestUserTokenIsRestrictedToOwnOrders(t *testing.T) {
+	authenticator := newAuthenticator(t)
+
+	token := signToken(t, secret, jwt.MapClaims{
+		"user_id": "user1",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+
+	principal, err := authenticator.AuthenticateToken(token)
+	if err != nil {
+		t.Fatalf("Expected token to be accepted, got %v", err)
+	}
+
+	if !authenticator.CanAccess(principal, "user1") {
+		t.Errorf("Expected user1 to access own orders")
+	}
+
+	if authenticator.CanAccess(principal, "user2") {
+		t.Errorf("Expected user1 not to access orders of user2")
+	}
+}
+
+func TestBackofficeScopeBypassesUserCheck(t *testing.T) {
+	authenticator := newAuthenticator(t)
+
+	token := signToken(t, secret, jwt.MapClaims{
+		"scope": "profile orders:read:all",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	})
+
+	principal, err := authenticator.AuthenticateToken(token)
+	if err != nil {
+		t.Fatalf("Expected token to be accepted, got %v", err)
+	}
+
+	if !authenticator.CanAccess(principal, "user2") {
+		t.Errorf("Expected back office token to access orders of any user")
+	}
+
+	principal, err = authenticator.AuthenticateAPIKey("backoffice-key")
+	if err != nil {
+		t.Fatalf("Expected api key to be accepted, got %v", err)
+	}
+
+	if !authenticator.IsBackoffice(principal) {
+		t.Errorf("Expected api key to grant back office access")
+	}
+}
+
+func TestRejectsInvalidCredentials(t *testing.T) {
+	authenticator := newAuthenticator(t)
+
+	if _, err := authenticator.AuthenticateAPIKey("unknown"); err != auth.ErrUnauthenticated {
+		t.Errorf("Expected unknown api key to be rejected, got %v", err)
+	}
+
+	forged := signToken(t, "other-secret", jwt.MapClaims{"user_id": "user1", "exp": time.Now().Add(time.Minute).Unix()})
+	if _, err := authenticator.AuthenticateToken(forged); err != auth.ErrUnauthenticated {
+		t.Errorf("Expected token with wrong signature to be rejected, got %v", err)
+	}
+
+	expired := signToken(t, secret, jwt.MapClaims{
+		"user_id": "user1",
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	})
+	if _, err := authenticator.AuthenticateToken(expired); err != auth.ErrUnauthenticated {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+
+	neverExpiring := signToken(t, secret, jwt.MapClaims{"user_id": "user1"})
+	if _, err := authenticator.AuthenticateToken(neverExpiring); err != auth.ErrUnauthenticated {
+		t.Errorf("Expected token without exp to be rejected, got %v", err)
+	}
+
+	anonymous := signToken(t, secret, jwt.MapClaims{"scope": "profile", "exp": time.Now().Add(time.Minute).Unix()})
+	if _, err := authenticator.AuthenticateToken(anonymous); err != auth.ErrUnauthenticated {
+		t.Errorf("Expected token without user_id to be rejected, got %v", err)
+	}
+}
@@ -9,12 +9,12 @@ import (
 type ConsoleNotifier struct{}
 
 // RegisterClient implements domain.OrderObserver.
-func (c *ConsoleNotifier) RegisterClient(orderID string, client domain.OrderEventsSubscriber) {
+func (c *ConsoleNotifier) RegisterClient(orderID string, client *domain.OrderEventsSubscriber) {
 	panic("unimplemented")
 }
 
 // UnregisterClient implements domain.OrderObserver.
-func (c *ConsoleNotifier) UnregisterClient(orderID string, client domain.OrderEventsSubscriber) {
+func (c *ConsoleNotifier) UnregisterClient(orderID string, client *domain.OrderEventsSubscriber) {
 	panic("unimplemented")
 }
 
@@ -22,6 +22,14 @@ func (c *ConsoleNotifier) Notify(order *domain.Order, event domain.OrderEvent) {
 	fmt.Printf("Order %s has been updated with status %s, isFinal: %t\n", order.OrderID, event.OrderStatus, order.IsFinal)
 }
 
+func (c *ConsoleNotifier) NotifyAnomaly(order *domain.Order, violation domain.SequenceViolation) {
+	fmt.Printf("Order %s rejected event %s: %s\n", order.OrderID, violation.EventID, violation.Error())
+}
+
+func (c *ConsoleNotifier) NotifyStuck(stuck domain.OrderStuck) {
+	fmt.Printf("Order %s is stuck in status %s for %.0fs, SLA is %.0fs\n", stuck.OrderID, stuck.Status, stuck.StuckSeconds, stuck.SLASeconds)
+}
+
 func (c *ConsoleNotifier) AddProcessedEvent(orderID string, event domain.OrderEvent) {
 	fmt.Printf("Order %s has been updated with status %s, isFinal: %t\n", orderID, event.OrderStatus, event.IsFinal)
 }
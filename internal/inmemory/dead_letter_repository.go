@@ -0,0 +1,94 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.DeadLetterRepository = new(DeadLetterRepository)
+
+type DeadLetterRepository struct {
+	mu     sync.RWMutex
+	events map[string]domain.DeadLetterEvent
+}
+
+func (r *DeadLetterRepository) Add(event domain.OrderEvent, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	stored, exists := r.events[event.EventID]
+	if !exists {
+		stored = domain.DeadLetterEvent{
+			EventID:   event.EventID,
+			OrderID:   event.OrderID,
+			CreatedAt: now,
+		}
+	}
+
+	stored.Reason = reason
+	stored.Payload = event
+	stored.Attempts++
+	stored.UpdatedAt = now
+	r.events[event.EventID] = stored
+
+	return nil
+}
+
+func (r *DeadLetterRepository) Get(eventID string) (*domain.DeadLetterEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	event, exists := r.events[eventID]
+	if !exists {
+		return nil, nil
+	}
+
+	return &event, nil
+}
+
+func (r *DeadLetterRepository) List(filter domain.DeadLetterFilter) ([]domain.DeadLetterEvent, error) {
+	r.mu.RLock()
+	var events []domain.DeadLetterEvent
+	for _, event := range r.events {
+		if filter.OrderID == "" || event.OrderID == filter.OrderID {
+			events = append(events, event)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].UpdatedAt.Equal(events[j].UpdatedAt) {
+			return events[i].UpdatedAt.After(events[j].UpdatedAt)
+		}
+		return events[i].EventID < events[j].EventID
+	})
+
+	if filter.Offset >= len(events) {
+		return nil, nil
+	}
+	events = events[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(events) {
+		events = events[:filter.Limit]
+	}
+
+	return events, nil
+}
+
+func (r *DeadLetterRepository) Delete(eventIDs ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, eventID := range eventIDs {
+		delete(r.events, eventID)
+	}
+
+	return nil
+}
+
+func NewDeadLetterRepository() *DeadLetterRepository {
+	return &DeadLetterRepository{events: make(map[string]domain.DeadLetterEvent)}
+}
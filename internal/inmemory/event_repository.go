@@ -0,0 +1,75 @@
+package inmemory
+
+import (
+	"errors"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.EventRepository = new(EventRepository)
+
+var errOrderMissing = errors.New("event references missing order")
+
+type EventRepository struct {
+	storage *Storage
+}
+
+func (r *EventRepository) Get(eventID string) (*domain.OrderEvent, error) {
+	r.storage.mu.RLock()
+	defer r.storage.mu.RUnlock()
+
+	if event, exists := r.storage.events[eventID]; exists {
+		return &event, nil
+	}
+	return nil, nil
+}
+
+// Create stores a new event. Like the order_events foreign key,
+// it requires the order to be saved first.
+func (r *EventRepository) Create(event domain.OrderEvent) error {
+	r.storage.mu.Lock()
+	defer r.storage.mu.Unlock()
+
+	if _, exists := r.storage.events[event.EventID]; exists {
+		return domain.ErrEventConflict
+	}
+
+	if _, exists := r.storage.orders[event.OrderID]; !exists {
+		return errOrderMissing
+	}
+
+	r.storage.events[event.EventID] = event
+	return nil
+}
+
+// Update changes status, finality, update time and rejected reason of an existing event.
+// Updating a missing event is a no-op.
+func (r *EventRepository) Update(event domain.OrderEvent) error {
+	r.storage.mu.Lock()
+	defer r.storage.mu.Unlock()
+
+	stored, exists := r.storage.events[event.EventID]
+	if !exists {
+		return nil
+	}
+
+	stored.OrderStatus = event.OrderStatus
+	stored.IsFinal = event.IsFinal
+	stored.UpdatedAt = event.UpdatedAt
+	stored.RejectedReason = event.RejectedReason
+	r.storage.events[event.EventID] = stored
+
+	return nil
+}
+
+func (r *EventRepository) Delete(eventID string) error {
+	r.storage.mu.Lock()
+	defer r.storage.mu.Unlock()
+
+	delete(r.storage.events, eventID)
+	return nil
+}
+
+func NewEventRepository(storage *Storage) *EventRepository {
+	return &EventRepository{storage: storage}
+}
@@ -0,0 +1,50 @@
+package inmemory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.IdempotencyStore = new(IdempotencyStore)
+
+type IdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]domain.IdempotentResponse
+}
+
+func (s *IdempotencyStore) Get(key string) (*domain.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response, exists := s.responses[key]
+	if !exists || !time.Now().Before(response.ExpiresAt) {
+		return nil, nil
+	}
+
+	return &response, nil
+}
+
+// Save stores the response and drops expired ones.
+func (s *IdempotencyStore) Save(response domain.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, stored := range s.responses {
+		if !now.Before(stored.ExpiresAt) {
+			delete(s.responses, key)
+		}
+	}
+
+	if _, exists := s.responses[response.Key]; !exists {
+		s.responses[response.Key] = response
+	}
+
+	return nil
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{responses: make(map[string]domain.IdempotentResponse)}
+}
@@ -0,0 +1,51 @@
+package inmemory
+
+import (
+	"sync"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.Locker = new(Locker)
+
+// Locker locks orders within a single instance. Locks of different orders
+// are independent, and unused locks are dropped.
+type Locker struct {
+	mu    sync.Mutex
+	locks map[string]*orderLock
+}
+
+type orderLock struct {
+	mu sync.Mutex
+	// holders counts goroutines holding or waiting for the lock
+	holders int
+}
+
+func (l *Locker) Lock(orderID string) (func(), error) {
+	l.mu.Lock()
+	lock, exists := l.locks[orderID]
+	if !exists {
+		lock = &orderLock{}
+		l.locks[orderID] = lock
+	}
+	lock.holders++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		lock.holders--
+		if lock.holders == 0 {
+			delete(l.locks, orderID)
+		}
+	}, nil
+}
+
+func NewLocker() *Locker {
+	return &Locker{locks: make(map[string]*orderLock)}
+}
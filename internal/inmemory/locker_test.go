@@ -0,0 +1,73 @@
+package inmemory_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/internal/inmemory"
+)
+
+func TestLockerSerializesOrder(t *testing.T) {
+	locker := inmemory.NewLocker()
+
+	var mu sync.Mutex
+	var active, maxActive int
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := locker.Lock("order1")
+			if err != nil {
+				t.Errorf("Failed to lock order: %v", err)
+				return
+			}
+			defer unlock()
+
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("Expected one holder of the order lock at a time, got %d", maxActive)
+	}
+}
+
+func TestLockerIndependentOrders(t *testing.T) {
+	locker := inmemory.NewLocker()
+
+	unlock, err := locker.Lock("order1")
+	if err != nil {
+		t.Fatalf("Failed to lock order1: %v", err)
+	}
+	defer unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		unlockOther, err := locker.Lock("order2")
+		if err == nil {
+			unlockOther()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected order2 to be locked while order1 is locked")
+	}
+}
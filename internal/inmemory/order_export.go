@@ -0,0 +1,38 @@
+package inmemory
+
+import (
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.OrderExportRepository = new(OrderRepository)
+
+// StreamOrders calls fn for every order matching the filter. Matching orders
+// are snapshotted first, so fn may call back into the repository.
+func (r *OrderRepository) StreamOrders(filter *domain.OrderFilter, fn func(domain.Order) error) error {
+	orders, err := r.GetMany(filter)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) StreamEvents(orderID string, fn func(domain.OrderEvent) error) error {
+	r.storage.mu.RLock()
+	events := r.storage.orderEvents(orderID)
+	r.storage.mu.RUnlock()
+
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
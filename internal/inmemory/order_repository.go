@@ -0,0 +1,224 @@
+package inmemory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.OrderRepository = new(OrderRepository)
+
+type OrderRepository struct {
+	storage *Storage
+}
+
+func (r *OrderRepository) Get(orderID string) (*domain.Order, error) {
+	r.storage.mu.RLock()
+	defer r.storage.mu.RUnlock()
+
+	order, exists := r.storage.orders[orderID]
+	if !exists {
+		return nil, nil
+	}
+
+	order.Events = r.storage.orderEvents(orderID)
+	if len(order.Events) > 0 {
+		order.LastEvent = &order.Events[len(order.Events)-1]
+	}
+
+	return &order, nil
+}
+
+func (r *OrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, error) {
+	r.storage.mu.RLock()
+	defer r.storage.mu.RUnlock()
+
+	orders := r.filter(filter)
+
+	if filter.Offset >= len(orders) {
+		return nil, nil
+	}
+	orders = orders[filter.Offset:]
+
+	// A non-positive limit selects all matching orders, which is used by exports
+	if filter.Limit > 0 && filter.Limit < len(orders) {
+		orders = orders[:filter.Limit]
+	}
+
+	return orders, nil
+}
+
+func (r *OrderRepository) Count(filter *domain.OrderFilter, exactLimit int) (*domain.OrderCount, error) {
+	r.storage.mu.RLock()
+	defer r.storage.mu.RUnlock()
+
+	return &domain.OrderCount{Total: len(r.filter(filter))}, nil
+}
+
+// Save stores the order row only, events are stored with EventRepository.
+// Like the database repositories it compares versions before writing.
+func (r *OrderRepository) Save(order *domain.Order) error {
+	r.storage.mu.Lock()
+	defer r.storage.mu.Unlock()
+
+	// A missing order has version zero, so new orders must have zero version too
+	if current := r.storage.orders[order.OrderID]; current.Version != order.Version {
+		return domain.ErrConcurrentModification
+	}
+
+	order.Version++
+	stored := *order
+	stored.Events = nil
+	stored.LastEvent = nil
+	r.storage.orders[order.OrderID] = stored
+
+	return nil
+}
+
+// filter returns sorted orders matching the filter without events.
+// The caller must hold the lock.
+func (r *OrderRepository) filter(filter *domain.OrderFilter) []domain.Order {
+	var orders []domain.Order
+	for _, order := range r.storage.orders {
+		if r.matches(filter, order) {
+			orders = append(orders, order)
+		}
+	}
+
+	sortOrders(orders, filter.Sort)
+
+	return orders
+}
+
+func (r *OrderRepository) matches(filter *domain.OrderFilter, order domain.Order) bool {
+	if len(filter.Status) > 0 && !contains(filter.Status, order.Status) {
+		return false
+	}
+
+	if len(filter.UserIDs) > 0 && !contains(filter.UserIDs, order.UserID) {
+		return false
+	}
+
+	if len(filter.OrderIDs) > 0 && !contains(filter.OrderIDs, order.OrderID) {
+		return false
+	}
+
+	if filter.IsFinal != nil && order.IsFinal != *filter.IsFinal {
+		return false
+	}
+
+	if !inRange(order.CreatedAt, filter.CreatedFrom, filter.CreatedTo) ||
+		!inRange(order.UpdatedAt, filter.UpdatedFrom, filter.UpdatedTo) {
+		return false
+	}
+
+	if len(filter.EverInStatus) > 0 && !r.everInStatus(order.OrderID, filter.EverInStatus) {
+		return false
+	}
+
+	if (filter.AmountFrom != nil || filter.AmountTo != nil) && order.Amount == nil {
+		return false
+	}
+
+	if filter.AmountFrom != nil && *order.Amount < *filter.AmountFrom {
+		return false
+	}
+
+	if filter.AmountTo != nil && *order.Amount > *filter.AmountTo {
+		return false
+	}
+
+	if len(filter.Currencies) > 0 && (order.Amount == nil || !contains(filter.Currencies, order.Currency)) {
+		return false
+	}
+
+	if filter.StuckFor > 0 && (order.IsFinal || !order.UpdatedAt.Before(filter.StuckBefore())) {
+		return false
+	}
+
+	return true
+}
+
+func (r *OrderRepository) everInStatus(orderID string, statuses []domain.OrderStatus) bool {
+	for _, event := range r.storage.events {
+		if event.OrderID == orderID && event.RejectedReason == "" && contains(statuses, event.OrderStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortOrders sorts by the requested keys with order_id as the tie-breaker.
+func sortOrders(orders []domain.Order, orderSort domain.OrderSort) {
+	if len(orderSort) == 0 {
+		orderSort = domain.DefaultOrderSort
+	}
+	keys := orderSort.WithTieBreaker()
+
+	sort.SliceStable(orders, func(i, j int) bool {
+		for _, key := range keys {
+			cmp := compareField(orders[i], orders[j], key.Field)
+			if cmp == 0 {
+				continue
+			}
+			if key.Descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func compareField(a, b domain.Order, field domain.SortField) int {
+	switch field {
+	case domain.SortByCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case domain.SortByUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case domain.SortByOrderID:
+		return compareStrings(a.OrderID, b.OrderID)
+	case domain.SortByUserID:
+		return compareStrings(a.UserID, b.UserID)
+	case domain.SortByStatus:
+		return compareStrings(string(a.Status), string(b.Status))
+	default:
+		return 0
+	}
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inRange checks from <= t < to, nil bounds are open.
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	if to != nil && !t.Before(*to) {
+		return false
+	}
+	return true
+}
+
+func NewOrderRepository(storage *Storage) *OrderRepository {
+	return &OrderRepository{storage: storage}
+}
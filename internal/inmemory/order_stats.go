@@ -0,0 +1,167 @@
+package inmemory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.OrderStatsRepository = new(OrderRepository)
+
+type transitionKey struct {
+	from domain.OrderStatus
+	to   domain.OrderStatus
+}
+
+func (r *OrderRepository) Stats(filter *domain.OrderStatsFilter) (*domain.OrderStats, error) {
+	r.storage.mu.RLock()
+	defer r.storage.mu.RUnlock()
+
+	stats := &domain.OrderStats{GroupBy: filter.GroupBy}
+	groups := make(map[string]int)
+	durations := make(map[transitionKey][]float64)
+	amounts := make(map[string]*domain.CurrencyTotal)
+
+	for _, order := range r.storage.orders {
+		if filter.UserID != "" && order.UserID != filter.UserID {
+			continue
+		}
+		if !inRange(order.CreatedAt, filter.From, filter.To) {
+			continue
+		}
+
+		stats.Total++
+		groups[groupKey(order, filter.GroupBy)]++
+
+		if order.Amount != nil {
+			total, ok := amounts[order.Currency]
+			if !ok {
+				total = &domain.CurrencyTotal{Currency: order.Currency}
+				amounts[order.Currency] = total
+			}
+			total.Count++
+			total.Amount += *order.Amount
+		}
+
+		events := appliedEvents(r.storage.orderEvents(order.OrderID))
+		var chinazes, refunded, canceled bool
+		for i, event := range events {
+			switch event.OrderStatus {
+			case domain.Chinazes:
+				chinazes = true
+			case domain.GiveMyMoneyBack:
+				refunded = true
+			case domain.ChangedMyMind, domain.Failed:
+				canceled = true
+			}
+
+			if i > 0 {
+				key := transitionKey{from: events[i-1].OrderStatus, to: event.OrderStatus}
+				durations[key] = append(durations[key], event.CreatedAt.Sub(events[i-1].CreatedAt).Seconds())
+			}
+		}
+
+		if chinazes {
+			stats.Chinazes++
+		}
+		if refunded {
+			stats.Refunded++
+		}
+		if canceled {
+			stats.Canceled++
+		}
+	}
+
+	stats.Groups = sortedGroups(groups, filter.GroupBy)
+	stats.Transitions = transitionStats(durations)
+	stats.Amounts = currencyTotals(amounts)
+	stats.CalculateRates()
+
+	return stats, nil
+}
+
+func groupKey(order domain.Order, groupBy domain.StatsGroupBy) string {
+	switch groupBy {
+	case domain.GroupByDay:
+		return order.CreatedAt.UTC().Truncate(24 * time.Hour).Format(time.RFC3339)
+	case domain.GroupByHour:
+		return order.CreatedAt.UTC().Truncate(time.Hour).Format(time.RFC3339)
+	default:
+		return string(order.Status)
+	}
+}
+
+func sortedGroups(groups map[string]int, groupBy domain.StatsGroupBy) []domain.StatsGroup {
+	result := make([]domain.StatsGroup, 0, len(groups))
+	for key, count := range groups {
+		result = append(result, domain.StatsGroup{Key: key, Count: count})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if groupBy == domain.GroupByStatus || groupBy == "" {
+			return domain.OrderStatus(result[i].Key).Value() < domain.OrderStatus(result[j].Key).Value()
+		}
+		// RFC3339 timestamps in UTC sort lexicographically
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+func transitionStats(durations map[transitionKey][]float64) []domain.TransitionStats {
+	result := make([]domain.TransitionStats, 0, len(durations))
+	for key, values := range durations {
+		result = append(result, domain.TransitionStats{
+			From:          key.from,
+			To:            key.to,
+			Count:         len(values),
+			MedianSeconds: median(values),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].From.Value() != result[j].From.Value() {
+			return result[i].From.Value() < result[j].From.Value()
+		}
+		return result[i].To.Value() < result[j].To.Value()
+	})
+
+	return result
+}
+
+// median interpolates between the middle values like percentile_cont(0.5).
+func median(values []float64) float64 {
+	sort.Float64s(values)
+
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+func currencyTotals(amounts map[string]*domain.CurrencyTotal) []domain.CurrencyTotal {
+	totals := make([]domain.CurrencyTotal, 0, len(amounts))
+	for _, total := range amounts {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals
+}
+
+// appliedEvents drops events that are stored but were rejected, they never changed the order.
+func appliedEvents(events []domain.OrderEvent) []domain.OrderEvent {
+	applied := events[:0]
+	for _, event := range events {
+		if event.RejectedReason == "" {
+			applied = append(applied, event)
+		}
+	}
+	return applied
+}
@@ -8,27 +8,29 @@ import (
 
 var _ domain.ProcessedEvents = new(ProcessedEvents)
 
+// ProcessedEvents is shared by processors of a single instance only.
 type ProcessedEvents struct {
 	mu     sync.Mutex
 	events map[string]bool
 }
 
-func (c *ProcessedEvents) Add(eventID string) {
+func (c *ProcessedEvents) Add(eventID string) (bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
+	if c.events[eventID] {
+		return false, nil
+	}
 	c.events[eventID] = true
-}
 
-func (c *ProcessedEvents) Contains(eventID string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.events[eventID]
+	return true, nil
 }
 
-func (c *ProcessedEvents) Remove(eventID string) {
+func (c *ProcessedEvents) Remove(eventID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.events, eventID)
+	return nil
 }
 
 func NewProcessedEvents() *ProcessedEvents {
@@ -0,0 +1,60 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+var _ domain.ReconciliationAuditRepository = new(ReconciliationRepository)
+
+type ReconciliationRepository struct {
+	mu          sync.RWMutex
+	corrections []domain.ReconciliationCorrection
+}
+
+func (r *ReconciliationRepository) Add(correction domain.ReconciliationCorrection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.corrections = append(r.corrections, correction)
+	return nil
+}
+
+func (r *ReconciliationRepository) List(filter domain.ReconciliationCorrectionFilter) ([]domain.ReconciliationCorrection, error) {
+	r.mu.RLock()
+	var corrections []domain.ReconciliationCorrection
+	for _, correction := range r.corrections {
+		if (filter.RunID == "" || correction.RunID == filter.RunID) &&
+			(filter.OrderID == "" || correction.OrderID == filter.OrderID) {
+			corrections = append(corrections, correction)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(corrections, func(i, j int) bool {
+		a, b := corrections[i], corrections[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		if a.RunID != b.RunID {
+			return a.RunID < b.RunID
+		}
+		return a.EventID < b.EventID
+	})
+
+	if filter.Offset >= len(corrections) {
+		return nil, nil
+	}
+	corrections = corrections[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(corrections) {
+		corrections = corrections[:filter.Limit]
+	}
+
+	return corrections, nil
+}
+
+func NewReconciliationRepository() *ReconciliationRepository {
+	return &ReconciliationRepository{}
+}
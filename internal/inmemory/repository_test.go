@@ -0,0 +1,22 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/repotest"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		storage := inmemory.NewStorage()
+		return repotest.Repositories{
+			Orders:         inmemory.NewOrderRepository(storage),
+			Events:         inmemory.NewEventRepository(storage),
+			DeadLetters:    inmemory.NewDeadLetterRepository(),
+			Reconciliation: inmemory.NewReconciliationRepository(),
+			Stats:          inmemory.NewOrderRepository(storage),
+			Idempotency:    inmemory.NewIdempotencyStore(),
+		}
+	})
+}
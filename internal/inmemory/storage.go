@@ -0,0 +1,44 @@
+package inmemory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// Storage keeps orders and their events in memory. It is shared by
+// OrderRepository and EventRepository the same way both Postgres
+// repositories share the database, so orders are returned with their events.
+type Storage struct {
+	mu     sync.RWMutex
+	orders map[string]domain.Order
+	events map[string]domain.OrderEvent
+}
+
+// orderEvents returns events of the order sorted by creation time.
+// The caller must hold the lock.
+func (s *Storage) orderEvents(orderID string) []domain.OrderEvent {
+	var events []domain.OrderEvent
+	for _, event := range s.events {
+		if event.OrderID == orderID {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].EventID < events[j].EventID
+		}
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+
+	return events
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		orders: make(map[string]domain.Order),
+		events: make(map[string]domain.OrderEvent),
+	}
+}
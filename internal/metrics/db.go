@@ -0,0 +1,36 @@
+package metrics
+
+import "database/sql"
+
+// PoolStats is sql.DBStats of a connection pool as exported in metrics.
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMillis int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// DBStats returns the current stats of every pool by name.
+func DBStats(databases map[string]*sql.DB) map[string]PoolStats {
+	stats := make(map[string]PoolStats, len(databases))
+	for name, db := range databases {
+		s := db.Stats()
+		stats[name] = PoolStats{
+			MaxOpenConnections: s.MaxOpenConnections,
+			OpenConnections:    s.OpenConnections,
+			InUse:              s.InUse,
+			Idle:               s.Idle,
+			WaitCount:          s.WaitCount,
+			WaitDurationMillis: s.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      s.MaxIdleClosed,
+			MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  s.MaxLifetimeClosed,
+		}
+	}
+	return stats
+}
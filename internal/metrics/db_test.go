@@ -0,0 +1,50 @@
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/therealyo/justdone/infrastructure/database/sqlite"
+	"github.com/therealyo/justdone/internal/metrics"
+)
+
+func TestDBStats(t *testing.T) {
+	primary, err := sqlite.New(filepath.Join(t.TempDir(), "primary.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer primary.Close()
+	primary.SetMaxOpenConns(3)
+
+	locks, err := sqlite.New(filepath.Join(t.TempDir(), "locks.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer locks.Close()
+	locks.SetMaxOpenConns(1)
+
+	// A held connection is in use until it is returned to the pool
+	conn, err := locks.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	stats := metrics.DBStats(map[string]*sql.DB{"primary": primary, "locks": locks})
+
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats of 2 pools, got %+v", stats)
+	}
+	if stats["primary"].MaxOpenConnections != 3 || stats["primary"].InUse != 0 {
+		t.Errorf("Expected idle primary pool of 3 connections, got %+v", stats["primary"])
+	}
+	if stats["locks"].MaxOpenConnections != 1 || stats["locks"].InUse != 1 || stats["locks"].OpenConnections != 1 {
+		t.Errorf("Expected the single locks connection in use, got %+v", stats["locks"])
+	}
+
+	if stats := metrics.DBStats(nil); len(stats) != 0 {
+		t.Errorf("Expected no stats without databases, got %+v", stats)
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// registry groups application metrics under a single expvar entry,
+	// so components can (re)register their gauges without name clashes.
+	registry = expvar.NewMap("justdone")
+	mu       sync.Mutex
+)
+
+// Publish registers a metric that is evaluated on every scrape.
+func Publish(name string, fn func() interface{}) {
+	registry.Set(name, expvar.Func(fn))
+}
+
+// Counter returns the counter registered under name, creating it if needed.
+func Counter(name string) *expvar.Int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if v, ok := registry.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	counter := new(expvar.Int)
+	registry.Set(name, counter)
+	return counter
+}
+
+// Handler serves all published metrics as JSON.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(expvar.Handler())
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrTooManyConnections = errors.New("too many concurrent connections")
+
+// ConnectionLimits caps concurrent connections. Zero means unlimited.
+type ConnectionLimits struct {
+	PerOrder int
+	PerUser  int
+	Total    int
+}
+
+// ConnectionUsage is a snapshot of currently held connections. It only holds
+// counts, so it neither reveals who is connected nor grows with the connections.
+type ConnectionUsage struct {
+	Total int `json:"total"`
+	// Orders and Users are the numbers of orders and users with connections
+	Orders int `json:"orders"`
+	Users  int `json:"users"`
+	// OrdersAtLimit and UsersAtLimit are the numbers of orders and users that can't connect again
+	OrdersAtLimit int `json:"orders_at_limit"`
+	UsersAtLimit  int `json:"users_at_limit"`
+}
+
+// ConnectionLimiter counts concurrent connections per order, per user and globally.
+type ConnectionLimiter struct {
+	mu       sync.Mutex
+	limits   ConnectionLimits
+	total    int
+	perOrder map[string]int
+	perUser  map[string]int
+}
+
+// Acquire reserves a connection slot. The returned release function must be
+// called once the connection is closed.
+func (l *ConnectionLimiter) Acquire(orderID, userKey string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if exceeds(l.total, l.limits.Total) ||
+		exceeds(l.perOrder[orderID], l.limits.PerOrder) ||
+		exceeds(l.perUser[userKey], l.limits.PerUser) {
+		return nil, ErrTooManyConnections
+	}
+
+	l.total++
+	l.perOrder[orderID]++
+	l.perUser[userKey]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.release(orderID, userKey) })
+	}, nil
+}
+
+func (l *ConnectionLimiter) release(orderID, userKey string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total--
+	decrement(l.perOrder, orderID)
+	decrement(l.perUser, userKey)
+}
+
+// Usage returns the current connection counts.
+func (l *ConnectionLimiter) Usage() ConnectionUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return ConnectionUsage{
+		Total:         l.total,
+		Orders:        len(l.perOrder),
+		Users:         len(l.perUser),
+		OrdersAtLimit: atLimit(l.perOrder, l.limits.PerOrder),
+		UsersAtLimit:  atLimit(l.perUser, l.limits.PerUser),
+	}
+}
+
+func atLimit(counts map[string]int, limit int) int {
+	n := 0
+	for _, count := range counts {
+		if exceeds(count, limit) {
+			n++
+		}
+	}
+	return n
+}
+
+func exceeds(current, limit int) bool {
+	return limit > 0 && current >= limit
+}
+
+func decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+func NewConnectionLimiter(limits ConnectionLimits) *ConnectionLimiter {
+	return &ConnectionLimiter{
+		limits:   limits,
+		perOrder: make(map[string]int),
+		perUser:  make(map[string]int),
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleBucketTTL is how long an unused bucket is kept before it is dropped.
+const idleBucketTTL = 10 * time.Minute
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// KeyedLimiter is a token bucket limiter keeping a separate bucket per key
+// (client IP or user ID).
+type KeyedLimiter struct {
+	mu      sync.Mutex
+	limit   rate.Limit
+	burst   int
+	buckets map[string]*bucket
+	sweep   time.Time
+}
+
+// Allow takes a token from the bucket of the given key. If the bucket is empty
+// it returns false and the time after which a token will be available.
+func (l *KeyedLimiter) Allow(key string) (bool, time.Duration) {
+	if l.limit == rate.Inf {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.evictIdle(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// Keys returns the number of tracked buckets.
+func (l *KeyedLimiter) Keys() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+func (l *KeyedLimiter) evictIdle(now time.Time) {
+	if now.Sub(l.sweep) < idleBucketTTL {
+		return
+	}
+	l.sweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// NewKeyedLimiter creates a limiter allowing perSecond requests per key with
+// the given burst. A non-positive perSecond disables limiting.
+func NewKeyedLimiter(perSecond float64, burst int) *KeyedLimiter {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &KeyedLimiter{
+		limit:   limit,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		sweep:   time.Now(),
+	}
+}
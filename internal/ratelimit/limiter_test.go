@@ -0,0 +1,72 @@
+package ratelimit_test
+
+import (
+	"testing"
+
+	"github.com/therealyo/justdone/internal/ratelimit"
+)
+
+func TestKeyedLimiterSeparatesKeys(t *testing.T) {
+	limiter := ratelimit.NewKeyedLimiter(1, 2)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("ip:1"); !allowed {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("ip:1")
+	if allowed {
+		t.Fatalf("Expected request over burst to be rejected")
+	}
+	if retryAfter <= 0 {
+		t.Errorf("Expected positive retry after, got %v", retryAfter)
+	}
+
+	if allowed, _ := limiter.Allow("ip:2"); !allowed {
+		t.Errorf("Expected another key to have its own bucket")
+	}
+}
+
+func TestConnectionLimiterCaps(t *testing.T) {
+	limiter := ratelimit.NewConnectionLimiter(ratelimit.ConnectionLimits{
+		PerOrder: 2,
+		PerUser:  1,
+		Total:    3,
+	})
+
+	release, err := limiter.Acquire("order1", "user1")
+	if err != nil {
+		t.Fatalf("Expected first connection to be accepted, got %v", err)
+	}
+
+	if _, err := limiter.Acquire("order2", "user1"); err != ratelimit.ErrTooManyConnections {
+		t.Errorf("Expected per user limit to be enforced, got %v", err)
+	}
+
+	if _, err := limiter.Acquire("order1", "user2"); err != nil {
+		t.Fatalf("Expected second connection to order1 to be accepted, got %v", err)
+	}
+
+	if _, err := limiter.Acquire("order1", "user3"); err != ratelimit.ErrTooManyConnections {
+		t.Errorf("Expected per order limit to be enforced, got %v", err)
+	}
+
+	if _, err := limiter.Acquire("order2", "user3"); err != nil {
+		t.Fatalf("Expected connection to order2 to be accepted, got %v", err)
+	}
+
+	if _, err := limiter.Acquire("order3", "user4"); err != ratelimit.ErrTooManyConnections {
+		t.Errorf("Expected global limit to be enforced, got %v", err)
+	}
+
+	release()
+	release()
+
+	// user2 on order1 and user3 on order2 remain, both users are at their limit
+	usage := limiter.Usage()
+	expected := ratelimit.ConnectionUsage{Total: 2, Orders: 2, Users: 2, OrdersAtLimit: 0, UsersAtLimit: 2}
+	if usage != expected {
+		t.Errorf("Expected usage %+v after release, got %+v", expected, usage)
+	}
+}
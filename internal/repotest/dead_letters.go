@@ -0,0 +1,78 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func testDeadLetters(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+	first := newEvent(order, 1, domain.ConfirmedByMayor, 1)
+	second := newEvent(order, 2, domain.Chinazes, 2)
+	other := newEvent(newOrder(2, 1, domain.CoolOrderCreated, false, 0), 3, domain.Chinazes, 0)
+
+	if got, err := repos.DeadLetters.Get(first.EventID); err != nil || got != nil {
+		t.Fatalf("Expected no dead letter for missing event, got %+v, %v", got, err)
+	}
+
+	// Dead letters are stored without their orders
+	addDeadLetter(t, repos, first, "order not found")
+	addDeadLetter(t, repos, other, "order not found")
+	addDeadLetter(t, repos, second, "order not found")
+	addDeadLetter(t, repos, first, "invalid event sequence")
+
+	got, err := repos.DeadLetters.Get(first.EventID)
+	if err != nil || got == nil {
+		t.Fatalf("Failed to get dead letter: %+v, %v", got, err)
+	}
+	if got.OrderID != first.OrderID || got.Reason != "invalid event sequence" || got.Attempts != 2 {
+		t.Errorf("Expected latest reason and 2 attempts, got %+v", got)
+	}
+	assertEvent(t, &got.Payload, first)
+	if got.UpdatedAt.Before(got.CreatedAt) {
+		t.Errorf("Expected updated_at %v not before created_at %v", got.UpdatedAt, got.CreatedAt)
+	}
+
+	// The most recently failed event is listed first
+	assertDeadLetters(t, repos, domain.DeadLetterFilter{}, first, second, other)
+	assertDeadLetters(t, repos, domain.DeadLetterFilter{OrderID: order.OrderID}, first, second)
+	assertDeadLetters(t, repos, domain.DeadLetterFilter{Limit: 1, Offset: 1}, second)
+
+	if err := repos.DeadLetters.Delete(first.EventID, other.EventID, eventID(9)); err != nil {
+		t.Fatalf("Failed to delete dead letters: %v", err)
+	}
+	if err := repos.DeadLetters.Delete(); err != nil {
+		t.Fatalf("Expected no error when deleting nothing, got %v", err)
+	}
+	assertDeadLetters(t, repos, domain.DeadLetterFilter{}, second)
+}
+
+func addDeadLetter(t *testing.T, repos Repositories, event domain.OrderEvent, reason string) {
+	t.Helper()
+
+	if err := repos.DeadLetters.Add(event, reason); err != nil {
+		t.Fatalf("Failed to add dead letter %s: %v", event.EventID, err)
+	}
+	// Keep failure times distinct at microsecond precision
+	time.Sleep(time.Millisecond)
+}
+
+func assertDeadLetters(t *testing.T, repos Repositories, filter domain.DeadLetterFilter, want ...domain.OrderEvent) {
+	t.Helper()
+
+	got, err := repos.DeadLetters.List(filter)
+	if err != nil {
+		t.Fatalf("Failed to list dead letters: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d dead letters for %+v, got %d", len(want), filter, len(got))
+	}
+	for i := range want {
+		if got[i].EventID != want[i].EventID {
+			t.Errorf("Expected dead letter %d to be %s, got %s", i, want[i].EventID, got[i].EventID)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package repotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+const hour = time.Hour
+
+// base is truncated to microseconds, the precision of Postgres timestamps.
+var base = time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+
+// IDs are valid UUIDs, since backends may store them as such.
+func orderID(n int) string { return fmt.Sprintf("00000000-0000-4000-8000-%012d", n) }
+func userID(n int) string  { return fmt.Sprintf("10000000-0000-4000-8000-%012d", n) }
+func eventID(n int) string { return fmt.Sprintf("20000000-0000-4000-8000-%012d", n) }
+func runID(n int) string   { return fmt.Sprintf("30000000-0000-4000-8000-%012d", n) }
+
+// newOrder returns an order created createdAfter hours after base
+// and updated half an hour later.
+func newOrder(n, user int, status domain.OrderStatus, isFinal bool, createdAfter int) *domain.Order {
+	createdAt := base.Add(time.Duration(createdAfter) * hour)
+	return &domain.Order{
+		OrderID:   orderID(n),
+		UserID:    userID(user),
+		Status:    status,
+		IsFinal:   isFinal,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt.Add(hour / 2),
+	}
+}
+
+// newEvent returns an event of the order created step minutes after the order.
+func newEvent(order *domain.Order, n int, status domain.OrderStatus, step int) domain.OrderEvent {
+	createdAt := order.CreatedAt.Add(time.Duration(step) * time.Minute)
+	return domain.OrderEvent{
+		EventID:     eventID(n),
+		OrderID:     order.OrderID,
+		UserID:      order.UserID,
+		OrderStatus: status,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
+
+// seed stores six orders used by the GetMany and Count tests. Orders 5 and 6
+// share creation and update times, so sorting by them relies on the order_id
+// tie-breaker.
+//
+//	order  user  status                    final  created  amount    events
+//	1      1     cool_order_created        no     +0h      -         created
+//	2      1     chinazes                  yes    +1h      1500 EUR  created, sbu, mayor, chinazes
+//	3      2     changed_my_mind           yes    +2h      -         created, changed
+//	4      2     sbu_verification_pending  no     +3h      -         created, sbu, rejected chinazes (updated now)
+//	5      3     give_my_money_back        yes    +4h      2500 EUR  created, sbu, mayor, chinazes, refund
+//	6      3     confirmed_by_mayor        no     +4h      990 USD   created, sbu, mayor
+func seed(t *testing.T, repos Repositories) {
+	t.Helper()
+
+	histories := []struct {
+		order  *domain.Order
+		events []domain.OrderStatus
+	}{
+		{
+			order:  newOrder(1, 1, domain.CoolOrderCreated, false, 0),
+			events: []domain.OrderStatus{domain.CoolOrderCreated},
+		},
+		{
+			order: newOrder(2, 1, domain.Chinazes, true, 1),
+			events: []domain.OrderStatus{
+				domain.CoolOrderCreated, domain.SbuVerificationPending, domain.ConfirmedByMayor, domain.Chinazes,
+			},
+		},
+		{
+			order:  newOrder(3, 2, domain.ChangedMyMind, true, 2),
+			events: []domain.OrderStatus{domain.CoolOrderCreated, domain.ChangedMyMind},
+		},
+		{
+			order:  newOrder(4, 2, domain.SbuVerificationPending, false, 3),
+			events: []domain.OrderStatus{domain.CoolOrderCreated, domain.SbuVerificationPending},
+		},
+		{
+			order: newOrder(5, 3, domain.GiveMyMoneyBack, true, 4),
+			events: []domain.OrderStatus{
+				domain.CoolOrderCreated, domain.SbuVerificationPending, domain.ConfirmedByMayor,
+				domain.Chinazes, domain.GiveMyMoneyBack,
+			},
+		},
+		{
+			order: newOrder(6, 3, domain.ConfirmedByMayor, false, 4),
+			events: []domain.OrderStatus{
+				domain.CoolOrderCreated, domain.SbuVerificationPending, domain.ConfirmedByMayor,
+			},
+		},
+	}
+
+	histories[3].order.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	histories[1].order.PaymentDetails = payment(1500, "EUR", "")
+	histories[4].order.PaymentDetails = payment(2500, "EUR", "")
+	histories[5].order.PaymentDetails = payment(990, "USD", "")
+
+	for i, history := range histories {
+		save(t, repos, history.order)
+		for step, status := range history.events {
+			create(t, repos, newEvent(history.order, (i+1)*10+step, status, step))
+		}
+	}
+
+	// A chinazes event that arrived before the mayor confirmed the order is
+	// stored, but was never applied
+	rejected := newEvent(histories[3].order, 42, domain.Chinazes, 2)
+	rejected.RejectedReason = "illegal transition from sbu_verification_pending to chinazes"
+	create(t, repos, rejected)
+}
+
+// payment returns payment details, metadata is omitted when empty.
+func payment(amount int64, currency, metadata string) domain.PaymentDetails {
+	details := domain.PaymentDetails{Amount: &amount, Currency: currency}
+	if metadata != "" {
+		details.Metadata = json.RawMessage(metadata)
+	}
+	return details
+}
+
+func save(t *testing.T, repos Repositories, order *domain.Order) {
+	t.Helper()
+	if err := repos.Orders.Save(order); err != nil {
+		t.Fatalf("Failed to save order %s: %v", order.OrderID, err)
+	}
+}
+
+func create(t *testing.T, repos Repositories, event domain.OrderEvent) {
+	t.Helper()
+	if err := repos.Events.Create(event); err != nil {
+		t.Fatalf("Failed to create event %s: %v", event.EventID, err)
+	}
+}
+
+func get(t *testing.T, repos Repositories, orderID string) *domain.Order {
+	t.Helper()
+	order, err := repos.Orders.Get(orderID)
+	if err != nil {
+		t.Fatalf("Failed to get order %s: %v", orderID, err)
+	}
+	if order == nil {
+		t.Fatalf("Expected order %s to be found", orderID)
+	}
+	return order
+}
+
+func assertOrder(t *testing.T, got, want *domain.Order) {
+	t.Helper()
+	if got.OrderID != want.OrderID || got.UserID != want.UserID || got.Status != want.Status ||
+		got.IsFinal != want.IsFinal || got.Version != want.Version ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("Expected order %+v, got %+v", *want, *got)
+	}
+	assertPaymentDetails(t, got.PaymentDetails, want.PaymentDetails)
+}
+
+func assertEvent(t *testing.T, got *domain.OrderEvent, want domain.OrderEvent) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("Expected event %s, got nil", want.EventID)
+	}
+	if got.EventID != want.EventID || got.OrderID != want.OrderID || got.UserID != want.UserID ||
+		got.OrderStatus != want.OrderStatus || got.IsFinal != want.IsFinal || got.RejectedReason != want.RejectedReason ||
+		!got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("Expected event %+v, got %+v", want, *got)
+	}
+	assertPaymentDetails(t, got.PaymentDetails, want.PaymentDetails)
+}
+
+// assertPaymentDetails compares metadata as JSON values, since backends may
+// reformat it.
+func assertPaymentDetails(t *testing.T, got, want domain.PaymentDetails) {
+	t.Helper()
+	if (got.Amount == nil) != (want.Amount == nil) || got.Amount != nil && *got.Amount != *want.Amount ||
+		got.Currency != want.Currency {
+		t.Errorf("Expected amount %v %s, got %v %s", want.Amount, want.Currency, got.Amount, got.Currency)
+	}
+
+	if (len(got.Metadata) == 0) != (len(want.Metadata) == 0) {
+		t.Errorf("Expected metadata %s, got %s", want.Metadata, got.Metadata)
+		return
+	}
+	if len(want.Metadata) == 0 {
+		return
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got.Metadata, &gotValue); err != nil {
+		t.Fatalf("Failed to parse metadata %s: %v", got.Metadata, err)
+	}
+	if err := json.Unmarshal(want.Metadata, &wantValue); err != nil {
+		t.Fatalf("Failed to parse metadata %s: %v", want.Metadata, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("Expected metadata %s, got %s", want.Metadata, got.Metadata)
+	}
+}
+
+// orderNumbers returns the numbers of the orders created by seed.
+func orderNumbers(orders []domain.Order) []int {
+	numbers := make([]int, len(orders))
+	for i, order := range orders {
+		fmt.Sscanf(order.OrderID[len(order.OrderID)-12:], "%d", &numbers[i])
+	}
+	return numbers
+}
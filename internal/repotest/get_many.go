@@ -0,0 +1,175 @@
+package repotest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func testGetManyFilters(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	final, notFinal := true, false
+	createdFrom, createdTo := base.Add(hour), base.Add(3*hour)
+	updatedFrom, updatedTo := base.Add(3*hour), base.Add(hour+hour/2)
+	amountFrom, amountTo := int64(990), int64(1500)
+
+	tests := []struct {
+		name     string
+		options  []domain.FilterOption
+		expected []int
+	}{
+		{"no filter", nil, []int{5, 6, 4, 3, 2, 1}},
+		{"status", []domain.FilterOption{domain.WithStatus(domain.Chinazes, domain.GiveMyMoneyBack)}, []int{5, 2}},
+		{"no match", []domain.FilterOption{domain.WithStatus(domain.Failed)}, []int{}},
+		{"user", []domain.FilterOption{domain.WithUserID(userID(1))}, []int{2, 1}},
+		{"users", []domain.FilterOption{domain.WithUserIDs(userID(1), userID(3))}, []int{5, 6, 2, 1}},
+		{"orders", []domain.FilterOption{domain.WithOrderIDs(orderID(1), orderID(3))}, []int{3, 1}},
+		{"final", []domain.FilterOption{domain.WithIsFinal(&final)}, []int{5, 3, 2}},
+		{"not final", []domain.FilterOption{domain.WithIsFinal(&notFinal)}, []int{6, 4, 1}},
+		{"created between", []domain.FilterOption{domain.WithCreatedBetween(&createdFrom, &createdTo)}, []int{3, 2}},
+		{"updated from", []domain.FilterOption{domain.WithUpdatedBetween(&updatedFrom, nil)}, []int{5, 6, 4}},
+		{"updated to", []domain.FilterOption{domain.WithUpdatedBetween(nil, &updatedTo)}, []int{1}},
+		{"ever in status", []domain.FilterOption{domain.WithEverInStatus(domain.ConfirmedByMayor)}, []int{5, 6, 2}},
+		{"ever in status ignores rejected events", []domain.FilterOption{domain.WithEverInStatus(domain.Chinazes)}, []int{5, 2}},
+		{"stuck", []domain.FilterOption{domain.WithStuckFor(hour), domain.WithNow(time.Now())}, []int{6, 1}},
+		{"stuck at", []domain.FilterOption{domain.WithStuckFor(hour), domain.WithNow(base.Add(5 * hour))}, []int{1}},
+		{"amount between", []domain.FilterOption{domain.WithAmountBetween(&amountFrom, &amountTo)}, []int{6, 2}},
+		{"amount from", []domain.FilterOption{domain.WithAmountBetween(&amountTo, nil)}, []int{5, 2}},
+		{"currency", []domain.FilterOption{domain.WithCurrencies("EUR")}, []int{5, 2}},
+		{"amount and currency", []domain.FilterOption{domain.WithAmountBetween(nil, &amountTo), domain.WithCurrencies("USD", "GBP")}, []int{6}},
+		{"combined", []domain.FilterOption{domain.WithUserID(userID(3)), domain.WithIsFinal(&notFinal)}, []int{6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGetMany(t, repos, domain.NewOrderFilter(tt.options...), tt.expected)
+		})
+	}
+}
+
+func testGetManySort(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	tests := []struct {
+		sort     string
+		expected []int
+	}{
+		{"created_at", []int{1, 2, 3, 4, 5, 6}},
+		{"-created_at", []int{5, 6, 4, 3, 2, 1}},
+		{"-order_id", []int{6, 5, 4, 3, 2, 1}},
+		{"-updated_at", []int{4, 5, 6, 3, 2, 1}},
+		{"user_id,-created_at", []int{2, 1, 4, 3, 5, 6}},
+		{"status", []int{3, 2, 6, 1, 5, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			sort, err := domain.ParseOrderSort(tt.sort)
+			if err != nil {
+				t.Fatalf("Failed to parse sort: %v", err)
+			}
+			assertGetMany(t, repos, domain.NewOrderFilter(domain.WithSort(sort)), tt.expected)
+		})
+	}
+}
+
+func testGetManyPagination(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	tests := []struct {
+		name     string
+		limit    int
+		offset   int
+		expected []int
+	}{
+		{"first page", 2, 0, []int{5, 6}},
+		{"second page", 2, 2, []int{4, 3}},
+		{"last page", 2, 4, []int{2, 1}},
+		{"partial page", 4, 4, []int{2, 1}},
+		{"offset at end", 2, 6, []int{}},
+		{"offset past end", 2, 100, []int{}},
+		{"no limit", 0, 0, []int{5, 6, 4, 3, 2, 1}},
+		{"no limit with offset", 0, 3, []int{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := domain.NewOrderFilter(domain.WithLimit(tt.limit), domain.WithOffset(tt.offset))
+			assertGetMany(t, repos, filter, tt.expected)
+		})
+	}
+}
+
+func testCount(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	notFinal := false
+
+	tests := []struct {
+		name     string
+		options  []domain.FilterOption
+		expected int
+	}{
+		{"no filter", nil, 6},
+		{"filtered", []domain.FilterOption{domain.WithIsFinal(&notFinal)}, 3},
+		{"no match", []domain.FilterOption{domain.WithStatus(domain.Failed)}, 0},
+		{"ignores pagination", []domain.FilterOption{domain.WithLimit(2), domain.WithOffset(4)}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repos.Orders.Count(domain.NewOrderFilter(tt.options...), 100)
+			if err != nil {
+				t.Fatalf("Failed to count orders: %v", err)
+			}
+			if count.Total != tt.expected || count.Estimated {
+				t.Errorf("Expected exact count %d, got %+v", tt.expected, *count)
+			}
+		})
+	}
+
+	// Above the exact limit the count may be estimated, but never below the rows seen
+	for _, exactLimit := range []int{1, 5} {
+		count, err := repos.Orders.Count(domain.NewOrderFilter(), exactLimit)
+		if err != nil {
+			t.Fatalf("Failed to count orders: %v", err)
+		}
+		if count.Estimated != repos.EstimatesCount {
+			t.Errorf("Expected estimated %v above exact limit %d, got %+v", repos.EstimatesCount, exactLimit, *count)
+		}
+		if count.Total < exactLimit+1 || (!count.Estimated && count.Total != 6) {
+			t.Errorf("Expected count of 6 orders above exact limit %d, got %+v", exactLimit, *count)
+		}
+	}
+
+	// A non-positive exact limit always counts exactly
+	count, err := repos.Orders.Count(domain.NewOrderFilter(), 0)
+	if err != nil {
+		t.Fatalf("Failed to count orders: %v", err)
+	}
+	if count.Total != 6 || count.Estimated {
+		t.Errorf("Expected exact count 6 without exact limit, got %+v", *count)
+	}
+}
+
+func assertGetMany(t *testing.T, repos Repositories, filter *domain.OrderFilter, expected []int) {
+	t.Helper()
+
+	orders, err := repos.Orders.GetMany(filter)
+	if err != nil {
+		t.Fatalf("Failed to get orders: %v", err)
+	}
+
+	if got := orderNumbers(orders); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected orders %v, got %v", expected, got)
+	}
+
+	for _, order := range orders {
+		if order.UserID == "" || order.Status == "" || order.CreatedAt.IsZero() || order.UpdatedAt.IsZero() {
+			t.Errorf("Expected order %s to be fully loaded, got %+v", order.OrderID, order)
+		}
+	}
+}
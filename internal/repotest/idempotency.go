@@ -0,0 +1,58 @@
+package repotest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func testIdempotentResponses(t *testing.T, repos Repositories) {
+	want := domain.IdempotentResponse{
+		Key:         "event:" + eventID(1),
+		RequestHash: "hash1",
+		Status:      202,
+		Headers:     map[string][]string{"Content-Type": {"application/json; charset=utf-8"}, "Location": {"/orders/1"}},
+		Body:        []byte(`{"message":"Event created"}`),
+		ExpiresAt:   time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond),
+	}
+	if err := repos.Idempotency.Save(want); err != nil {
+		t.Fatalf("Failed to save idempotent response: %v", err)
+	}
+
+	// A response is kept until it expires
+	replaced := want
+	replaced.RequestHash = "hash2"
+	if err := repos.Idempotency.Save(replaced); err != nil {
+		t.Fatalf("Failed to save idempotent response again: %v", err)
+	}
+
+	got, err := repos.Idempotency.Get(want.Key)
+	if err != nil || got == nil {
+		t.Fatalf("Expected idempotent response, got %v, %v", got, err)
+	}
+	if got.RequestHash != want.RequestHash || got.Status != want.Status || string(got.Body) != string(want.Body) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if !reflect.DeepEqual(got.Headers, want.Headers) {
+		t.Errorf("Expected headers %v, got %v", want.Headers, got.Headers)
+	}
+	if !got.ExpiresAt.Equal(want.ExpiresAt) {
+		t.Errorf("Expected expiry %v, got %v", want.ExpiresAt, got.ExpiresAt)
+	}
+
+	expired := domain.IdempotentResponse{
+		Key:         "event:" + eventID(2),
+		RequestHash: "hash3",
+		Status:      200,
+		Body:        []byte(`{}`),
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+	if err := repos.Idempotency.Save(expired); err != nil {
+		t.Fatalf("Failed to save expired idempotent response: %v", err)
+	}
+	if got, err := repos.Idempotency.Get(expired.Key); err != nil || got != nil {
+		t.Errorf("Expected no expired response, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,59 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func testReconciliationCorrections(t *testing.T, repos Repositories) {
+	newCorrection := func(run, event, order int, status domain.OrderStatus, minutes int) domain.ReconciliationCorrection {
+		return domain.ReconciliationCorrection{
+			RunID:     runID(run),
+			EventID:   eventID(event),
+			OrderID:   orderID(order),
+			Status:    status,
+			Source:    "settlement.csv",
+			CreatedAt: base.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	first := newCorrection(1, 1, 1, domain.SbuVerificationPending, 0)
+	failed := newCorrection(1, 2, 2, domain.Chinazes, 1)
+	failed.Error = "invalid event sequence"
+	// The same event is corrected again by a later run
+	retried := newCorrection(2, 2, 2, domain.Chinazes, 2)
+
+	for _, correction := range []domain.ReconciliationCorrection{first, failed, retried} {
+		if err := repos.Reconciliation.Add(correction); err != nil {
+			t.Fatalf("Failed to add reconciliation correction: %v", err)
+		}
+	}
+
+	// The most recent correction is listed first
+	assertCorrections(t, repos, domain.ReconciliationCorrectionFilter{}, retried, failed, first)
+	assertCorrections(t, repos, domain.ReconciliationCorrectionFilter{RunID: runID(1)}, failed, first)
+	assertCorrections(t, repos, domain.ReconciliationCorrectionFilter{OrderID: orderID(2), RunID: runID(1)}, failed)
+	assertCorrections(t, repos, domain.ReconciliationCorrectionFilter{Limit: 1, Offset: 1}, failed)
+}
+
+func assertCorrections(t *testing.T, repos Repositories, filter domain.ReconciliationCorrectionFilter, want ...domain.ReconciliationCorrection) {
+	t.Helper()
+
+	got, err := repos.Reconciliation.List(filter)
+	if err != nil {
+		t.Fatalf("Failed to list reconciliation corrections: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d corrections for %+v, got %d", len(want), filter, len(got))
+	}
+	for i := range want {
+		if got[i].RunID != want[i].RunID || got[i].EventID != want[i].EventID || got[i].OrderID != want[i].OrderID ||
+			got[i].Status != want[i].Status || got[i].Source != want[i].Source || got[i].Error != want[i].Error ||
+			!got[i].CreatedAt.Equal(want[i].CreatedAt) {
+			t.Errorf("Expected correction %d to be %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
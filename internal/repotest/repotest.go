@@ -0,0 +1,302 @@
+// Package repotest is a conformance suite for storage backends. Every backend
+// runs the same tests, so the order processor and the API behave the same
+// regardless of where orders are stored.
+package repotest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+// Repositories are the repositories under test. Orders and events must share
+// the same storage, so that orders are returned with the events created for them.
+type Repositories struct {
+	Orders      domain.OrderRepository
+	Events      domain.EventRepository
+	DeadLetters domain.DeadLetterRepository
+	// Reconciliation stores audit records without orders
+	Reconciliation domain.ReconciliationAuditRepository
+	// Stats aggregates the orders and events of the same storage
+	Stats       domain.OrderStatsRepository
+	Idempotency domain.IdempotencyStore
+	// EstimatesCount is set when Orders.Count estimates counts above the exact limit
+	EstimatesCount bool
+}
+
+// Factory returns repositories over empty storage. It is called for every test.
+type Factory func(t *testing.T) Repositories
+
+// Run runs the conformance suite against the repositories created by factory.
+func Run(t *testing.T, factory Factory) {
+	t.Run("GetMissingOrder", func(t *testing.T) { testGetMissingOrder(t, factory(t)) })
+	t.Run("SaveOrder", func(t *testing.T) { testSaveOrder(t, factory(t)) })
+	t.Run("SaveExistingOrder", func(t *testing.T) { testSaveExistingOrder(t, factory(t)) })
+	t.Run("SaveStaleOrder", func(t *testing.T) { testSaveStaleOrder(t, factory(t)) })
+	t.Run("SaveDuplicateOrder", func(t *testing.T) { testSaveDuplicateOrder(t, factory(t)) })
+	t.Run("GetOrderWithEvents", func(t *testing.T) { testGetOrderWithEvents(t, factory(t)) })
+
+	t.Run("GetMissingEvent", func(t *testing.T) { testGetMissingEvent(t, factory(t)) })
+	t.Run("CreateEvent", func(t *testing.T) { testCreateEvent(t, factory(t)) })
+	t.Run("CreateDuplicateEvent", func(t *testing.T) { testCreateDuplicateEvent(t, factory(t)) })
+	t.Run("CreateEventForMissingOrder", func(t *testing.T) { testCreateEventForMissingOrder(t, factory(t)) })
+	t.Run("UpdateEvent", func(t *testing.T) { testUpdateEvent(t, factory(t)) })
+	t.Run("UpdateMissingEvent", func(t *testing.T) { testUpdateMissingEvent(t, factory(t)) })
+	t.Run("DeleteEvent", func(t *testing.T) { testDeleteEvent(t, factory(t)) })
+	t.Run("DeleteMissingEvent", func(t *testing.T) { testDeleteMissingEvent(t, factory(t)) })
+
+	t.Run("GetManyFilters", func(t *testing.T) { testGetManyFilters(t, factory(t)) })
+	t.Run("GetManySort", func(t *testing.T) { testGetManySort(t, factory(t)) })
+	t.Run("GetManyPagination", func(t *testing.T) { testGetManyPagination(t, factory(t)) })
+	t.Run("Count", func(t *testing.T) { testCount(t, factory(t)) })
+
+	t.Run("Stats", func(t *testing.T) { testStats(t, factory(t)) })
+	t.Run("StatsIgnoresRejectedEvents", func(t *testing.T) { testStatsIgnoresRejectedEvents(t, factory(t)) })
+
+	t.Run("DeadLetters", func(t *testing.T) { testDeadLetters(t, factory(t)) })
+	t.Run("ReconciliationCorrections", func(t *testing.T) { testReconciliationCorrections(t, factory(t)) })
+	t.Run("IdempotentResponses", func(t *testing.T) { testIdempotentResponses(t, factory(t)) })
+}
+
+func testGetMissingOrder(t *testing.T, repos Repositories) {
+	order, err := repos.Orders.Get(orderID(1))
+	if err != nil {
+		t.Fatalf("Expected no error for missing order, got %v", err)
+	}
+	if order != nil {
+		t.Errorf("Expected nil for missing order, got %+v", order)
+	}
+}
+
+func testSaveOrder(t *testing.T, repos Repositories) {
+	want := newOrder(1, 1, domain.ConfirmedByMayor, false, 0)
+	save(t, repos, want)
+
+	if want.Version != 1 {
+		t.Errorf("Expected new order to get version 1, got %d", want.Version)
+	}
+
+	got := get(t, repos, want.OrderID)
+	assertOrder(t, got, want)
+
+	if len(got.Events) != 0 || got.LastEvent != nil {
+		t.Errorf("Expected order without events, got %d events and last event %v", len(got.Events), got.LastEvent)
+	}
+}
+
+func testSaveExistingOrder(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+	save(t, repos, order)
+
+	order.Status = domain.Chinazes
+	order.IsFinal = true
+	order.UpdatedAt = order.UpdatedAt.Add(hour)
+	order.PaymentDetails = payment(4200, "EUR", `{"invoice": "A-1", "items": [1, 2]}`)
+	save(t, repos, order)
+
+	if order.Version != 2 {
+		t.Errorf("Expected updated order to get version 2, got %d", order.Version)
+	}
+
+	assertOrder(t, get(t, repos, order.OrderID), order)
+}
+
+func testSaveStaleOrder(t *testing.T, repos Repositories) {
+	save(t, repos, newOrder(1, 1, domain.CoolOrderCreated, false, 0))
+
+	first := get(t, repos, orderID(1))
+	second := get(t, repos, orderID(1))
+
+	first.Status = domain.SbuVerificationPending
+	save(t, repos, first)
+
+	second.Status = domain.ChangedMyMind
+	if err := repos.Orders.Save(second); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("Expected %v for stale order, got %v", domain.ErrConcurrentModification, err)
+	}
+	if second.Version != 1 {
+		t.Errorf("Expected failed save to keep version 1, got %d", second.Version)
+	}
+
+	assertOrder(t, get(t, repos, orderID(1)), first)
+}
+
+func testSaveDuplicateOrder(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+	save(t, repos, order)
+
+	duplicate := newOrder(1, 2, domain.CoolOrderCreated, false, 1)
+	if err := repos.Orders.Save(duplicate); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("Expected %v for duplicate order, got %v", domain.ErrConcurrentModification, err)
+	}
+
+	assertOrder(t, get(t, repos, order.OrderID), order)
+}
+
+func testGetOrderWithEvents(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.ConfirmedByMayor, false, 0)
+	order.PaymentDetails = payment(1200, "UAH", `{"invoice":"A-1"}`)
+	save(t, repos, order)
+
+	// Events are created out of order and must be returned by creation time
+	events := []domain.OrderEvent{
+		newEvent(order, 1, domain.CoolOrderCreated, 0),
+		newEvent(order, 2, domain.SbuVerificationPending, 1),
+		newEvent(order, 3, domain.ConfirmedByMayor, 2),
+	}
+	events[1].PaymentDetails = order.PaymentDetails
+	for _, i := range []int{2, 0, 1} {
+		create(t, repos, events[i])
+	}
+
+	got := get(t, repos, order.OrderID)
+	if len(got.Events) != len(events) {
+		t.Fatalf("Expected %d events, got %d", len(events), len(got.Events))
+	}
+	for i := range events {
+		assertEvent(t, &got.Events[i], events[i])
+	}
+
+	if got.LastEvent == nil {
+		t.Fatal("Expected last event to be set")
+	}
+	assertEvent(t, got.LastEvent, events[2])
+	assertOrder(t, got, order)
+}
+
+func testGetMissingEvent(t *testing.T, repos Repositories) {
+	event, err := repos.Events.Get(eventID(1))
+	if err != nil {
+		t.Fatalf("Expected no error for missing event, got %v", err)
+	}
+	if event != nil {
+		t.Errorf("Expected nil for missing event, got %+v", event)
+	}
+}
+
+func testCreateEvent(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+	save(t, repos, order)
+
+	want := newEvent(order, 1, domain.CoolOrderCreated, 0)
+	want.PaymentDetails = payment(0, "JPY", `{"nested": {"key": "value"}}`)
+	create(t, repos, want)
+
+	got, err := repos.Events.Get(want.EventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	if got == nil {
+		t.Fatal("Expected created event to be found")
+	}
+	assertEvent(t, got, want)
+}
+
+func testCreateDuplicateEvent(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+	save(t, repos, order)
+
+	event := newEvent(order, 1, domain.CoolOrderCreated, 0)
+	create(t, repos, event)
+
+	duplicate := event
+	duplicate.OrderStatus = domain.Chinazes
+	err := repos.Events.Create(duplicate)
+	if !errors.Is(err, domain.ErrEventConflict) {
+		t.Fatalf("Expected %v for duplicate event, got %v", domain.ErrEventConflict, err)
+	}
+
+	got, err := repos.Events.Get(event.EventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	assertEvent(t, got, event)
+}
+
+func testCreateEventForMissingOrder(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.CoolOrderCreated, false, 0)
+
+	if err := repos.Events.Create(newEvent(order, 1, domain.CoolOrderCreated, 0)); err == nil {
+		t.Fatal("Expected error when creating event for missing order")
+	}
+}
+
+func testUpdateEvent(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.Chinazes, false, 0)
+	save(t, repos, order)
+
+	event := newEvent(order, 1, domain.Chinazes, 0)
+	create(t, repos, event)
+
+	rejected := event
+	rejected.RejectedReason = "illegal transition from cool_order_created to chinazes"
+	if err := repos.Events.Update(rejected); err != nil {
+		t.Fatalf("Failed to reject event: %v", err)
+	}
+	assertEvent(t, get(t, repos, order.OrderID).LastEvent, rejected)
+
+	update := event
+	update.IsFinal = true
+	update.UpdatedAt = event.UpdatedAt.Add(hour)
+	if err := repos.Events.Update(update); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+
+	got, err := repos.Events.Get(event.EventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	assertEvent(t, got, update)
+}
+
+func testUpdateMissingEvent(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.Chinazes, false, 0)
+	event := newEvent(order, 1, domain.Chinazes, 0)
+
+	if err := repos.Events.Update(event); err != nil {
+		t.Fatalf("Expected no error when updating missing event, got %v", err)
+	}
+
+	got, err := repos.Events.Get(event.EventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	if got != nil {
+		t.Errorf("Expected update not to create event, got %+v", got)
+	}
+}
+
+func testDeleteEvent(t *testing.T, repos Repositories) {
+	order := newOrder(1, 1, domain.SbuVerificationPending, false, 0)
+	save(t, repos, order)
+
+	first := newEvent(order, 1, domain.CoolOrderCreated, 0)
+	second := newEvent(order, 2, domain.SbuVerificationPending, 1)
+	create(t, repos, first)
+	create(t, repos, second)
+
+	if err := repos.Events.Delete(second.EventID); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	deleted, err := repos.Events.Get(second.EventID)
+	if err != nil {
+		t.Fatalf("Failed to get event: %v", err)
+	}
+	if deleted != nil {
+		t.Errorf("Expected deleted event to be missing, got %+v", deleted)
+	}
+
+	got := get(t, repos, order.OrderID)
+	if len(got.Events) != 1 {
+		t.Fatalf("Expected 1 event after delete, got %d", len(got.Events))
+	}
+	assertEvent(t, got.LastEvent, first)
+}
+
+func testDeleteMissingEvent(t *testing.T, repos Repositories) {
+	if err := repos.Events.Delete(eventID(1)); err != nil {
+		t.Fatalf("Expected no error when deleting missing event, got %v", err)
+	}
+}
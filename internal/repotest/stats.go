@@ -0,0 +1,179 @@
+package repotest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+)
+
+func testStatsIgnoresRejectedEvents(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	// Orders 3 and 4, the rejected chinazes of order 4 did not convert it
+	stats, err := repos.Stats.Stats(domain.NewOrderStatsFilter(domain.WithStatsUserID(userID(2))))
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+
+	if stats.Total != 2 || stats.Chinazes != 0 || stats.Refunded != 0 || stats.Canceled != 1 {
+		t.Errorf("Expected 2 orders with 1 canceled and none converted, got %+v", stats)
+	}
+
+	for _, transition := range stats.Transitions {
+		if transition.To == domain.Chinazes {
+			t.Errorf("Expected no transition to chinazes, got %+v", transition)
+		}
+	}
+	if len(stats.Transitions) != 2 {
+		t.Errorf("Expected created to sbu and created to changed transitions, got %+v", stats.Transitions)
+	}
+}
+
+func testStats(t *testing.T, repos Repositories) {
+	seed(t, repos)
+
+	// Two more orders of user 4 on the next day, verified after two and five minutes,
+	// only the first one has an amount
+	for i, minutes := range []int{2, 5} {
+		order := newOrder(7+i, 4, domain.SbuVerificationPending, false, 24+i)
+		if i == 0 {
+			order.PaymentDetails = payment(300, "PLN", "")
+		}
+		save(t, repos, order)
+		create(t, repos, newEvent(order, 70+i*10, domain.CoolOrderCreated, 0))
+		create(t, repos, newEvent(order, 71+i*10, domain.SbuVerificationPending, minutes))
+	}
+
+	from, to := base.Add(hour), base.Add(4*hour)
+	day, nextDay := base.Truncate(24*hour), base.Truncate(24*hour).Add(24*hour)
+	minute := time.Minute.Seconds()
+
+	tests := []struct {
+		name        string
+		options     []domain.StatsFilterOption
+		total       int
+		chinazes    int
+		refunded    int
+		canceled    int
+		groups      []domain.StatsGroup
+		transitions []domain.TransitionStats
+		amounts     []domain.CurrencyTotal
+	}{
+		{
+			name:  "all orders by status",
+			total: 8, chinazes: 2, refunded: 1, canceled: 1,
+			groups: []domain.StatsGroup{
+				{Key: string(domain.CoolOrderCreated), Count: 1},
+				{Key: string(domain.SbuVerificationPending), Count: 3},
+				{Key: string(domain.ConfirmedByMayor), Count: 1},
+				{Key: string(domain.Chinazes), Count: 1},
+				{Key: string(domain.GiveMyMoneyBack), Count: 1},
+				{Key: string(domain.ChangedMyMind), Count: 1},
+			},
+			transitions: []domain.TransitionStats{
+				{From: domain.CoolOrderCreated, To: domain.SbuVerificationPending, Count: 6, MedianSeconds: minute},
+				{From: domain.CoolOrderCreated, To: domain.ChangedMyMind, Count: 1, MedianSeconds: minute},
+				{From: domain.SbuVerificationPending, To: domain.ConfirmedByMayor, Count: 3, MedianSeconds: minute},
+				{From: domain.ConfirmedByMayor, To: domain.Chinazes, Count: 2, MedianSeconds: minute},
+				{From: domain.Chinazes, To: domain.GiveMyMoneyBack, Count: 1, MedianSeconds: minute},
+			},
+			amounts: []domain.CurrencyTotal{
+				{Currency: "EUR", Count: 2, Amount: 4000},
+				{Currency: "PLN", Count: 1, Amount: 300},
+				{Currency: "USD", Count: 1, Amount: 990},
+			},
+		},
+		{
+			name:    "by day",
+			options: []domain.StatsFilterOption{domain.WithStatsGroupBy(domain.GroupByDay)},
+			total:   8, chinazes: 2, refunded: 1, canceled: 1,
+			groups: []domain.StatsGroup{
+				{Key: day.Format(time.RFC3339), Count: 6},
+				{Key: nextDay.Format(time.RFC3339), Count: 2},
+			},
+			amounts: []domain.CurrencyTotal{
+				{Currency: "EUR", Count: 2, Amount: 4000},
+				{Currency: "PLN", Count: 1, Amount: 300},
+				{Currency: "USD", Count: 1, Amount: 990},
+			},
+		},
+		{
+			name:    "user by hour",
+			options: []domain.StatsFilterOption{domain.WithStatsUserID(userID(4)), domain.WithStatsGroupBy(domain.GroupByHour)},
+			total:   2,
+			groups: []domain.StatsGroup{
+				{Key: base.Add(24 * hour).Format(time.RFC3339), Count: 1},
+				{Key: base.Add(25 * hour).Format(time.RFC3339), Count: 1},
+			},
+			// The median of an even number of durations is interpolated
+			transitions: []domain.TransitionStats{
+				{From: domain.CoolOrderCreated, To: domain.SbuVerificationPending, Count: 2, MedianSeconds: 3.5 * minute},
+			},
+			// The order without an amount is not counted
+			amounts: []domain.CurrencyTotal{{Currency: "PLN", Count: 1, Amount: 300}},
+		},
+		{
+			name:    "period",
+			options: []domain.StatsFilterOption{domain.WithStatsPeriod(&from, &to)},
+			total:   3, chinazes: 1, canceled: 1,
+			groups: []domain.StatsGroup{
+				{Key: string(domain.SbuVerificationPending), Count: 1},
+				{Key: string(domain.Chinazes), Count: 1},
+				{Key: string(domain.ChangedMyMind), Count: 1},
+			},
+			transitions: []domain.TransitionStats{
+				{From: domain.CoolOrderCreated, To: domain.SbuVerificationPending, Count: 2, MedianSeconds: minute},
+				{From: domain.CoolOrderCreated, To: domain.ChangedMyMind, Count: 1, MedianSeconds: minute},
+				{From: domain.SbuVerificationPending, To: domain.ConfirmedByMayor, Count: 1, MedianSeconds: minute},
+				{From: domain.ConfirmedByMayor, To: domain.Chinazes, Count: 1, MedianSeconds: minute},
+			},
+			amounts: []domain.CurrencyTotal{{Currency: "EUR", Count: 1, Amount: 1500}},
+		},
+		{
+			name:    "no match",
+			options: []domain.StatsFilterOption{domain.WithStatsUserID(userID(9))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := domain.NewOrderStatsFilter(tt.options...)
+			stats, err := repos.Stats.Stats(filter)
+			if err != nil {
+				t.Fatalf("Failed to get stats: %v", err)
+			}
+
+			if stats.Total != tt.total || stats.Chinazes != tt.chinazes || stats.Refunded != tt.refunded || stats.Canceled != tt.canceled {
+				t.Errorf("Expected %d orders with %d chinazes, %d refunded and %d canceled, got %+v",
+					tt.total, tt.chinazes, tt.refunded, tt.canceled, stats)
+			}
+			if stats.GroupBy != filter.GroupBy {
+				t.Errorf("Expected stats grouped by %s, got %s", filter.GroupBy, stats.GroupBy)
+			}
+
+			expected := domain.OrderStats{Total: tt.total, Chinazes: tt.chinazes, Refunded: tt.refunded, Canceled: tt.canceled}
+			expected.CalculateRates()
+			if stats.Conversion != expected.Conversion || stats.RefundRate != expected.RefundRate || stats.CancelRate != expected.CancelRate {
+				t.Errorf("Expected rates %v, %v and %v, got %v, %v and %v",
+					expected.Conversion, expected.RefundRate, expected.CancelRate, stats.Conversion, stats.RefundRate, stats.CancelRate)
+			}
+
+			if len(stats.Groups) != 0 || len(tt.groups) != 0 {
+				if !reflect.DeepEqual(stats.Groups, tt.groups) {
+					t.Errorf("Expected groups %+v, got %+v", tt.groups, stats.Groups)
+				}
+			}
+			if len(stats.Amounts) != 0 || len(tt.amounts) != 0 {
+				if !reflect.DeepEqual(stats.Amounts, tt.amounts) {
+					t.Errorf("Expected amounts %+v, got %+v", tt.amounts, stats.Amounts)
+				}
+			}
+			// Transitions do not depend on grouping, only cases listing them check them
+			if tt.transitions != nil && !reflect.DeepEqual(stats.Transitions, tt.transitions) {
+				t.Errorf("Expected transitions %+v, got %+v", tt.transitions, stats.Transitions)
+			}
+		})
+	}
+}
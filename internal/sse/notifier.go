@@ -6,79 +6,101 @@ import (
 	"time"
 
 	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/pkg/clock"
 )
 
+// SSENotifier queues order events for the subscribers of the order. Events
+// are queued while holding the lock, so every subscriber gets them in the
+// same order they were notified.
 type SSENotifier struct {
 	mu              sync.Mutex
-	clients         map[string][]domain.OrderEventsSubscriber
-	processedEvents map[string][]string
+	clients         map[string][]*subscription
+	processedEvents map[string]map[string]bool
+	clock           clock.Clock
 }
 
-func NewSSENotifier() *SSENotifier {
+type subscription struct {
+	client *domain.OrderEventsSubscriber
+	// lastSent is when an event was last queued, the idle timeout runs from it
+	lastSent time.Time
+}
+
+func NewSSENotifier(clock clock.Clock) *SSENotifier {
 	return &SSENotifier{
-		clients:         make(map[string][]domain.OrderEventsSubscriber),
-		processedEvents: make(map[string][]string),
+		clients:         make(map[string][]*subscription),
+		processedEvents: make(map[string]map[string]bool),
+		clock:           clock,
 	}
 }
 
-func (n *SSENotifier) AddProcessedEvent(orderId string, event domain.OrderEvent) {
+func (n *SSENotifier) AddProcessedEvent(orderID string, event domain.OrderEvent) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	n.processedEvents[orderId] = append(n.processedEvents[orderId], event.EventID)
+	if len(n.clients[orderID]) > 0 {
+		n.markProcessed(orderID, event)
+	}
 }
 
-func (n *SSENotifier) RegisterClient(orderID string, client domain.OrderEventsSubscriber) {
+func (n *SSENotifier) RegisterClient(orderID string, client *domain.OrderEventsSubscriber) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	// Register the client
-	n.clients[orderID] = append(n.clients[orderID], client)
+	sub := &subscription{client: client, lastSent: n.clock.Now()}
+	n.clients[orderID] = append(n.clients[orderID], sub)
 
-	// Start the timeout handler
-	go n.startTimeout(orderID, client)
+	go n.watchIdle(orderID, sub)
 }
 
-func (n *SSENotifier) startTimeout(orderID string, client domain.OrderEventsSubscriber) {
-	timeout := time.NewTimer(client.Timeout)
+// watchIdle disconnects the client once no event was queued for it for its
+// idle timeout. The timer only fires at the earliest possible deadline and
+// is moved forward by the events queued in between.
+func (n *SSENotifier) watchIdle(orderID string, sub *subscription) {
+	timer := n.clock.NewTimer(sub.client.IdleTimeout)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-timeout.C:
-			fmt.Println("Timeout: Client timeout", orderID)
-			n.UnregisterClient(orderID, client)
-			return
-		case <-client.Disconnect:
-			fmt.Println("Timeout: Client disconnected", orderID)
-			n.UnregisterClient(orderID, client)
+		case <-sub.client.Done():
 			return
-		case event, ok := <-client.EventChan:
-			if !ok {
-				return
+		case <-timer.C():
+			n.mu.Lock()
+			remaining := sub.client.IdleTimeout - n.clock.Now().Sub(sub.lastSent)
+			n.mu.Unlock()
+
+			if remaining > 0 {
+				timer.Reset(remaining)
+				continue
 			}
 
-			// Reset the timeout and propagate the event
-			timeout.Reset(client.Timeout)
-			client.EventChan <- event
+			fmt.Println("Timeout: Client timeout", orderID)
+			n.disconnect(orderID, sub.client, domain.DisconnectIdle)
+			return
 		}
 	}
 }
 
-func (n *SSENotifier) UnregisterClient(orderID string, client domain.OrderEventsSubscriber) {
+func (n *SSENotifier) UnregisterClient(orderID string, client *domain.OrderEventsSubscriber) {
+	n.disconnect(orderID, client, domain.DisconnectClosed)
+}
+
+func (n *SSENotifier) disconnect(orderID string, client *domain.OrderEventsSubscriber, reason domain.DisconnectReason) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	n.remove(orderID, client, reason)
+}
+
+// remove unregisters the client and signals the reason to it. The lock must be held.
+func (n *SSENotifier) remove(orderID string, client *domain.OrderEventsSubscriber, reason domain.DisconnectReason) {
 	clients := n.clients[orderID]
-	for i, c := range clients {
-		if c == client {
-			// Remove client from the list
-			n.clients[orderID] = append(clients[:i], clients[i+1:]...)
-			// Close channels to signal the end of connection
-			close(client.EventChan)
-			close(client.Disconnect)
+	for i, sub := range clients {
+		if sub.client == client {
+			n.clients[orderID] = append(clients[:i:i], clients[i+1:]...)
 			break
 		}
 	}
+	client.Disconnect(reason)
 
 	// Clean up if there are no more clients for the order
 	if len(n.clients[orderID]) == 0 {
@@ -87,43 +109,76 @@ func (n *SSENotifier) UnregisterClient(orderID string, client domain.OrderEvents
 	}
 }
 
+// send queues the notification for every client of the order. A client whose
+// queue is full is disconnected with a resync signal, since it would miss the
+// notification. The lock must be held.
+func (n *SSENotifier) send(orderID string, notification domain.OrderNotification) {
+	now := n.clock.Now()
+	for _, sub := range n.clients[orderID] {
+		if sub.client.Send(notification) {
+			sub.lastSent = now
+			continue
+		}
+
+		fmt.Println("Queue overflow: client has to resync", orderID)
+		n.remove(orderID, sub.client, domain.DisconnectResync)
+	}
+}
+
 func (n *SSENotifier) Notify(order *domain.Order, event domain.OrderEvent) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	if event.IsFinal {
-		for _, client := range n.clients[order.OrderID] {
-			select {
-			case client.EventChan <- event:
-			default:
-			}
-		}
+	// Events are tracked only while the order has clients
+	if len(n.clients[order.OrderID]) == 0 {
+		return
+	}
 
+	if event.IsFinal {
+		n.send(order.OrderID, domain.OrderNotification{Event: event})
 		delete(n.processedEvents, order.OrderID)
 		return
 	}
 
 	for _, evt := range order.Events {
-		if !n.isEventProcessed(order.OrderID, evt) {
-			for _, client := range n.clients[order.OrderID] {
-				select {
-				case client.EventChan <- evt:
-				default:
-				}
-			}
-
-			n.processedEvents[order.OrderID] = append(n.processedEvents[order.OrderID], evt.EventID)
+		if !n.processedEvents[order.OrderID][evt.EventID] {
+			n.send(order.OrderID, domain.OrderNotification{Event: evt})
+			n.markProcessed(order.OrderID, evt)
 		}
 	}
 }
 
-func (n *SSENotifier) isEventProcessed(orderID string, event domain.OrderEvent) bool {
-	for _, cachedEventID := range n.processedEvents[orderID] {
-		if cachedEventID == event.EventID {
-			return true
+// NotifyAnomaly sends the rejected event to the clients of the order. It is not
+// marked as processed, so it is sent again once the sequence becomes valid.
+func (n *SSENotifier) NotifyAnomaly(order *domain.Order, violation domain.SequenceViolation) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, event := range order.Events {
+		if event.EventID != violation.EventID {
+			continue
 		}
+
+		event.RejectedReason = violation.Error()
+		n.send(order.OrderID, domain.OrderNotification{Event: event})
+	}
+}
+
+// NotifyStuck sends a notice that the order is stuck to its clients.
+func (n *SSENotifier) NotifyStuck(stuck domain.OrderStuck) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.send(stuck.OrderID, domain.OrderNotification{Stuck: &stuck})
+}
+
+// markProcessed records that the event was sent to the clients of the order.
+// The lock must be held.
+func (n *SSENotifier) markProcessed(orderID string, event domain.OrderEvent) {
+	if n.processedEvents[orderID] == nil {
+		n.processedEvents[orderID] = make(map[string]bool)
 	}
-	return false
+	n.processedEvents[orderID][event.EventID] = true
 }
 
 var _ domain.OrderObserver = new(SSENotifier)
@@ -0,0 +1,215 @@
+package sse_test
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/sse"
+	"github.com/therealyo/justdone/pkg/clock"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+func TestClientTimeout(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Now())
+	notifier := sse.NewSSENotifier(fakeClock)
+
+	client := domain.NewOrderEventsSubscriber(1, time.Minute)
+	notifier.RegisterClient("order1", client)
+	fakeClock.BlockUntil(1)
+
+	fakeClock.Advance(time.Minute - time.Nanosecond)
+	select {
+	case <-client.Done():
+		t.Fatalf("Expected client to stay connected before the timeout")
+	default:
+	}
+
+	fakeClock.Advance(time.Nanosecond)
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected client to be disconnected after the timeout")
+	}
+
+	if client.Reason() != domain.DisconnectIdle {
+		t.Errorf("Expected client to be disconnected as idle, got %q", client.Reason())
+	}
+}
+
+func TestClientTimeoutRestartsOnEvents(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Now())
+	notifier := sse.NewSSENotifier(fakeClock)
+
+	client := domain.NewOrderEventsSubscriber(10, time.Minute)
+	notifier.RegisterClient("order1", client)
+	fakeClock.BlockUntil(1)
+
+	fakeClock.Advance(30 * time.Second)
+	notifier.Notify(newOrder("order1", 1), domain.OrderEvent{})
+
+	// The timer fires at the initial deadline and is moved to a minute after the event
+	fakeClock.Advance(30 * time.Second)
+	fakeClock.BlockUntil(1)
+	if client.Reason() != "" {
+		t.Fatalf("Expected client to stay connected a minute after the event, got %q", client.Reason())
+	}
+
+	fakeClock.Advance(30 * time.Second)
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected client to be disconnected a minute after the event")
+	}
+
+	// The queued event is not consumed by the timeout
+	select {
+	case notification := <-client.Events():
+		if notification.Event.EventID != eventID(0) {
+			t.Errorf("Expected queued event %s, got %s", eventID(0), notification.Event.EventID)
+		}
+	default:
+		t.Errorf("Expected the event to stay queued")
+	}
+}
+
+func TestDeliversAllEventsInOrder(t *testing.T) {
+	// Clients get the default queue and read concurrently with the notifications,
+	// which carry several times more events than fit into a queue
+	const events, clients, queueSize = 1000, 10, 256
+	notifier := sse.NewSSENotifier(clock.New())
+
+	var wg sync.WaitGroup
+	received := make([][]string, clients)
+	progress := make([]atomic.Int64, clients)
+	subscribers := make([]*domain.OrderEventsSubscriber, clients)
+	for i := range subscribers {
+		subscribers[i] = domain.NewOrderEventsSubscriber(queueSize, time.Minute)
+		notifier.RegisterClient("order1", subscribers[i])
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for len(received[i]) < events {
+				select {
+				case notification := <-subscribers[i].Events():
+					received[i] = append(received[i], notification.Event.EventID)
+					progress[i].Add(1)
+				case <-subscribers[i].Done():
+					return
+				}
+			}
+		}(i)
+	}
+
+	// Every notification carries all events of the order so far. Like webhooks
+	// of a single order, they never get further ahead of a reader than half a queue.
+	for n := 1; n <= events; n++ {
+		for i := range progress {
+			for int64(n)-progress[i].Load() > queueSize/2 && subscribers[i].Reason() == "" {
+				runtime.Gosched()
+			}
+		}
+		notifier.Notify(newOrder("order1", n), domain.OrderEvent{})
+	}
+	wg.Wait()
+
+	for i, eventIDs := range received {
+		if reason := subscribers[i].Reason(); reason == domain.DisconnectResync {
+			t.Fatalf("Expected client %d to keep up without a resync", i)
+		} else if reason != "" {
+			t.Fatalf("Expected client %d to stay connected, got %q", i, reason)
+		}
+		if len(eventIDs) != events {
+			t.Fatalf("Expected client %d to receive %d events, got %d", i, events, len(eventIDs))
+		}
+		for n, id := range eventIDs {
+			if id != eventID(n) {
+				t.Fatalf("Expected client %d to receive %s at %d, got %s", i, eventID(n), n, id)
+			}
+		}
+		notifier.UnregisterClient("order1", subscribers[i])
+	}
+}
+
+func TestQueueOverflowDisconnectsWithResync(t *testing.T) {
+	notifier := sse.NewSSENotifier(clock.New())
+
+	slow := domain.NewOrderEventsSubscriber(2, time.Minute)
+	fast := domain.NewOrderEventsSubscriber(10, time.Minute)
+	notifier.RegisterClient("order1", slow)
+	notifier.RegisterClient("order1", fast)
+	defer notifier.UnregisterClient("order1", fast)
+
+	for n := 1; n <= 3; n++ {
+		notifier.Notify(newOrder("order1", n), domain.OrderEvent{})
+	}
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("Expected the client with a full queue to be disconnected")
+	}
+	if slow.Reason() != domain.DisconnectResync {
+		t.Errorf("Expected the client to be told to resync, got %q", slow.Reason())
+	}
+
+	// Other clients of the order are not affected
+	if fast.Reason() != "" {
+		t.Fatalf("Expected the other client to stay connected, got %q", fast.Reason())
+	}
+	for n := 0; n < 3; n++ {
+		if notification := <-fast.Events(); notification.Event.EventID != eventID(n) {
+			t.Errorf("Expected event %s, got %s", eventID(n), notification.Event.EventID)
+		}
+	}
+}
+
+func TestNotifyStuckSendsNotice(t *testing.T) {
+	notifier := sse.NewSSENotifier(clock.New())
+
+	client := domain.NewOrderEventsSubscriber(2, time.Minute)
+	notifier.RegisterClient("order1", client)
+	defer notifier.UnregisterClient("order1", client)
+
+	stuck := domain.OrderStuck{OrderID: "order1", UserID: "user1", Status: domain.SbuVerificationPending, SLASeconds: 60}
+	notifier.NotifyStuck(stuck)
+	notifier.NotifyStuck(domain.OrderStuck{OrderID: "order2"})
+
+	select {
+	case notification := <-client.Events():
+		if notification.Stuck == nil || *notification.Stuck != stuck {
+			t.Errorf("Expected stuck notice %+v, got %+v", stuck, notification.Stuck)
+		}
+		// The notice is not an event of the order
+		if notification.Event.EventID != "" || notification.Event.OrderID != "" {
+			t.Errorf("Expected no event with the stuck notice, got %+v", notification.Event)
+		}
+	default:
+		t.Fatal("Expected the stuck notice to be queued")
+	}
+
+	// Only clients of the stuck order are notified
+	select {
+	case notification := <-client.Events():
+		t.Errorf("Expected no notice for another order, got %+v", notification)
+	default:
+	}
+}
+
+func eventID(n int) string {
+	return fmt.Sprintf("event%d", n)
+}
+
+// newOrder returns an order with events event0 to event<n-1>.
+func newOrder(orderID string, n int) *domain.Order {
+	order := &domain.Order{OrderID: orderID, Status: domain.CoolOrderCreated}
+	for i := 0; i < n; i++ {
+		order.Events = append(order.Events, domain.OrderEvent{EventID: eventID(i), OrderID: orderID})
+	}
+	return order
+}
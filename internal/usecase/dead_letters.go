@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"github.com/therealyo/justdone/domain"
+)
+
+type DeadLetters struct {
+	deadLetterRepo domain.DeadLetterRepository
+	processor      *domain.OrderProcessor
+}
+
+func NewDeadLetters(deadLetterRepo domain.DeadLetterRepository, processor *domain.OrderProcessor) DeadLetters {
+	return DeadLetters{deadLetterRepo: deadLetterRepo, processor: processor}
+}
+
+func (d *DeadLetters) List(filter domain.DeadLetterFilter) ([]domain.DeadLetterEvent, error) {
+	return d.deadLetterRepo.List(filter)
+}
+
+func (d *DeadLetters) Get(eventID string) (*domain.DeadLetterEvent, error) {
+	event, err := d.deadLetterRepo.Get(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, domain.ErrDeadLetterNotFound
+	}
+	return event, nil
+}
+
+// Retry feeds the event to the processor again. The processor removes the
+// dead letter when the event is applied and counts another attempt when it
+// fails again. An event ignored for an invalid sequence is already stored,
+// it is applied to the order once the missing events arrived.
+func (d *DeadLetters) Retry(eventID string) error {
+	event, err := d.Get(eventID)
+	if err != nil {
+		return err
+	}
+
+	return d.processor.RetryEvent(event.Payload)
+}
+
+// Discard removes the event without processing it.
+func (d *DeadLetters) Discard(eventID string) error {
+	if _, err := d.Get(eventID); err != nil {
+		return err
+	}
+
+	return d.deadLetterRepo.Delete(eventID)
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+type Exports struct {
+	exportRepo domain.OrderExportRepository
+	clock      clock.Clock
+}
+
+func NewExports(exportRepo domain.OrderExportRepository, clock clock.Clock) Exports {
+	return Exports{exportRepo: exportRepo, clock: clock}
+}
+
+func (e *Exports) ExportOrders(filter *domain.OrderFilter, fn func(domain.Order) error) error {
+	filter.Now = e.clock.Now()
+	return e.exportRepo.StreamOrders(filter, fn)
+}
+
+func (e *Exports) ExportEvents(orderID string, fn func(domain.OrderEvent) error) error {
+	return e.exportRepo.StreamEvents(orderID, fn)
+}
@@ -2,14 +2,17 @@ package usecase
 
 import (
 	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/pkg/clock"
 )
 
 type Orders struct {
-	orderRepo domain.OrderRepository
+	orderRepo       domain.OrderRepository
+	countExactLimit int
+	clock           clock.Clock
 }
 
-func NewOrders(orderRepo domain.OrderRepository) Orders {
-	return Orders{orderRepo: orderRepo}
+func NewOrders(orderRepo domain.OrderRepository, countExactLimit int, clock clock.Clock) Orders {
+	return Orders{orderRepo: orderRepo, countExactLimit: countExactLimit, clock: clock}
 }
 
 func (o *Orders) GetOrder(id string) (*domain.Order, error) {
@@ -22,9 +25,40 @@ func (o *Orders) GetOrder(id string) (*domain.Order, error) {
 }
 
 func (o *Orders) GetOrders(filter *domain.OrderFilter) ([]domain.Order, error) {
+	o.setNow(filter)
 	orders, err := o.orderRepo.GetMany(filter)
 	if err != nil {
 		return nil, err
 	}
 	return orders, nil
 }
+
+func (o *Orders) CountOrders(filter *domain.OrderFilter) (*domain.OrderCount, error) {
+	o.setNow(filter)
+	count, err := o.orderRepo.Count(filter, o.countExactLimit)
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// setNow sets the reference time of the filter from the clock, unless the
+// filter was already used, so the orders and their count match.
+func (o *Orders) setNow(filter *domain.OrderFilter) {
+	if filter.Now.IsZero() {
+		filter.Now = o.clock.Now()
+	}
+}
+
+// GetTimeline returns the transitions of the order built from its stored events.
+func (o *Orders) GetTimeline(id string) (*domain.OrderTimeline, error) {
+	order, err := o.orderRepo.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	return domain.NewOrderTimeline(order, o.clock.Now()), nil
+}
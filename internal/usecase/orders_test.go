@@ -0,0 +1,96 @@
+package usecase_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+func TestGetTimelineCurrentStatusDuration(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start.Add(time.Hour))
+	storage := inmemory.NewStorage()
+	orders := inmemory.NewOrderRepository(storage)
+	events := inmemory.NewEventRepository(storage)
+
+	order := &domain.Order{OrderID: "order1", UserID: "user1", Status: domain.SbuVerificationPending, CreatedAt: start, UpdatedAt: start}
+	if err := orders.Save(order); err != nil {
+		t.Fatalf("Failed to save order: %v", err)
+	}
+	for i, status := range []domain.OrderStatus{domain.CoolOrderCreated, domain.SbuVerificationPending} {
+		createdAt := start.Add(time.Duration(i) * 10 * time.Minute)
+		event := domain.OrderEvent{EventID: fmt.Sprintf("event%d", i+1), OrderID: "order1", UserID: "user1", OrderStatus: status, CreatedAt: createdAt, UpdatedAt: createdAt}
+		if err := events.Create(event); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	usecaseOrders := usecase.NewOrders(orders, 0, fakeClock)
+
+	assertDurations := func(expected ...float64) {
+		t.Helper()
+		timeline, err := usecaseOrders.GetTimeline("order1")
+		if err != nil {
+			t.Fatalf("Failed to get timeline: %v", err)
+		}
+		if len(timeline.Transitions) != len(expected) {
+			t.Fatalf("Expected %d transitions, got %+v", len(expected), timeline.Transitions)
+		}
+		for i, transition := range timeline.Transitions {
+			if transition.DurationSeconds == nil || *transition.DurationSeconds != expected[i] {
+				t.Errorf("Expected %s to last %vs, got %v", transition.To, expected[i], transition.DurationSeconds)
+			}
+		}
+	}
+
+	// The current status lasts from its event until the clock's now
+	assertDurations(600, 3000)
+
+	fakeClock.Advance(30 * time.Minute)
+	assertDurations(600, 4800)
+
+	if _, err := usecaseOrders.GetTimeline("order2"); !errors.Is(err, domain.ErrOrderNotFound) {
+		t.Errorf("Expected %v for a missing order, got %v", domain.ErrOrderNotFound, err)
+	}
+}
+
+func TestGetOrdersStuckForUsesClock(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start.Add(30 * time.Minute))
+	orders := inmemory.NewOrderRepository(inmemory.NewStorage())
+
+	order := &domain.Order{OrderID: "order1", UserID: "user1", Status: domain.SbuVerificationPending, CreatedAt: start, UpdatedAt: start}
+	if err := orders.Save(order); err != nil {
+		t.Fatalf("Failed to save order: %v", err)
+	}
+
+	usecaseOrders := usecase.NewOrders(orders, 0, fakeClock)
+
+	assertStuck := func(expected int) {
+		t.Helper()
+		filter := domain.NewOrderFilter(domain.WithStuckFor(time.Hour))
+		got, err := usecaseOrders.GetOrders(filter)
+		if err != nil {
+			t.Fatalf("Failed to get orders: %v", err)
+		}
+		count, err := usecaseOrders.CountOrders(filter)
+		if err != nil {
+			t.Fatalf("Failed to count orders: %v", err)
+		}
+		if len(got) != expected || count.Total != expected {
+			t.Errorf("Expected %d stuck orders, got %d and a count of %d", expected, len(got), count.Total)
+		}
+	}
+
+	// Stuck orders are relative to the clock's now, not the wall clock
+	assertStuck(0)
+
+	fakeClock.Advance(time.Hour)
+	assertStuck(1)
+}
@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+// reconciliationBatch is the number of orders read at once to match settlement records.
+const reconciliationBatch = 500
+
+// correctionNamespace derives IDs of corrective events from the order and the
+// status, so a correction applied by several runs creates its event once.
+// Corrections that are not applied are discarded, so a later run can create
+// the event again.
+var correctionNamespace = uuid.MustParse("6f1c3a52-8d0e-4b7a-9c35-2e4f7d9b1a60")
+
+type Reconciliation struct {
+	orderRepo domain.OrderRepository
+	auditRepo domain.ReconciliationAuditRepository
+	processor *domain.OrderProcessor
+	clock     clock.Clock
+}
+
+func NewReconciliation(
+	orderRepo domain.OrderRepository,
+	auditRepo domain.ReconciliationAuditRepository,
+	processor *domain.OrderProcessor,
+	clock clock.Clock,
+) Reconciliation {
+	return Reconciliation{orderRepo: orderRepo, auditRepo: auditRepo, processor: processor, clock: clock}
+}
+
+// Reconcile matches the settlement records to stored orders by order ID and
+// reports the discrepancies, including stored orders of the period that are
+// missing from the file. Without bounds the period spans the creation times
+// of the stored orders in the file. With correct set, the corrections of
+// every discrepancy are fed to the processor as synthetic events and recorded
+// in the audit trail, whether they were applied or not.
+//
+// Orders are listed from the replica, which may lag behind. Before an order is
+// corrected it is read again from the primary, so an order created meanwhile
+// does not get a second cool_order_created event that would block it forever.
+func (r *Reconciliation) Reconcile(
+	source string,
+	records []domain.SettlementRecord,
+	period domain.SettlementPeriod,
+	correct bool,
+) (*domain.ReconciliationReport, error) {
+	report := &domain.ReconciliationReport{
+		RunID:         uuid.NewString(),
+		Source:        source,
+		Rows:          len(records),
+		Discrepancies: []domain.Discrepancy{},
+	}
+	derived := period.From == nil && period.To == nil
+
+	for start := 0; start < len(records); start += reconciliationBatch {
+		batch := records[start:min(start+reconciliationBatch, len(records))]
+
+		orderIDs := make([]string, len(batch))
+		for i, record := range batch {
+			orderIDs[i] = record.OrderID
+		}
+		orders, err := r.orderRepo.GetMany(domain.NewOrderFilter(
+			domain.WithOrderIDs(orderIDs...),
+			domain.WithLimit(len(orderIDs)),
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		stored := make(map[string]*domain.Order, len(orders))
+		for i := range orders {
+			stored[orders[i].OrderID] = &orders[i]
+			if derived {
+				period = extendPeriod(period, orders[i].CreatedAt)
+			}
+		}
+
+		for _, record := range batch {
+			order := stored[record.OrderID]
+			discrepancy := domain.Reconcile(record, order)
+			if discrepancy != nil && correct && len(discrepancy.Corrections) > 0 {
+				if order, err = r.orderRepo.Get(record.OrderID); err != nil {
+					return nil, err
+				}
+				discrepancy = domain.Reconcile(record, order)
+			}
+			if discrepancy == nil {
+				report.Matched++
+				continue
+			}
+
+			report.Discrepancies = append(report.Discrepancies, *discrepancy)
+			if correct && len(discrepancy.Corrections) > 0 {
+				if err := r.correct(report, record, order, discrepancy.Corrections); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if period.From != nil || period.To != nil {
+		report.Period = &period
+		if err := r.reportUnsettled(report, records, period); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// extendPeriod returns the period extended to include an order created at createdAt.
+func extendPeriod(period domain.SettlementPeriod, createdAt time.Time) domain.SettlementPeriod {
+	if period.From == nil || createdAt.Before(*period.From) {
+		period.From = &createdAt
+	}
+	if to := createdAt.Add(time.Microsecond); period.To == nil || to.After(*period.To) {
+		period.To = &to
+	}
+	return period
+}
+
+// reportUnsettled adds a discrepancy for every stored order of the period that
+// is not in the settlement records.
+func (r *Reconciliation) reportUnsettled(report *domain.ReconciliationReport, records []domain.SettlementRecord, period domain.SettlementPeriod) error {
+	settled := make(map[string]bool, len(records))
+	for _, record := range records {
+		settled[record.OrderID] = true
+	}
+
+	for offset := 0; ; offset += reconciliationBatch {
+		orders, err := r.orderRepo.GetMany(domain.NewOrderFilter(
+			domain.WithCreatedBetween(period.From, period.To),
+			domain.WithSort(domain.OrderSort{{Field: domain.SortByCreatedAt}}),
+			domain.WithLimit(reconciliationBatch),
+			domain.WithOffset(offset),
+		))
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if settled[order.OrderID] {
+				continue
+			}
+			report.Discrepancies = append(report.Discrepancies, domain.Discrepancy{
+				OrderID:     order.OrderID,
+				Kind:        domain.DiscrepancyMissingSettlement,
+				OrderStatus: order.Status,
+				OrderFinal:  order.IsFinal,
+			})
+		}
+
+		if len(orders) < reconciliationBatch {
+			return nil
+		}
+	}
+}
+
+// correct processes an event for every status in order. Processing stops at
+// the first event that is not applied, since the next ones would not be either.
+func (r *Reconciliation) correct(report *domain.ReconciliationReport, record domain.SettlementRecord, order *domain.Order, statuses []domain.OrderStatus) error {
+	userID := record.UserID
+	createdAt := r.clock.Now()
+	if order != nil {
+		userID = order.UserID
+		// Corrections must be sorted after the stored events of the order
+		if !order.UpdatedAt.Before(createdAt) {
+			createdAt = order.UpdatedAt.Add(time.Microsecond)
+		}
+	}
+
+	for i, status := range statuses {
+		// An existing order already has its initial event
+		if order != nil && status == domain.CoolOrderCreated {
+			continue
+		}
+
+		eventCreatedAt := createdAt.Add(time.Duration(i) * time.Microsecond)
+		event := domain.OrderEvent{
+			EventID:     uuid.NewSHA1(correctionNamespace, []byte(record.OrderID+"/"+string(status))).String(),
+			OrderID:     record.OrderID,
+			UserID:      userID,
+			OrderStatus: status,
+			CreatedAt:   eventCreatedAt,
+			UpdatedAt:   eventCreatedAt,
+		}
+
+		correction := domain.ReconciliationCorrection{
+			RunID:     report.RunID,
+			EventID:   event.EventID,
+			OrderID:   event.OrderID,
+			Status:    status,
+			Source:    report.Source,
+			CreatedAt: r.clock.Now(),
+		}
+		processErr := r.processor.HandleEvent(event)
+		if errors.Is(processErr, domain.ErrEventConflict) {
+			// The event of an earlier run that was not applied is replaced
+			if r.processor.DiscardEvent(event.EventID) == nil {
+				processErr = r.processor.HandleEvent(event)
+			}
+		}
+		if processErr != nil {
+			correction.Error = processErr.Error()
+			// A stored event that was not applied would stay pending on the order
+			if !errors.Is(processErr, domain.ErrEventConflict) && !errors.Is(processErr, domain.ErrEventInProgress) {
+				if err := r.processor.DiscardEvent(event.EventID); err != nil {
+					fmt.Printf("error discarding corrective event %s: %v\n", event.EventID, err)
+				}
+			}
+		}
+
+		if err := r.auditRepo.Add(correction); err != nil {
+			return err
+		}
+		report.Corrections = append(report.Corrections, correction)
+
+		if processErr != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciliation) Corrections(filter domain.ReconciliationCorrectionFilter) ([]domain.ReconciliationCorrection, error) {
+	return r.auditRepo.List(filter)
+}
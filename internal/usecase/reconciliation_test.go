@@ -0,0 +1,309 @@
+package usecase_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/console"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+func TestReconciliation(t *testing.T) {
+	const (
+		behind  = "00000000-0000-4000-8000-000000000001"
+		missing = "00000000-0000-4000-8000-000000000002"
+		ahead   = "00000000-0000-4000-8000-000000000003"
+		matched = "00000000-0000-4000-8000-000000000004"
+		user    = "10000000-0000-4000-8000-000000000001"
+	)
+
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC))
+	storage := inmemory.NewStorage()
+	orders := inmemory.NewOrderRepository(storage)
+	audit := inmemory.NewReconciliationRepository()
+	processor := domain.NewOrderProcessor(
+		orders,
+		inmemory.NewEventRepository(storage),
+		console.NewConsoleNotifier(),
+		inmemory.NewProcessedEvents(),
+		inmemory.NewLocker(),
+		inmemory.NewDeadLetterRepository(),
+		time.Minute,
+		fakeClock,
+	)
+
+	for _, event := range []domain.OrderEvent{
+		{EventID: "20000000-0000-4000-8000-000000000001", OrderID: behind, UserID: user, OrderStatus: domain.CoolOrderCreated},
+		{EventID: "20000000-0000-4000-8000-000000000003", OrderID: ahead, UserID: user, OrderStatus: domain.CoolOrderCreated},
+		{EventID: "20000000-0000-4000-8000-000000000004", OrderID: ahead, UserID: user, OrderStatus: domain.SbuVerificationPending, CreatedAt: fakeClock.Now()},
+		{EventID: "20000000-0000-4000-8000-000000000005", OrderID: matched, UserID: user, OrderStatus: domain.CoolOrderCreated},
+	} {
+		if err := processor.HandleEvent(event); err != nil {
+			t.Fatalf("Failed to process event: %v", err)
+		}
+	}
+
+	reconciliation := usecase.NewReconciliation(orders, audit, processor, fakeClock)
+	records := []domain.SettlementRecord{
+		{OrderID: behind, Status: domain.ConfirmedByMayor},
+		{OrderID: missing, UserID: user, Status: domain.ChangedMyMind, IsFinal: true},
+		{OrderID: ahead, Status: domain.CoolOrderCreated},
+		{OrderID: matched, Status: domain.CoolOrderCreated},
+	}
+
+	report, err := reconciliation.Reconcile("settlement.csv", records, domain.SettlementPeriod{}, true)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if report.Rows != 4 || report.Matched != 1 || len(report.Discrepancies) != 3 {
+		t.Fatalf("Expected 1 matched order and 3 discrepancies, got %+v", report)
+	}
+	// Two events for each correctable order, the order ahead is only reported
+	if len(report.Corrections) != 4 {
+		t.Fatalf("Expected 4 corrections, got %+v", report.Corrections)
+	}
+	for _, correction := range report.Corrections {
+		if correction.Error != "" {
+			t.Errorf("Expected correction to be applied, got %+v", correction)
+		}
+	}
+
+	for orderID, status := range map[string]domain.OrderStatus{behind: domain.ConfirmedByMayor, missing: domain.ChangedMyMind} {
+		order, err := orders.Get(orderID)
+		if err != nil || order == nil || order.Status != status {
+			t.Errorf("Expected order %s to be corrected to %s, got %+v, %v", orderID, status, order, err)
+		}
+	}
+
+	audited, err := reconciliation.Corrections(domain.ReconciliationCorrectionFilter{RunID: report.RunID})
+	if err != nil || len(audited) != 4 {
+		t.Errorf("Expected 4 corrections in the audit trail, got %+v, %v", audited, err)
+	}
+
+	// Corrected orders match on the next run
+	report, err = reconciliation.Reconcile("settlement.csv", records, domain.SettlementPeriod{}, true)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if report.Matched != 3 || len(report.Corrections) != 0 {
+		t.Errorf("Expected only the order ahead to differ, got %+v", report)
+	}
+}
+
+// laggingOrderRepository lists no orders, like a replica that did not catch up yet.
+type laggingOrderRepository struct {
+	domain.OrderRepository
+}
+
+func (laggingOrderRepository) GetMany(filter *domain.OrderFilter) ([]domain.Order, error) {
+	return nil, nil
+}
+
+func TestReconciliationReadsPrimaryBeforeCorrecting(t *testing.T) {
+	const (
+		orderID = "00000000-0000-4000-8000-000000000001"
+		user    = "10000000-0000-4000-8000-000000000001"
+	)
+
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC))
+	storage := inmemory.NewStorage()
+	orders := inmemory.NewOrderRepository(storage)
+	processor := domain.NewOrderProcessor(
+		orders,
+		inmemory.NewEventRepository(storage),
+		console.NewConsoleNotifier(),
+		inmemory.NewProcessedEvents(),
+		inmemory.NewLocker(),
+		inmemory.NewDeadLetterRepository(),
+		time.Minute,
+		fakeClock,
+	)
+
+	created := domain.OrderEvent{EventID: "20000000-0000-4000-8000-000000000001", OrderID: orderID, UserID: user, OrderStatus: domain.CoolOrderCreated}
+	if err := processor.HandleEvent(created); err != nil {
+		t.Fatalf("Failed to process event: %v", err)
+	}
+
+	reconciliation := usecase.NewReconciliation(laggingOrderRepository{orders}, inmemory.NewReconciliationRepository(), processor, fakeClock)
+	records := []domain.SettlementRecord{{OrderID: orderID, UserID: user, Status: domain.SbuVerificationPending}}
+
+	report, err := reconciliation.Reconcile("settlement.csv", records, domain.SettlementPeriod{}, true)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+
+	// The stored order only misses the verification event
+	if len(report.Corrections) != 1 || report.Corrections[0].Status != domain.SbuVerificationPending || report.Corrections[0].Error != "" {
+		t.Fatalf("Expected a single applied sbu_verification_pending correction, got %+v", report.Corrections)
+	}
+	if len(report.Discrepancies) != 1 || report.Discrepancies[0].Kind != domain.DiscrepancyStatusMismatch {
+		t.Errorf("Expected the discrepancy of the stored order, got %+v", report.Discrepancies)
+	}
+
+	order, err := orders.Get(orderID)
+	if err != nil || order == nil || order.Status != domain.SbuVerificationPending {
+		t.Errorf("Expected order to be corrected to sbu_verification_pending, got %+v, %v", order, err)
+	}
+}
+
+func TestReconciliationReportsUnsettledOrders(t *testing.T) {
+	const user = "10000000-0000-4000-8000-000000000001"
+	orderID := func(n int) string { return fmt.Sprintf("00000000-0000-4000-8000-%012d", n) }
+
+	start := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start.Add(24 * time.Hour))
+	storage := inmemory.NewStorage()
+	orders := inmemory.NewOrderRepository(storage)
+	processor := domain.NewOrderProcessor(
+		orders,
+		inmemory.NewEventRepository(storage),
+		console.NewConsoleNotifier(),
+		inmemory.NewProcessedEvents(),
+		inmemory.NewLocker(),
+		inmemory.NewDeadLetterRepository(),
+		time.Minute,
+		fakeClock,
+	)
+
+	// Orders are created an hour apart
+	for n := 1; n <= 4; n++ {
+		createdAt := start.Add(time.Duration(n) * time.Hour)
+		event := domain.OrderEvent{
+			EventID:     fmt.Sprintf("20000000-0000-4000-8000-%012d", n),
+			OrderID:     orderID(n),
+			UserID:      user,
+			OrderStatus: domain.CoolOrderCreated,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		}
+		if err := processor.HandleEvent(event); err != nil {
+			t.Fatalf("Failed to process event: %v", err)
+		}
+	}
+
+	reconciliation := usecase.NewReconciliation(orders, inmemory.NewReconciliationRepository(), processor, fakeClock)
+	records := []domain.SettlementRecord{
+		{OrderID: orderID(1), Status: domain.CoolOrderCreated},
+		{OrderID: orderID(3), Status: domain.CoolOrderCreated},
+	}
+
+	unsettled := func(period domain.SettlementPeriod) []string {
+		t.Helper()
+		report, err := reconciliation.Reconcile("settlement.csv", records, period, false)
+		if err != nil {
+			t.Fatalf("Failed to reconcile: %v", err)
+		}
+		if report.Matched != 2 {
+			t.Errorf("Expected the settled orders to match, got %+v", report)
+		}
+
+		var orderIDs []string
+		for _, discrepancy := range report.Discrepancies {
+			if discrepancy.Kind != domain.DiscrepancyMissingSettlement || discrepancy.OrderStatus != domain.CoolOrderCreated {
+				t.Errorf("Expected only unsettled orders, got %+v", discrepancy)
+			}
+			orderIDs = append(orderIDs, discrepancy.OrderID)
+		}
+		return orderIDs
+	}
+
+	// Without bounds the period spans the settled orders
+	if got := unsettled(domain.SettlementPeriod{}); !reflect.DeepEqual(got, []string{orderID(2)}) {
+		t.Errorf("Expected order 2 to be unsettled, got %v", got)
+	}
+
+	from, to := start, start.Add(24*time.Hour)
+	if got := unsettled(domain.SettlementPeriod{From: &from, To: &to}); !reflect.DeepEqual(got, []string{orderID(2), orderID(4)}) {
+		t.Errorf("Expected orders 2 and 4 to be unsettled, got %v", got)
+	}
+}
+
+// staleOrderRepository returns a stale copy of the order on Get, like a
+// primary read that raced with a webhook.
+type staleOrderRepository struct {
+	domain.OrderRepository
+	stale domain.Order
+}
+
+func (r staleOrderRepository) Get(orderID string) (*domain.Order, error) {
+	if orderID == r.stale.OrderID {
+		order := r.stale
+		return &order, nil
+	}
+	return r.OrderRepository.Get(orderID)
+}
+
+func TestReconciliationReplacesRejectedCorrections(t *testing.T) {
+	const (
+		orderID = "00000000-0000-4000-8000-000000000001"
+		user    = "10000000-0000-4000-8000-000000000001"
+	)
+
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC))
+	storage := inmemory.NewStorage()
+	orders := inmemory.NewOrderRepository(storage)
+	deadLetters := inmemory.NewDeadLetterRepository()
+	processor := domain.NewOrderProcessor(
+		orders,
+		inmemory.NewEventRepository(storage),
+		console.NewConsoleNotifier(),
+		inmemory.NewProcessedEvents(),
+		inmemory.NewLocker(),
+		deadLetters,
+		time.Minute,
+		fakeClock,
+	)
+
+	created := domain.OrderEvent{EventID: "20000000-0000-4000-8000-000000000001", OrderID: orderID, UserID: user, OrderStatus: domain.CoolOrderCreated}
+	if err := processor.HandleEvent(created); err != nil {
+		t.Fatalf("Failed to process event: %v", err)
+	}
+	records := []domain.SettlementRecord{{OrderID: orderID, Status: domain.ConfirmedByMayor}}
+
+	// The first run sees the order verified already, so the mayor's confirmation is rejected
+	stale := domain.Order{OrderID: orderID, UserID: user, Status: domain.SbuVerificationPending}
+	first := usecase.NewReconciliation(staleOrderRepository{orders, stale}, inmemory.NewReconciliationRepository(), processor, fakeClock)
+	report, err := first.Reconcile("settlement.csv", records, domain.SettlementPeriod{}, true)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if len(report.Corrections) != 1 || report.Corrections[0].Error == "" {
+		t.Fatalf("Expected a rejected correction, got %+v", report.Corrections)
+	}
+	rejected := report.Corrections[0]
+
+	// The rejected event is discarded instead of waiting on the order
+	if deadLetter, _ := deadLetters.Get(rejected.EventID); deadLetter != nil {
+		t.Errorf("Expected no dead letter for the rejected correction, got %+v", deadLetter)
+	}
+	order, err := orders.Get(orderID)
+	if err != nil || order == nil || len(order.Events) != 1 {
+		t.Fatalf("Expected only the created event on the order, got %+v, %v", order, err)
+	}
+
+	// A later run creates the corrections again
+	fakeClock.Advance(time.Minute)
+	second := usecase.NewReconciliation(orders, inmemory.NewReconciliationRepository(), processor, fakeClock)
+	report, err = second.Reconcile("settlement.csv", records, domain.SettlementPeriod{}, true)
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if len(report.Corrections) != 2 {
+		t.Fatalf("Expected 2 corrections, got %+v", report.Corrections)
+	}
+	for _, correction := range report.Corrections {
+		if correction.Error != "" {
+			t.Errorf("Expected correction to be applied, got %+v", correction)
+		}
+	}
+
+	order, err = orders.Get(orderID)
+	if err != nil || order == nil || order.Status != domain.ConfirmedByMayor {
+		t.Errorf("Expected order to be corrected to confirmed_by_mayor, got %+v, %v", order, err)
+	}
+}
@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"github.com/therealyo/justdone/domain"
+)
+
+type Stats struct {
+	statsRepo domain.OrderStatsRepository
+}
+
+func NewStats(statsRepo domain.OrderStatsRepository) Stats {
+	return Stats{statsRepo: statsRepo}
+}
+
+func (s *Stats) GetStats(filter *domain.OrderStatsFilter) (*domain.OrderStats, error) {
+	stats, err := s.statsRepo.Stats(filter)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+// stuckCheckLimit is the number of the longest stuck orders notified on every check.
+const stuckCheckLimit = 1000
+
+// StuckOrders finds non-final orders that stayed in their status longer than
+// the SLA of the status and notifies their subscribers.
+type StuckOrders struct {
+	orderRepo domain.OrderRepository
+	observer  domain.OrderObserver
+	slas      map[domain.OrderStatus]time.Duration
+	clock     clock.Clock
+
+	mu sync.Mutex
+	// notified is the last update of the orders already reported as stuck,
+	// an order is reported again only after it was updated
+	notified map[string]time.Time
+}
+
+func NewStuckOrders(
+	orderRepo domain.OrderRepository,
+	observer domain.OrderObserver,
+	slas map[domain.OrderStatus]time.Duration,
+	clock clock.Clock,
+) *StuckOrders {
+	return &StuckOrders{
+		orderRepo: orderRepo,
+		observer:  observer,
+		slas:      slas,
+		clock:     clock,
+		notified:  make(map[string]time.Time),
+	}
+}
+
+// List returns up to limit stuck orders of the user, or of all users when
+// userID is empty, the longest stuck first.
+func (s *StuckOrders) List(userID string, limit int) ([]domain.OrderStuck, error) {
+	now := s.clock.Now()
+	notFinal := false
+
+	stuck := []domain.OrderStuck{}
+	for status, sla := range s.slas {
+		deadline := now.Add(-sla)
+		orders, err := s.orderRepo.GetMany(domain.NewOrderFilter(
+			domain.WithStatus(status),
+			domain.WithIsFinal(&notFinal),
+			domain.WithUpdatedBetween(nil, &deadline),
+			domain.WithUserID(userID),
+			domain.WithLimit(limit),
+			domain.WithSort(domain.OrderSort{{Field: domain.SortByUpdatedAt}}),
+		))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, order := range orders {
+			stuck = append(stuck, domain.NewOrderStuck(order, sla, now))
+		}
+	}
+
+	sort.SliceStable(stuck, func(i, j int) bool {
+		if stuck[i].StuckSeconds != stuck[j].StuckSeconds {
+			return stuck[i].StuckSeconds > stuck[j].StuckSeconds
+		}
+		return stuck[i].OrderID < stuck[j].OrderID
+	})
+	if len(stuck) > limit {
+		stuck = stuck[:limit]
+	}
+	return stuck, nil
+}
+
+// Check notifies the observer of orders that became stuck since the last check.
+func (s *StuckOrders) Check() error {
+	stuck, err := s.List("", stuckCheckLimit)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notified := make(map[string]time.Time, len(stuck))
+	for _, order := range stuck {
+		if updatedAt, ok := s.notified[order.OrderID]; !ok || !updatedAt.Equal(order.UpdatedAt) {
+			s.observer.NotifyStuck(order)
+		}
+		notified[order.OrderID] = order.UpdatedAt
+	}
+	// Orders that are no longer stuck are forgotten
+	s.notified = notified
+
+	return nil
+}
+
+// Run checks for stuck orders every interval until the context is done.
+// A zero interval disables the checks.
+func (s *StuckOrders) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || len(s.slas) == 0 {
+		return
+	}
+
+	timer := s.clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			if err := s.Check(); err != nil {
+				fmt.Printf("error checking stuck orders: %v\n", err)
+			}
+			timer.Reset(interval)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/domain"
+	"github.com/therealyo/justdone/internal/console"
+	"github.com/therealyo/justdone/internal/inmemory"
+	"github.com/therealyo/justdone/internal/usecase"
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+type stuckObserver struct {
+	*console.ConsoleNotifier
+	stuck chan domain.OrderStuck
+}
+
+func (o stuckObserver) NotifyStuck(stuck domain.OrderStuck) {
+	o.stuck <- stuck
+}
+
+func TestStuckOrders(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start)
+	orders := inmemory.NewOrderRepository(inmemory.NewStorage())
+	observer := stuckObserver{console.NewConsoleNotifier(), make(chan domain.OrderStuck, 10)}
+
+	save := func(orderID string, status domain.OrderStatus, isFinal bool) {
+		order := &domain.Order{OrderID: orderID, UserID: "user1", Status: status, IsFinal: isFinal, UpdatedAt: start}
+		if err := orders.Save(order); err != nil {
+			t.Fatalf("Failed to save order: %v", err)
+		}
+	}
+	save("created", domain.CoolOrderCreated, false)
+	save("chinazes", domain.Chinazes, false)
+	save("refunded", domain.GiveMyMoneyBack, true)
+
+	stuckOrders := usecase.NewStuckOrders(orders, observer, map[domain.OrderStatus]time.Duration{
+		domain.CoolOrderCreated: time.Hour,
+		domain.Chinazes:         10 * time.Minute,
+	}, fakeClock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stuckOrders.Run(ctx, time.Minute)
+	fakeClock.BlockUntil(1)
+
+	expectStuck := func(orderIDs ...string) {
+		t.Helper()
+		for _, orderID := range orderIDs {
+			select {
+			case stuck := <-observer.stuck:
+				if stuck.OrderID != orderID {
+					t.Errorf("Expected %s to be stuck, got %+v", orderID, stuck)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Expected %s to be stuck", orderID)
+			}
+		}
+		select {
+		case stuck := <-observer.stuck:
+			t.Errorf("Expected no more stuck orders, got %+v", stuck)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Checks run every minute
+	tick := func(minutes int) {
+		for i := 0; i < minutes; i++ {
+			fakeClock.Advance(time.Minute)
+			fakeClock.BlockUntil(1)
+		}
+	}
+
+	// Only chinazes passed its SLA
+	tick(11)
+	expectStuck("chinazes")
+
+	// Orders already reported are not reported again
+	tick(50)
+	expectStuck("created")
+
+	list, err := stuckOrders.List("user1", 10)
+	if err != nil {
+		t.Fatalf("Failed to list stuck orders: %v", err)
+	}
+	if len(list) != 2 || list[0].StuckSeconds != 3660 || list[0].SLASeconds != 600 || list[1].SLASeconds != 3600 {
+		t.Errorf("Expected both non-final orders to be stuck for 61 minutes, got %+v", list)
+	}
+}
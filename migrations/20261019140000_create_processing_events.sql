@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+CREATE TABLE processing_events (
+    event_id UUID PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP TABLE IF EXISTS processing_events;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+CREATE TABLE dead_letter_events (
+    event_id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_dead_letter_events_order_id ON dead_letter_events(order_id);
+CREATE INDEX idx_dead_letter_events_updated_at ON dead_letter_events(updated_at DESC, event_id);
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP TABLE IF EXISTS dead_letter_events;
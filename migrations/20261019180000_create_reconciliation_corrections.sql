@@ -0,0 +1,21 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+CREATE TABLE reconciliation_corrections (
+    run_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    order_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    source TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (run_id, event_id)
+);
+
+CREATE INDEX idx_reconciliation_corrections_order_id ON reconciliation_corrections(order_id);
+CREATE INDEX idx_reconciliation_corrections_created_at ON reconciliation_corrections(created_at DESC, run_id, event_id);
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP TABLE IF EXISTS reconciliation_corrections;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied.
+
+ALTER TABLE orders
+    ADD COLUMN amount BIGINT,
+    ADD COLUMN currency CHAR(3),
+    ADD COLUMN metadata JSONB;
+
+ALTER TABLE order_events
+    ADD COLUMN amount BIGINT,
+    ADD COLUMN currency CHAR(3),
+    ADD COLUMN metadata JSONB;
+
+CREATE INDEX idx_orders_currency_amount ON orders(currency, amount) WHERE amount IS NOT NULL;
+
+-- +goose Down
+-- SQL in section 'Down' is executed when this migration is rolled back.
+
+DROP INDEX IF EXISTS idx_orders_currency_amount;
+
+ALTER TABLE order_events
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS amount;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS amount;
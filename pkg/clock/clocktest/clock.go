@@ -0,0 +1,143 @@
+// Package clocktest provides a clock that only moves when told to.
+package clocktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/therealyo/justdone/pkg/clock"
+)
+
+// FakeClock is a clock.Clock whose time is moved by Advance. Timers fire
+// during Advance, and functions of AfterFunc are called before it returns,
+// so everything scheduled up to the new time has happened by then.
+type FakeClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	t := &fakeTimer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing due timers in the order
+// of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(end) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.deadline
+		c.changed.Broadcast()
+		c.mu.Unlock()
+
+		t.fire()
+
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// BlockUntil waits until n timers are pending, for timers started
+// by other goroutines.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// schedule adds the timer, keeping timers sorted by deadline.
+// It must be called with mu held.
+func (c *FakeClock) schedule(t *fakeTimer) {
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+	c.changed.Broadcast()
+}
+
+// unschedule removes the timer and reports whether it was pending.
+// It must be called with mu held.
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	pending := t.clock.unschedule(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.schedule(t)
+	return pending
+}
+
+func (t *fakeTimer) fire() {
+	if t.f != nil {
+		t.f()
+		return
+	}
+
+	// Like time.Timer, a tick nobody received yet is dropped
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+}
+
+var _ clock.Clock = new(FakeClock)
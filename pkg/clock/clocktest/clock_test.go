@@ -0,0 +1,50 @@
+package clocktest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/therealyo/justdone/pkg/clock/clocktest"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	clock := clocktest.NewFakeClock(start)
+
+	var fired []string
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "second") })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "first") })
+	stopped := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	timer := clock.NewTimer(3 * time.Second)
+
+	if !stopped.Stop() {
+		t.Errorf("Expected a pending timer to be stopped")
+	}
+
+	clock.Advance(2 * time.Second)
+	if len(fired) != 2 || fired[0] != "first" || fired[1] != "second" {
+		t.Errorf("Expected timers to fire in order of deadlines, got %v", fired)
+	}
+	if !clock.Now().Equal(start.Add(2 * time.Second)) {
+		t.Errorf("Expected clock to be at %v, got %v", start.Add(2*time.Second), clock.Now())
+	}
+
+	// A reset timer counts from the current time
+	timer.Reset(2 * time.Second)
+	clock.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatalf("Expected reset timer not to fire yet")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case at := <-timer.C():
+		if !at.Equal(start.Add(4 * time.Second)) {
+			t.Errorf("Expected timer to fire at %v, got %v", start.Add(4*time.Second), at)
+		}
+	default:
+		t.Fatalf("Expected reset timer to fire")
+	}
+}